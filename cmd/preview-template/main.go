@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"monitor-uptime/internal/notifier"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/storage"
+	"monitor-uptime/internal/templates"
+	"os"
+	"time"
+)
+
+// Creates sample notification of uptime monitor which failed after being OK
+func sampleNotification() *notifier.Notification {
+	now := time.Now()
+	return &notifier.Notification{
+		UptimeID:       "sample-uptime",
+		Host:           "https://example.com/health",
+		PreviousStatus: sns.STATUS_OK,
+		Status:         sns.STATUS_FAIL,
+		Reason:         "unexpected status code 503",
+		StatusCode:     503,
+		TTFB:           230,
+		DNSLookup:      12,
+		TCPConnect:     25,
+		TLSHandshake:   48,
+		Total:          1250,
+		FailCounter:    4,
+		Time:           now,
+		OutageStart:    now.Add(-3 * time.Minute),
+		RecentRuns: []storage.UptimeResultItem{
+			{RunAt: now.Add(-2 * time.Minute).Unix(), StatusCode: 200, TTFB: 180},
+			{RunAt: now.Add(-time.Minute).Unix(), StatusCode: 503, TTFB: 210, Reason: "unexpected status code 503"},
+			{RunAt: now.Unix(), StatusCode: 503, TTFB: 230, Reason: "unexpected status code 503"},
+		},
+	}
+}
+
+// Renders notification template against sample event for previewing
+// Event is read from JSON file (in the shape of notification) or built-in sample event is used,
+// template may be overridden by content of file
+func main() {
+	name := flag.String("template", templates.TEXT, "Name of rendered template (title, summary, text, email.subject, email.text, email.html)")
+	file := flag.String("file", "", "File with template overriding the rendered one")
+	event := flag.String("event", "", "JSON file with sample event, built-in sample event is used if empty")
+	status := flag.String("status", "", "Overrides status of sample event (OK, DEGRADED or FAIL)")
+	flag.Parse()
+
+	if err := preview(*name, *file, *event, *status); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Renders template and prints it to standard output
+func preview(name string, file string, event string, status string) error {
+	notification := sampleNotification()
+	if event != "" {
+		content, err := os.ReadFile(event)
+		if err != nil {
+			return err
+		}
+		notification = &notifier.Notification{}
+		if err = json.Unmarshal(content, notification); err != nil {
+			return err
+		}
+	}
+	if status != "" {
+		if event == "" && status != sns.STATUS_FAIL {
+			// Sample event recovers or degrades from outage
+			notification.PreviousStatus, notification.Reason = sns.STATUS_FAIL, ""
+		}
+		notification.Status = sns.UptimeStatus(status)
+	}
+
+	overrides := map[string]string{}
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		overrides[name] = string(content)
+	}
+	set, err := templates.New(overrides)
+	if err != nil {
+		return err
+	}
+	rendered, err := set.Render(name, notification)
+	if err != nil {
+		return err
+	}
+	fmt.Println(rendered)
+	return nil
+}
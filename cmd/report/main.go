@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/session"
+	dynamodbAPI "github.com/aws/aws-sdk-go/service/dynamodb"
+	"monitor-uptime/internal/dynamodb"
+	"monitor-uptime/internal/report"
+	"monitor-uptime/internal/sqlite"
+	"monitor-uptime/internal/storage"
+	"os"
+	"strings"
+	"time"
+)
+
+// Layout of month flag
+const monthLayout = "2006-01"
+
+// Prints SLA report of uptime monitors over month or arbitrary period to standard output
+// Uptime monitor results are read from DynamoDB executions table (DYNAMO_TABLE_EXECUTIONS and DYNAMO_INDEX_EXECUTIONS)
+// or from SQLite database
+func main() {
+	uptimeIDs := flag.String("uptime", "", "Comma separated uptime IDs of reported monitors")
+	month := flag.String("month", "", "Reported month (2006-01), previous month is reported if neither month nor period is set")
+	from := flag.String("from", "", "Start of reported period (RFC 3339), overrides month")
+	to := flag.String("to", "", "End of reported period (RFC 3339), now by default")
+	timeZone := flag.String("timezone", "UTC", "Time zone of reported month")
+	format := flag.String("format", report.FORMAT_MARKDOWN, "Output format (json, csv or markdown)")
+	backend := flag.String("storage", "dynamodb", "Storage of uptime monitor results (dynamodb or sqlite)")
+	sqlitePath := flag.String("sqlite", "uptime.db", "Path to SQLite database file used by sqlite storage")
+	flag.Parse()
+
+	if err := run(*uptimeIDs, *month, *from, *to, *timeZone, *format, *backend, *sqlitePath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Computes report and writes it to standard output
+func run(uptimeIDs string, month string, from string, to string, timeZone string, format string, backend string, sqlitePath string) error {
+	if uptimeIDs == "" {
+		return errors.New("uptime IDs of reported monitors are required")
+	}
+	start, end, err := period(month, from, to, timeZone, time.Now())
+	if err != nil {
+		return err
+	}
+	store, err := openStorage(backend, sqlitePath)
+	if err != nil {
+		return err
+	}
+	r, err := report.New(store, strings.Split(uptimeIDs, ","), start, end)
+	if err != nil {
+		return err
+	}
+	return r.Write(os.Stdout, format)
+}
+
+// Returns reported period as from and to timestamps (inclusive)
+// Period between from and to takes precedence over month, previous month is reported if neither is set.
+// Month is reported until now at most.
+func period(month string, from string, to string, timeZone string, now time.Time) (int64, int64, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return 0, 0, err
+	}
+	if from != "" {
+		start, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return 0, 0, err
+		}
+		end := now
+		if to != "" {
+			if end, err = time.Parse(time.RFC3339, to); err != nil {
+				return 0, 0, err
+			}
+		}
+		return start.Unix(), end.Unix(), nil
+	}
+
+	reported := now.In(loc).AddDate(0, 0, -now.In(loc).Day()) // Last day of previous month
+	if month != "" {
+		if reported, err = time.ParseInLocation(monthLayout, month, loc); err != nil {
+			return 0, 0, err
+		}
+	}
+	start, end := report.Month(reported.Year(), reported.Month(), loc)
+	if end > now.Unix() {
+		// Current month is reported until now, runs have not covered the rest of it yet
+		end = now.Unix()
+	}
+	return start, end, nil
+}
+
+// Opens storage of uptime monitor results
+func openStorage(backend string, sqlitePath string) (storage.Storage, error) {
+	switch backend {
+	case "dynamodb":
+		executionsTable := os.Getenv("DYNAMO_TABLE_EXECUTIONS")
+		if executionsTable == "" {
+			return nil, errors.New("DYNAMO_TABLE_EXECUTIONS is required by dynamodb storage")
+		}
+		sess := session.Must(session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable}))
+		executionsIndex, ok := os.LookupEnv("DYNAMO_INDEX_EXECUTIONS")
+		if !ok {
+			executionsIndex = "uptimeId-runAt-index"
+		}
+		return dynamodb.NewStorage(dynamodbAPI.New(sess), executionsTable, executionsIndex, "", "", ""), nil
+	case "sqlite":
+		return sqlite.NewStorage(sqlitePath)
+	}
+	return nil, errors.New("unsupported storage: " + backend)
+}
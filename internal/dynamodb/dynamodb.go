@@ -1,26 +1,198 @@
 package dynamodb
 
 import (
+	"errors"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"monitor-uptime/internal/storage"
+	"sort"
 	"strconv"
 )
 
-// Represents uptime monitor result that will be stored in DynamoDB
-// Item contains all collected data from single uptime monitor run
-type UptimeResultItem struct {
-	RequestID    string `json:"requestId"` // Uniquely identifies single uptime monitor's run
-	UptimeID     string `json:"uptimeId"`
-	RunAt        int64  `json:"runAt"` // Timestamp when the uptime monitor has been invoked
-	Host         string `json:"host"`
-	StatusCode   int    `json:"statusCode"`
-	TTFB         int64  `json:"ttfb"`         // Resulted Time To First Byte in milliseconds
-	DNSLookup    int64  `json:"dnslookup"`    // Resulted duration of DNS lookup in milliseconds
-	TLSHandshake int64  `json:"tlshandshake"` // Resulted duration of TLS handshake in milliseconds
+// Maximal number of attempts of conditional update of uptime status, update is retried if status is updated concurrently
+const STATUS_UPDATE_ATTEMPTS = 3
+
+// Represents storage of uptime monitor results and statuses backed by DynamoDB tables
+type Storage struct {
+	db              dynamodbiface.DynamoDBAPI
+	executionsTable string // Table of uptime monitor results, if empty results are not stored
+	executionsIndex string // Index of executions table with uptimeId hash key and runAt range key
+	statusTable     string // Table of uptime monitor statuses
+	incidentsTable  string // Table of incidents, if empty incidents are not tracked
+	incidentsIndex  string // Index of incidents table with uptimeId hash key and openedAt range key
+}
+
+// Creates storage backed by DynamoDB tables using provided DynamoDB API interface
+func NewStorage(
+	db dynamodbiface.DynamoDBAPI,
+	executionsTable string,
+	executionsIndex string,
+	statusTable string,
+	incidentsTable string,
+	incidentsIndex string) *Storage {
+	return &Storage{
+		db:              db,
+		executionsTable: executionsTable,
+		executionsIndex: executionsIndex,
+		statusTable:     statusTable,
+		incidentsTable:  incidentsTable,
+		incidentsIndex:  incidentsIndex,
+	}
+}
+
+// Store uptime monitor result from single execution
+// If executions table is not configured, then result is not stored
+func (s *Storage) StoreUptimeResult(uptime *UptimeResultItem) error {
+	if s.executionsTable == "" {
+		return nil
+	}
+	return StoreUptimeResult(uptime, s.executionsTable, s.db)
+}
+
+// List uptime monitor results of uptime monitor run between from and to timestamps (inclusive)
+// If executions table is not configured, then no results are returned
+func (s *Storage) ListUptimeResults(uptimeID string, from int64, to int64) ([]UptimeResultItem, error) {
+	if s.executionsTable == "" {
+		return nil, nil
+	}
+	return ListUptimeResults(uptimeID, from, to, s.executionsTable, s.executionsIndex, s.db)
+}
+
+// Get uptime status, nil is returned if it does not exist
+func (s *Storage) GetUptimeStatus(uptimeID string) (*storage.UptimeStatusItem, error) {
+	return GetUptimeStatus(uptimeID, s.statusTable, s.db)
+}
+
+// Record notification of kind sent at timestamp, nothing is recorded if uptime status does not exist
+func (s *Storage) RecordNotification(uptimeID string, kind string, at int64) error {
+	return RecordNotification(uptimeID, kind, at, s.statusTable, s.db)
+}
+
+// Count failed run, returns true if FAIL status should be announced
+func (s *Storage) UpdateUptimeStatus(uptimeID string, threshold int) (bool, error) {
+	return UpdateUptimeStatus(uptimeID, threshold, s.statusTable, s.db)
+}
+
+// Count degraded run, returns true if DEGRADED status should be announced
+func (s *Storage) DegradeUptimeStatus(uptimeID string, degradedThreshold int) (bool, error) {
+	return DegradeUptimeStatus(uptimeID, degradedThreshold, s.statusTable, s.db)
+}
+
+// Count successful run, returns true if OK status should be announced
+func (s *Storage) RecoverUptimeStatus(uptimeID string, recoveryThreshold int) (bool, error) {
+	return RecoverUptimeStatus(uptimeID, recoveryThreshold, s.statusTable, s.db)
+}
+
+// Evaluate flap score of the last window runs, returns FLAPPING if uptime monitor started flapping,
+// announced status (OK, DEGRADED or FAIL) if it is stable again, empty status otherwise
+func (s *Storage) DetectFlapping(uptimeID string, window int, threshold int, stableThreshold int) (string, error) {
+	return DetectFlapping(uptimeID, window, threshold, stableThreshold, s.statusTable, s.db)
+}
+
+// Mark uptime monitor UNREACHABLE_DUE_TO_PARENT because of failing parent, returns true if it has not been marked yet
+func (s *Storage) MarkUnreachable(uptimeID string, parent string) (bool, error) {
+	return MarkUnreachable(uptimeID, parent, s.statusTable, s.db)
+}
+
+// Acknowledge announced status of uptime monitor, returns false if no status (FAIL or DEGRADED) has been announced
+func (s *Storage) AcknowledgeUptimeStatus(uptimeID string, by string, note string, at int64) (bool, error) {
+	return AcknowledgeUptimeStatus(uptimeID, by, note, at, s.statusTable, s.db)
+}
+
+// Set maintenance window whose start has been announced, empty window ends it, returns true if it has changed
+func (s *Storage) SetMaintenance(uptimeID string, window string) (bool, error) {
+	return SetMaintenance(uptimeID, window, s.statusTable, s.db)
+}
+
+// Store incident opened for uptime monitor and link it to uptime status as its open incident
+// If incidents table is not configured, then incident is not stored
+func (s *Storage) OpenIncident(incident *storage.IncidentItem) error {
+	if s.incidentsTable == "" {
+		return nil
+	}
+	return OpenIncident(incident, s.incidentsTable, s.statusTable, s.db)
+}
+
+// Append event to open incident of uptime monitor, returns false if uptime monitor has no open incident
+func (s *Storage) AddIncidentEvent(uptimeID string, event storage.IncidentEvent) (bool, error) {
+	if s.incidentsTable == "" {
+		return false, nil
+	}
+	return AddIncidentEvent(uptimeID, event, s.incidentsTable, s.statusTable, s.db)
+}
+
+// Close open incident of uptime monitor at timestamp, returns false if uptime monitor has no open incident
+func (s *Storage) CloseIncident(uptimeID string, at int64) (bool, error) {
+	if s.incidentsTable == "" {
+		return false, nil
+	}
+	return CloseIncident(uptimeID, at, s.incidentsTable, s.statusTable, s.db)
 }
 
+// Add note to incident, returns false if incident does not exist
+func (s *Storage) AddIncidentNote(id string, note storage.IncidentNote) (bool, error) {
+	if s.incidentsTable == "" {
+		return false, nil
+	}
+	return AddIncidentNote(id, note, s.incidentsTable, s.db)
+}
+
+// Get incident, nil is returned if it does not exist
+func (s *Storage) GetIncident(id string) (*storage.IncidentItem, error) {
+	if s.incidentsTable == "" {
+		return nil, nil
+	}
+	return GetIncident(id, s.incidentsTable, s.db)
+}
+
+// List incidents of uptime monitor opened between from and to timestamps (inclusive)
+// If incidents table is not configured, then no incidents are returned
+func (s *Storage) ListIncidents(uptimeID string, from int64, to int64) ([]storage.IncidentItem, error) {
+	if s.incidentsTable == "" {
+		return nil, nil
+	}
+	return ListIncidents(uptimeID, from, to, s.incidentsTable, s.incidentsIndex, s.db)
+}
+
+// Store silence, silence with the same ID is replaced
+func (s *Storage) PutSilence(silence *storage.SilenceItem) error {
+	return PutSilence(silence, s.statusTable, s.db)
+}
+
+// List silences of uptime monitor and silences without uptime monitor, which have not expired at timestamp
+func (s *Storage) ListSilences(uptimeID string, at int64) ([]storage.SilenceItem, error) {
+	return ListSilences(uptimeID, at, s.statusTable, s.db)
+}
+
+// Remove silence of uptime monitor, returns true if it existed
+func (s *Storage) DeleteSilence(uptimeID string, id string) (bool, error) {
+	return DeleteSilence(uptimeID, id, s.statusTable, s.db)
+}
+
+// Remove silences of uptime monitor which expired at timestamp
+func (s *Storage) PruneSilences(uptimeID string, at int64) error {
+	return PruneSilences(uptimeID, at, s.statusTable, s.db)
+}
+
+// Record delivery attempts of notification of uptime monitor, only the last DELIVERY_HISTORY attempts are kept
+func (s *Storage) RecordDeliveryAttempts(uptimeID string, attempts []storage.DeliveryAttemptItem) error {
+	return RecordDeliveryAttempts(uptimeID, attempts, s.statusTable, s.db)
+}
+
+// List recorded delivery attempts of uptime monitor in order of their recording
+func (s *Storage) ListDeliveryAttempts(uptimeID string) ([]storage.DeliveryAttemptItem, error) {
+	return ListDeliveryAttempts(uptimeID, s.statusTable, s.db)
+}
+
+// Represents uptime monitor result that will be stored in DynamoDB
+type UptimeResultItem = storage.UptimeResultItem
+
+// Represents result of single assertion evaluated against HTTP response
+type AssertionResultItem = storage.AssertionResultItem
+
 // Store uptime monitor result from single execution in DynamoDB table using provided DynamoDB API interface
 // Returns error if result cannot be stored in DynamoDB table, otherwise nil
 func StoreUptimeResult(uptime *UptimeResultItem, tableName string, db dynamodbiface.DynamoDBAPI) error {
@@ -30,6 +202,47 @@ func StoreUptimeResult(uptime *UptimeResultItem, tableName string, db dynamodbif
 	return nil
 }
 
+// List uptime monitor results of uptime monitor run between from and to timestamps (inclusive) from DynamoDB table
+// Table is queried using index with uptimeId hash key and runAt range key, results are ordered by run time
+// Returns error if results cannot be queried
+func ListUptimeResults(
+	uptimeID string,
+	from int64,
+	to int64,
+	tableName string,
+	indexName string,
+	db dynamodbiface.DynamoDBAPI) ([]UptimeResultItem, error) {
+	var items []UptimeResultItem
+	var unmarshalErr error
+	err := db.QueryPages(&dynamodb.QueryInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":uptimeId": {
+				S: aws.String(uptimeID),
+			},
+			":from": {
+				N: aws.String(strconv.FormatInt(from, 10)),
+			},
+			":to": {
+				N: aws.String(strconv.FormatInt(to, 10)),
+			},
+		},
+		IndexName:              aws.String(indexName),
+		KeyConditionExpression: aws.String("uptimeId = :uptimeId AND runAt BETWEEN :from AND :to"),
+		TableName:              aws.String(tableName),
+	}, func(page *dynamodb.QueryOutput, _ bool) bool {
+		var pageItems []UptimeResultItem
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageItems); unmarshalErr != nil {
+			return false
+		}
+		items = append(items, pageItems...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, unmarshalErr
+}
+
 // Put item into Dynamo DB
 func putItem(in interface{}, tableName string, db dynamodbiface.DynamoDBAPI) error {
 	item, err := dynamodbattribute.MarshalMap(in)
@@ -48,19 +261,252 @@ func putItem(in interface{}, tableName string, db dynamodbiface.DynamoDBAPI) err
 	return nil
 }
 
-// Store or update uptime's monitor status in DynamoDB table using provided DynamoDB API interface
+// Get uptime's monitor status from DynamoDB table using provided DynamoDB API interface
+// Returns nil if uptime status does not exist, in case of error, non nil error is returned.
+func GetUptimeStatus(uptimeID string, tableName string, db dynamodbiface.DynamoDBAPI) (*storage.UptimeStatusItem, error) {
+	result, err := db.GetItem(&dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"uptimeId": {
+				S: aws.String(uptimeID),
+			},
+		},
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+	var status storage.UptimeStatusItem
+	if err = dynamodbattribute.UnmarshalMap(result.Item, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Record notification of announced status in DynamoDB table using provided DynamoDB API interface
+// Notification of transition sets announcedAt and notifiedAt and resets escalatedAt, reminder sets notifiedAt
+// and escalation sets escalatedAt, version of status is incremented. If uptime status does not exist (e.g. it has been cleared),
+// then nothing is recorded. In case of error, non nil error is returned.
+func RecordNotification(uptimeID string, kind string, at int64, tableName string, db dynamodbiface.DynamoDBAPI) error {
+	var updateExpression string
+	switch kind {
+	case storage.NOTIFICATION_TRANSITION:
+		updateExpression = "SET announcedAt=:at, notifiedAt=:at REMOVE escalatedAt ADD #version :inc"
+	case storage.NOTIFICATION_REMINDER:
+		updateExpression = "SET notifiedAt=:at ADD #version :inc"
+	case storage.NOTIFICATION_ESCALATION:
+		updateExpression = "SET escalatedAt=:at ADD #version :inc"
+	default:
+		return errors.New("unsupported notification kind: " + kind)
+	}
+
+	_, err := db.UpdateItem(&dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(uptimeId)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#version": aws.String("version"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":at": {
+				N: aws.String(strconv.FormatInt(at, 10)),
+			},
+			":inc": {
+				N: aws.String("1"),
+			},
+		},
+		Key: map[string]*dynamodb.AttributeValue{
+			"uptimeId": {
+				S: aws.String(uptimeID),
+			},
+		},
+		TableName:        aws.String(tableName),
+		UpdateExpression: aws.String(updateExpression),
+	})
+	if isConditionalCheckFailed(err) {
+		return nil
+	}
+	return err
+}
+
+// Count failed run of uptime monitor in DynamoDB table using provided DynamoDB API interface
 // For every uptime monitor represented by uptimeID is defined constant threshold and variable failCounter.
-// By every call failCounter is incremented. When failCounter cross threshold then true is returned, otherwise false.
+// By every call failCounter is incremented and successCounter of pending recovery and degradedCounter are reset.
+// Timestamp of the first failed run is stored as failingSince. Uptime monitor is FAILING until failCounter
+// crosses threshold, then it is DOWN. If uptime monitor went DOWN and FAIL status has not been announced before,
+// then true is returned, otherwise false.
 // In case of error, non nil error is returned.
 func UpdateUptimeStatus(
 	uptimeID string,
-	threshold string,
+	threshold int,
+	tableName string,
+	db dynamodbiface.DynamoDBAPI) (bool, error) {
+	return updateStatus(uptimeID, tableName, db, func(status *storage.UptimeStatusItem, exists bool) bool {
+		return status.Fail(threshold)
+	})
+}
+
+// Count degraded run of uptime monitor in DynamoDB table using provided DynamoDB API interface
+// For every uptime monitor represented by uptimeID is defined constant degradedThreshold and variable degradedCounter.
+// By every call degradedCounter is incremented and failCounter and successCounter are reset, failingSince is reset
+// unless FAIL has been announced.
+// When degradedCounter reaches degradedThreshold uptime monitor is DEGRADED, if DEGRADED status has not been
+// announced yet, then true is returned, otherwise false.
+// In case of error, non nil error is returned.
+func DegradeUptimeStatus(
+	uptimeID string,
+	degradedThreshold int,
+	tableName string,
+	db dynamodbiface.DynamoDBAPI) (bool, error) {
+	return updateStatus(uptimeID, tableName, db, func(status *storage.UptimeStatusItem, exists bool) bool {
+		return status.Degrade(degradedThreshold)
+	})
+}
+
+// Count successful run of uptime monitor in DynamoDB table using provided DynamoDB API interface
+// If no status (FAIL or DEGRADED) has been announced yet, then counters are reset, uptime monitor is OK
+// and false is returned. Otherwise successCounter is incremented and degradedCounter is reset, uptime monitor
+// is RECOVERING until successCounter reaches recoveryThreshold, then it is OK and true is returned.
+// In case of error, non nil error is returned.
+func RecoverUptimeStatus(
+	uptimeID string,
+	recoveryThreshold int,
+	tableName string,
+	db dynamodbiface.DynamoDBAPI) (bool, error) {
+	return updateStatus(uptimeID, tableName, db, func(status *storage.UptimeStatusItem, exists bool) bool {
+		return status.Recover(recoveryThreshold)
+	})
+}
+
+// Evaluate flap score of uptime monitor in DynamoDB table using provided DynamoDB API interface
+// Flap score is percentage of outcome changes among the last window runs recorded in status history. Uptime monitor
+// starts FLAPPING once flap score reaches threshold, then FLAPPING is returned. Once flap score of FLAPPING uptime
+// monitor drops below stableThreshold, announced status (OK, DEGRADED or FAIL) is returned, otherwise empty status.
+// In case of error, non nil error is returned.
+func DetectFlapping(
+	uptimeID string,
+	window int,
+	threshold int,
+	stableThreshold int,
+	tableName string,
+	db dynamodbiface.DynamoDBAPI) (string, error) {
+	var announce string
+	_, err := updateStatus(uptimeID, tableName, db, func(status *storage.UptimeStatusItem, exists bool) bool {
+		announce = status.DetectFlapping(window, threshold, stableThreshold)
+		return false
+	})
+	return announce, err
+}
+
+// Mark uptime monitor UNREACHABLE_DUE_TO_PARENT in DynamoDB table using provided DynamoDB API interface
+// Uptime monitor fails because its parent is down, so its counters and announced status are kept as they are.
+// Returns true if uptime monitor has not been unreachable due to the parent yet, otherwise false.
+// In case of error, non nil error is returned.
+func MarkUnreachable(uptimeID string, parent string, tableName string, db dynamodbiface.DynamoDBAPI) (bool, error) {
+	return updateStatus(uptimeID, tableName, db, func(status *storage.UptimeStatusItem, exists bool) bool {
+		return status.MarkUnreachable(parent)
+	})
+}
+
+// Updates uptime's monitor status by transition, which returns whether status should be announced
+// Status is read by consistent read, transition is applied and status is written back only if it has not been
+// updated in the meantime (its version has not changed). If status has been updated concurrently, then update is
+// retried with fresh status, so transition is announced by single invocation only. Unchanged status is not written.
+func updateStatus(
+	uptimeID string,
+	tableName string,
+	db dynamodbiface.DynamoDBAPI,
+	transition func(status *storage.UptimeStatusItem, exists bool) bool) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		previous, err := GetUptimeStatus(uptimeID, tableName, db)
+		if err != nil {
+			return false, err
+		}
+		status := storage.UptimeStatusItem{UptimeID: uptimeID}
+		if previous != nil {
+			status = *previous
+		}
+		notify := transition(&status, previous != nil)
+		if previous != nil && status == *previous {
+			return notify, nil
+		}
+
+		err = putStatus(&status, previous, tableName, db)
+		if isConditionalCheckFailed(err) && attempt < STATUS_UPDATE_ATTEMPTS {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return notify, nil
+	}
+}
+
+// Puts uptime's monitor status into DynamoDB table with incremented version
+// Status is put only if its version is the same as version of previous status, or if it does not exist yet
+// when there is no previous status. Otherwise ConditionalCheckFailedException is returned.
+func putStatus(status *storage.UptimeStatusItem, previous *storage.UptimeStatusItem, tableName string, db dynamodbiface.DynamoDBAPI) error {
+	input := &dynamodb.PutItemInput{
+		ConditionExpression: aws.String("attribute_not_exists(uptimeId)"),
+		TableName:           aws.String(tableName),
+	}
+	status.Version = 1
+	if previous != nil {
+		status.Version = previous.Version + 1
+		input.ExpressionAttributeNames = map[string]*string{"#version": aws.String("version")}
+		if previous.Version == 0 {
+			input.ConditionExpression = aws.String("attribute_not_exists(#version)")
+		} else {
+			input.ConditionExpression = aws.String("#version = :version")
+			input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+				":version": {
+					N: aws.String(strconv.FormatInt(previous.Version, 10)),
+				},
+			}
+		}
+	}
+
+	item, err := dynamodbattribute.MarshalMap(status)
+	if err != nil {
+		return err
+	}
+	input.Item = item
+	_, err = db.PutItem(input)
+	return err
+}
+
+// Returns true if error is caused by failed condition of conditional write
+func isConditionalCheckFailed(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+// Acknowledge announced status of uptime monitor in DynamoDB table using provided DynamoDB API interface
+// Acknowledgement is stored only if status (FAIL or DEGRADED) has been announced, otherwise false is returned.
+// In case of error, non nil error is returned.
+func AcknowledgeUptimeStatus(
+	uptimeID string,
+	by string,
+	note string,
+	at int64,
 	tableName string,
 	db dynamodbiface.DynamoDBAPI) (bool, error) {
-	result, err := db.UpdateItem(&dynamodb.UpdateItemInput{
+	_, err := db.UpdateItem(&dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(#status)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status":  aws.String("status"),
+			"#version": aws.String("version"),
+		},
 		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":threshold": {
-				N: aws.String(threshold),
+			":at": {
+				N: aws.String(strconv.FormatInt(at, 10)),
+			},
+			":by": {
+				S: aws.String(by),
+			},
+			":note": {
+				S: aws.String(note),
 			},
 			":inc": {
 				N: aws.String("1"),
@@ -71,39 +517,483 @@ func UpdateUptimeStatus(
 				S: aws.String(uptimeID),
 			},
 		},
-		ReturnValues:     aws.String("ALL_NEW"),
 		TableName:        aws.String(tableName),
-		UpdateExpression: aws.String("SET threshold=:threshold ADD failCounter :inc"),
+		UpdateExpression: aws.String("SET acknowledgedAt=:at, acknowledgedBy=:by, acknowledgeNote=:note ADD #version :inc"),
 	})
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
 
+// Set maintenance window of uptime monitor in DynamoDB table using provided DynamoDB API interface
+// Window is set once its start has been announced and it is cleared by empty window once its end has been announced.
+// Returns true if window has changed, otherwise false. In case of error, non nil error is returned.
+func SetMaintenance(uptimeID string, window string, tableName string, db dynamodbiface.DynamoDBAPI) (bool, error) {
+	return updateStatus(uptimeID, tableName, db, func(status *storage.UptimeStatusItem, exists bool) bool {
+		return status.SetMaintenance(window)
+	})
+}
+
+// Store incident in DynamoDB table of incidents and link it to uptime status as its open incident using provided
+// DynamoDB API interface
+// Returns error if incident cannot be stored or linked, otherwise nil
+func OpenIncident(incident *storage.IncidentItem, tableName string, statusTableName string, db dynamodbiface.DynamoDBAPI) error {
+	if err := putItem(incident, tableName, db); err != nil {
+		return err
+	}
+	_, err := updateStatus(incident.UptimeID, statusTableName, db, func(status *storage.UptimeStatusItem, exists bool) bool {
+		status.IncidentID = incident.ID
+		return false
+	})
+	return err
+}
+
+// Append event to timeline of open incident of uptime monitor in DynamoDB table using provided DynamoDB API interface
+// Open incident is looked up in uptime status. Returns false if uptime monitor has no open incident, otherwise true.
+// In case of error, non nil error is returned.
+func AddIncidentEvent(
+	uptimeID string,
+	event storage.IncidentEvent,
+	tableName string,
+	statusTableName string,
+	db dynamodbiface.DynamoDBAPI) (bool, error) {
+	status, err := GetUptimeStatus(uptimeID, statusTableName, db)
+	if err != nil || status == nil || status.IncidentID == "" {
+		return false, err
+	}
+	events, err := dynamodbattribute.MarshalList([]storage.IncidentEvent{event})
 	if err != nil {
 		return false, err
 	}
+	return updateIncident(&dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":events": {
+				L: events,
+			},
+		},
+		Key:              incidentKey(status.IncidentID),
+		TableName:        aws.String(tableName),
+		UpdateExpression: aws.String("SET timeline = list_append(timeline, :events)"),
+	}, db)
+}
 
-	return isThresholdCrossed(result), nil
+// Close open incident of uptime monitor at timestamp in DynamoDB table using provided DynamoDB API interface
+// Closing time, duration since the first failed run and recovered event are set and incident is unlinked from uptime
+// status. Returns false if uptime monitor has no open incident, otherwise true.
+// In case of error, non nil error is returned.
+func CloseIncident(uptimeID string, at int64, tableName string, statusTableName string, db dynamodbiface.DynamoDBAPI) (bool, error) {
+	status, err := GetUptimeStatus(uptimeID, statusTableName, db)
+	if err != nil || status == nil || status.IncidentID == "" {
+		return false, err
+	}
+	events, err := dynamodbattribute.MarshalList([]storage.IncidentEvent{{Type: storage.EVENT_RECOVERED, At: at}})
+	if err != nil {
+		return false, err
+	}
+	closed, err := updateIncident(&dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(id) AND attribute_not_exists(closedAt)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#duration": aws.String("duration"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":at": {
+				N: aws.String(strconv.FormatInt(at, 10)),
+			},
+			":events": {
+				L: events,
+			},
+		},
+		Key:              incidentKey(status.IncidentID),
+		TableName:        aws.String(tableName),
+		UpdateExpression: aws.String("SET closedAt=:at, #duration = :at - startedAt, timeline = list_append(timeline, :events)"),
+	}, db)
+	if err != nil {
+		return false, err
+	}
+	_, err = updateStatus(uptimeID, statusTableName, db, func(status *storage.UptimeStatusItem, exists bool) bool {
+		status.IncidentID = ""
+		return false
+	})
+	return closed, err
 }
 
-// Returns true if failCounter crosses threshold
-func isThresholdCrossed(result *dynamodb.UpdateItemOutput) bool {
-	failCounter, _ := strconv.Atoi(*result.Attributes["failCounter"].N)
-	threshold, _ := strconv.Atoi(*result.Attributes["threshold"].N)
-	return failCounter > threshold
+// Add note to incident in DynamoDB table using provided DynamoDB API interface
+// Returns false if incident does not exist, otherwise true. In case of error, non nil error is returned.
+func AddIncidentNote(id string, note storage.IncidentNote, tableName string, db dynamodbiface.DynamoDBAPI) (bool, error) {
+	notes, err := dynamodbattribute.MarshalList([]storage.IncidentNote{note})
+	if err != nil {
+		return false, err
+	}
+	return updateIncident(&dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(id)"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":notes": {
+				L: notes,
+			},
+			":empty": {
+				L: []*dynamodb.AttributeValue{},
+			},
+		},
+		Key:              incidentKey(id),
+		TableName:        aws.String(tableName),
+		UpdateExpression: aws.String("SET notes = list_append(if_not_exists(notes, :empty), :notes)"),
+	}, db)
 }
 
-// If uptime status exists, calling this method clears it (removes from Dynamo DB)
-// Returns true if uptime status was cleared
-func ClearUptimeStatus(uptimeID string, tableName string, db dynamodbiface.DynamoDBAPI) (bool, error) {
-	result, err := db.DeleteItem(&dynamodb.DeleteItemInput{
+// Get incident from DynamoDB table using provided DynamoDB API interface
+// Returns nil if incident does not exist, in case of error, non nil error is returned.
+func GetIncident(id string, tableName string, db dynamodbiface.DynamoDBAPI) (*storage.IncidentItem, error) {
+	result, err := db.GetItem(&dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
+		Key:            incidentKey(id),
+		TableName:      aws.String(tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+	var incident storage.IncidentItem
+	if err = dynamodbattribute.UnmarshalMap(result.Item, &incident); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// List incidents of uptime monitor opened between from and to timestamps (inclusive) from DynamoDB table
+// Table is queried using index with uptimeId hash key and openedAt range key, incidents are ordered by opening time
+// Returns error if incidents cannot be queried
+func ListIncidents(
+	uptimeID string,
+	from int64,
+	to int64,
+	tableName string,
+	indexName string,
+	db dynamodbiface.DynamoDBAPI) ([]storage.IncidentItem, error) {
+	var incidents []storage.IncidentItem
+	var unmarshalErr error
+	err := db.QueryPages(&dynamodb.QueryInput{
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":uptimeId": {
+				S: aws.String(uptimeID),
+			},
+			":from": {
+				N: aws.String(strconv.FormatInt(from, 10)),
+			},
+			":to": {
+				N: aws.String(strconv.FormatInt(to, 10)),
+			},
+		},
+		IndexName:              aws.String(indexName),
+		KeyConditionExpression: aws.String("uptimeId = :uptimeId AND openedAt BETWEEN :from AND :to"),
+		TableName:              aws.String(tableName),
+	}, func(page *dynamodb.QueryOutput, _ bool) bool {
+		var items []storage.IncidentItem
+		if unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &items); unmarshalErr != nil {
+			return false
+		}
+		incidents = append(incidents, items...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return incidents, unmarshalErr
+}
+
+// Updates incident by update expression, returns false if condition of update has failed
+func updateIncident(input *dynamodb.UpdateItemInput, db dynamodbiface.DynamoDBAPI) (bool, error) {
+	_, err := db.UpdateItem(input)
+	if isConditionalCheckFailed(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Returns key of incident
+func incidentKey(id string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id": {
+			S: aws.String(id),
+		},
+	}
+}
+
+// Represents silences of uptime monitor stored in uptime status table, silences without uptime monitor share one item
+// Item expires by DynamoDB TTL once time to live is enabled on expiresAt attribute of the table and all its silences expired
+type silencesItem struct {
+	Key       string                         `json:"uptimeId"` // Key of silences returned by storage.SilencesKey
+	Silences  map[string]storage.SilenceItem `json:"silences"` // Silences by their ID
+	ExpiresAt int64                          `json:"expiresAt"`
+	Version   int64                          `json:"version"`
+}
+
+// Store silence in DynamoDB table of uptime statuses using provided DynamoDB API interface
+// Silence is added to silences of its uptime monitor, silence with the same ID is replaced.
+// Returns error if silence cannot be stored, otherwise nil
+func PutSilence(silence *storage.SilenceItem, tableName string, db dynamodbiface.DynamoDBAPI) error {
+	_, err := updateSilences(silence.UptimeID, tableName, db, func(silences map[string]storage.SilenceItem) bool {
+		silences[silence.ID] = *silence
+		return true
+	})
+	return err
+}
+
+// List silences of uptime monitor and silences without uptime monitor, which have not expired at timestamp, from DynamoDB
+// table of uptime statuses using provided DynamoDB API interface, silences are ordered by ID
+// Silences are read by their keys, expired silences are filtered out as they are removed only by pruning or DynamoDB TTL.
+// In case of error, non nil error is returned.
+func ListSilences(uptimeID string, at int64, tableName string, db dynamodbiface.DynamoDBAPI) ([]storage.SilenceItem, error) {
+	keys := []string{storage.SilencesKey("")}
+	if uptimeID != "" {
+		keys = append(keys, storage.SilencesKey(uptimeID))
+	}
+	var silences []storage.SilenceItem
+	for _, key := range keys {
+		item, err := getSilences(key, tableName, db)
+		if err != nil {
+			return nil, err
+		}
+		if item == nil {
+			continue
+		}
+		for _, silence := range item.Silences {
+			if silence.Until > at {
+				silences = append(silences, silence)
+			}
+		}
+	}
+	sort.Slice(silences, func(i, j int) bool {
+		return silences[i].ID < silences[j].ID
+	})
+	return silences, nil
+}
+
+// Remove silence of uptime monitor from DynamoDB table of uptime statuses using provided DynamoDB API interface
+// Returns true if silence existed, in case of error, non nil error is returned.
+func DeleteSilence(uptimeID string, id string, tableName string, db dynamodbiface.DynamoDBAPI) (bool, error) {
+	return updateSilences(uptimeID, tableName, db, func(silences map[string]storage.SilenceItem) bool {
+		_, ok := silences[id]
+		delete(silences, id)
+		return ok
+	})
+}
+
+// Remove silences of uptime monitor which expired at timestamp from DynamoDB table of uptime statuses using
+// provided DynamoDB API interface. In case of error, non nil error is returned.
+func PruneSilences(uptimeID string, at int64, tableName string, db dynamodbiface.DynamoDBAPI) error {
+	_, err := updateSilences(uptimeID, tableName, db, func(silences map[string]storage.SilenceItem) bool {
+		pruned := false
+		for id, silence := range silences {
+			if silence.Until <= at {
+				delete(silences, id)
+				pruned = true
+			}
+		}
+		return pruned
+	})
+	return err
+}
+
+// Get silences item by consistent read, nil is returned if it does not exist
+func getSilences(key string, tableName string, db dynamodbiface.DynamoDBAPI) (*silencesItem, error) {
+	result, err := db.GetItem(&dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
 		Key: map[string]*dynamodb.AttributeValue{
 			"uptimeId": {
-				S: aws.String(uptimeID),
+				S: aws.String(key),
 			},
 		},
-		ReturnValues: aws.String("ALL_OLD"),
-		TableName:    aws.String(tableName),
+		TableName: aws.String(tableName),
 	})
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+	var item silencesItem
+	if err = dynamodbattribute.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Updates silences of uptime monitor by update, which returns whether silences have changed
+// Like uptime status, silences are written back only if their version has not changed in the meantime, otherwise
+// update is retried with fresh silences. Item is deleted once it has no silences. Returns result of update.
+func updateSilences(
+	uptimeID string,
+	tableName string,
+	db dynamodbiface.DynamoDBAPI,
+	update func(silences map[string]storage.SilenceItem) bool) (bool, error) {
+	key := storage.SilencesKey(uptimeID)
+	for attempt := 1; ; attempt++ {
+		previous, err := getSilences(key, tableName, db)
+		if err != nil {
+			return false, err
+		}
+		item := silencesItem{Key: key, Silences: map[string]storage.SilenceItem{}}
+		if previous != nil {
+			item.Version = previous.Version
+			for id, silence := range previous.Silences {
+				item.Silences[id] = silence
+			}
+		}
+		if !update(item.Silences) {
+			return false, nil
+		}
+
+		err = putSilences(&item, previous != nil, tableName, db)
+		if isConditionalCheckFailed(err) && attempt < STATUS_UPDATE_ATTEMPTS {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// Returns condition of write of item read with version, or of item which does not exist yet if it has not been read
+func versionCondition(version int64, exists bool) (*string, map[string]*string, map[string]*dynamodb.AttributeValue) {
+	if !exists {
+		return aws.String("attribute_not_exists(uptimeId)"), nil, nil
+	}
+	return aws.String("#version = :version"),
+		map[string]*string{"#version": aws.String("version")},
+		map[string]*dynamodb.AttributeValue{
+			":version": {
+				N: aws.String(strconv.FormatInt(version, 10)),
+			},
+		}
+}
+
+// Puts silences item with incremented version, or deletes it if it has no silences
+// Item is written only if it has the same version as when it was read, or if it does not exist yet when
+// it has not been read. Otherwise ConditionalCheckFailedException is returned.
+func putSilences(item *silencesItem, exists bool, tableName string, db dynamodbiface.DynamoDBAPI) error {
+	condition, names, values := versionCondition(item.Version, exists)
+
+	if len(item.Silences) == 0 {
+		if !exists {
+			return nil
+		}
+		_, err := db.DeleteItem(&dynamodb.DeleteItemInput{
+			ConditionExpression:       condition,
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			Key: map[string]*dynamodb.AttributeValue{
+				"uptimeId": {
+					S: aws.String(item.Key),
+				},
+			},
+			TableName: aws.String(tableName),
+		})
+		return err
+	}
+
+	item.Version++
+	item.ExpiresAt = 0
+	for _, silence := range item.Silences {
+		if silence.Until > item.ExpiresAt {
+			item.ExpiresAt = silence.Until
+		}
+	}
+	attributes, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	_, err = db.PutItem(&dynamodb.PutItemInput{
+		ConditionExpression:       condition,
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		Item:                      attributes,
+		TableName:                 aws.String(tableName),
+	})
+	return err
+}
+
+// Represents recent delivery attempts of notifications of uptime monitor stored in uptime status table
+type deliveryAttemptsItem struct {
+	Key      string                        `json:"uptimeId"` // ID of uptime monitor prefixed by DELIVERY_PREFIX
+	Attempts []storage.DeliveryAttemptItem `json:"attempts"`
+	Version  int64                         `json:"version"`
+}
+
+// Record delivery attempts of notification of uptime monitor in DynamoDB table of uptime statuses using provided
+// DynamoDB API interface, only the last DELIVERY_HISTORY attempts are kept
+// Like uptime status, attempts are written back only if they have not been recorded concurrently, otherwise
+// recording is retried. In case of error, non nil error is returned.
+func RecordDeliveryAttempts(
+	uptimeID string,
+	attempts []storage.DeliveryAttemptItem,
+	tableName string,
+	db dynamodbiface.DynamoDBAPI) error {
+	for attempt := 1; ; attempt++ {
+		previous, err := getDeliveryAttempts(uptimeID, tableName, db)
+		if err != nil {
+			return err
+		}
+		item := deliveryAttemptsItem{Key: storage.DELIVERY_PREFIX + uptimeID}
+		if previous != nil {
+			item = *previous
+		}
+		item.Attempts = storage.AppendDeliveryAttempts(item.Attempts, attempts)
+		condition, names, values := versionCondition(item.Version, previous != nil)
+		item.Version++
+
+		attributes, err := dynamodbattribute.MarshalMap(item)
+		if err != nil {
+			return err
+		}
+		_, err = db.PutItem(&dynamodb.PutItemInput{
+			ConditionExpression:       condition,
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			Item:                      attributes,
+			TableName:                 aws.String(tableName),
+		})
+		if isConditionalCheckFailed(err) && attempt < STATUS_UPDATE_ATTEMPTS {
+			continue
+		}
+		return err
+	}
+}
+
+// List recorded delivery attempts of uptime monitor from DynamoDB table of uptime statuses using provided
+// DynamoDB API interface, attempts are ordered by their recording. In case of error, non nil error is returned.
+func ListDeliveryAttempts(uptimeID string, tableName string, db dynamodbiface.DynamoDBAPI) ([]storage.DeliveryAttemptItem, error) {
+	item, err := getDeliveryAttempts(uptimeID, tableName, db)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	return item.Attempts, nil
+}
+
+// Get delivery attempts item of uptime monitor by consistent read, nil is returned if it does not exist
+func getDeliveryAttempts(uptimeID string, tableName string, db dynamodbiface.DynamoDBAPI) (*deliveryAttemptsItem, error) {
+	result, err := db.GetItem(&dynamodb.GetItemInput{
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			"uptimeId": {
+				S: aws.String(storage.DELIVERY_PREFIX + uptimeID),
+			},
+		},
+		TableName: aws.String(tableName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+	var item deliveryAttemptsItem
+	if err = dynamodbattribute.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, err
 	}
-	return result != nil && result.Attributes != nil, nil
+	return &item, nil
 }
@@ -3,51 +3,178 @@ package dynamodb
 import (
 	"errors"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/storage"
 	"testing"
 	"time"
 )
 
 // DynamoDB mock
 type mockDynamoDBClient struct {
-	failCounter string
-	threshold string
-	clearedUptimeId string
+	failCounter       string
+	threshold         string
+	successCounter    string
+	recoveryThreshold string
+	degradedCounter   string
+	degradedThreshold string
+	status            string
+	history           string
+	incidentID        string
+	version           string
+	missingStatus     bool
+	putItems          *[]*dynamodb.PutItemInput
+	updateItems       *[]*dynamodb.UpdateItemInput
 	dynamodbiface.DynamoDBAPI
 }
 
-func (m mockDynamoDBClient) DeleteItem(*dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
-	if m.clearedUptimeId != "" {
-		return &dynamodb.DeleteItemOutput{
-			Attributes: map[string]*dynamodb.AttributeValue{
-				"uptimeId": {
-					S: aws.String(m.clearedUptimeId),
-				},
+func (m mockDynamoDBClient) QueryPages(input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) error {
+	fn(&dynamodb.QueryOutput{
+		Items: []map[string]*dynamodb.AttributeValue{
+			{
+				"requestId":  {S: aws.String("request-1")},
+				"uptimeId":   {S: input.ExpressionAttributeValues[":uptimeId"].S},
+				"runAt":      {N: aws.String("100")},
+				"statusCode": {N: aws.String("200")},
 			},
-		}, nil
-	} else {
-		return &dynamodb.DeleteItemOutput{}, nil
+		},
+	}, false)
+	fn(&dynamodb.QueryOutput{
+		Items: []map[string]*dynamodb.AttributeValue{
+			{
+				"requestId":  {S: aws.String("request-2")},
+				"uptimeId":   {S: input.ExpressionAttributeValues[":uptimeId"].S},
+				"runAt":      {N: aws.String("200")},
+				"statusCode": {N: aws.String("500")},
+			},
+		},
+	}, true)
+	return nil
+}
+
+func (m mockDynamoDBClient) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	item := map[string]*dynamodb.AttributeValue{}
+	for name, value := range map[string]string{
+		"failCounter":       m.failCounter,
+		"threshold":         m.threshold,
+		"successCounter":    m.successCounter,
+		"recoveryThreshold": m.recoveryThreshold,
+		"degradedCounter":   m.degradedCounter,
+		"degradedThreshold": m.degradedThreshold,
+		"version":           m.version,
+	} {
+		if value != "" {
+			item[name] = &dynamodb.AttributeValue{N: aws.String(value)}
+		}
+	}
+	if m.status != "" {
+		item["status"] = &dynamodb.AttributeValue{S: aws.String(m.status)}
+	}
+	if m.history != "" {
+		item["history"] = &dynamodb.AttributeValue{S: aws.String(m.history)}
 	}
+	if m.incidentID != "" {
+		item["incidentId"] = &dynamodb.AttributeValue{S: aws.String(m.incidentID)}
+	}
+	if m.missingStatus || len(item) == 0 {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	item["uptimeId"] = input.Key["uptimeId"]
+	return &dynamodb.GetItemOutput{Item: item}, nil
 }
 
-func (m mockDynamoDBClient) PutItem(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+func (m mockDynamoDBClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if m.putItems != nil {
+		*m.putItems = append(*m.putItems, input)
+	}
 	return &dynamodb.PutItemOutput{}, nil
 }
 
-func (m mockDynamoDBClient) UpdateItem(*dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
-	return &dynamodb.UpdateItemOutput{
-		Attributes: map[string]*dynamodb.AttributeValue{
-			"failCounter": {
-				N: aws.String(m.failCounter),
-			},
-			"threshold": {
-				N: aws.String(m.threshold),
-			},
-		},
-	}, nil
+func (m mockDynamoDBClient) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	if m.updateItems != nil {
+		*m.updateItems = append(*m.updateItems, input)
+	}
+	if m.missingStatus {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "conditional request failed", nil)
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+// DynamoDB mock of uptime status updated concurrently
+// Every read returns next item, write fails by failed condition until the last item is read
+type mockDynamoDBClientConcurrent struct {
+	items []map[string]*dynamodb.AttributeValue
+	reads *int
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m mockDynamoDBClientConcurrent) GetItem(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	item := m.items[*m.reads]
+	*m.reads++
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (m mockDynamoDBClientConcurrent) PutItem(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	if *m.reads < len(m.items) {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "conditional request failed", nil)
+	}
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// DynamoDB mock of silences items stored by their keys
+type mockDynamoDBClientSilences struct {
+	items       map[string]silencesItem
+	gets        *[]string
+	putItems    *[]*dynamodb.PutItemInput
+	deleteItems *[]*dynamodb.DeleteItemInput
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m mockDynamoDBClientSilences) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	key := *input.Key["uptimeId"].S
+	if m.gets != nil {
+		*m.gets = append(*m.gets, key)
+	}
+	item, ok := m.items[key]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	attributes, err := dynamodbattribute.MarshalMap(item)
+	return &dynamodb.GetItemOutput{Item: attributes}, err
+}
+
+func (m mockDynamoDBClientSilences) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	*m.putItems = append(*m.putItems, input)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m mockDynamoDBClientSilences) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	*m.deleteItems = append(*m.deleteItems, input)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+type mockDynamoDBClientDeliveries struct {
+	items    map[string]deliveryAttemptsItem
+	putItems *[]*dynamodb.PutItemInput
+	dynamodbiface.DynamoDBAPI
+}
+
+func (m mockDynamoDBClientDeliveries) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	item, ok := m.items[*input.Key["uptimeId"].S]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	attributes, err := dynamodbattribute.MarshalMap(item)
+	return &dynamodb.GetItemOutput{Item: attributes}, err
+}
+
+func (m mockDynamoDBClientDeliveries) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	*m.putItems = append(*m.putItems, input)
+	return &dynamodb.PutItemOutput{}, nil
 }
 
 // DynamoDB erroneous mock
@@ -59,6 +186,14 @@ func (m mockDynamoDBClientBroken) DeleteItem(*dynamodb.DeleteItemInput) (*dynamo
 	return &dynamodb.DeleteItemOutput{}, errors.New("cannot delete item from dynamodb")
 }
 
+func (m mockDynamoDBClientBroken) QueryPages(*dynamodb.QueryInput, func(*dynamodb.QueryOutput, bool) bool) error {
+	return errors.New("cannot query dynamodb")
+}
+
+func (m mockDynamoDBClientBroken) GetItem(*dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{}, errors.New("cannot get item from dynamodb")
+}
+
 func (m mockDynamoDBClientBroken) PutItem(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
 	return &dynamodb.PutItemOutput{}, errors.New("cannot put item into dynamodb")
 }
@@ -120,9 +255,9 @@ func TestStoreUptimeResultSuccess(t *testing.T)  {
 // Then true is returned
 func TestUpdateUptimeStatusSuccessThresholdCrossed(t *testing.T) {
 	// When
-	res, err := UpdateUptimeStatus("anyUptimeId", "1", "anyTableName", mockDynamoDBClient{
+	res, err := UpdateUptimeStatus("anyUptimeId", 2, "anyTableName", mockDynamoDBClient{
 		threshold: "2",
-		failCounter: "3",
+		failCounter: "2",
 	})
 
 	// Then
@@ -135,9 +270,9 @@ func TestUpdateUptimeStatusSuccessThresholdCrossed(t *testing.T) {
 // Then false is returned
 func TestUpdateUptimeStatusSuccessThresholdNotCrossed(t *testing.T) {
 	// When
-	res, err := UpdateUptimeStatus("anyUptimeId", "1", "anyTableName", mockDynamoDBClient{
+	res, err := UpdateUptimeStatus("anyUptimeId", 2, "anyTableName", mockDynamoDBClient{
 		threshold: "2",
-		failCounter: "2",
+		failCounter: "1",
 	})
 
 	// Then
@@ -150,46 +285,762 @@ func TestUpdateUptimeStatusSuccessThresholdNotCrossed(t *testing.T) {
 // Then non-nil error is returned
 func TestUpdateUptimeStatusFailure(t *testing.T) {
 	// When
-	_, err := UpdateUptimeStatus("anyUptimeId", "1", "anyTableName", mockDynamoDBClientBroken{})
+	_, err := UpdateUptimeStatus("anyUptimeId", 1, "anyTableName", mockDynamoDBClientBroken{})
 
 	// Then
 	assert.NotNil(t, err, "Error was expected to be returned")
 }
 
-// Given uptime status exists
-// When uptime status is cleared
-// Then uptime status is cleared
-//      and true is returned
-func TestClearUptimeStatusCleared(t *testing.T) {
+// Given uptime status has version
+// When uptime status is updated
+// Then status with incremented version is put on condition that its version has not changed
+//      and uptime monitor is FAILING
+func TestUpdateUptimeStatusConditionalPut(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+
 	// When
-	res, err := ClearUptimeStatus("anyUptimeId", "anyTableName", mockDynamoDBClient{
-		clearedUptimeId: "anyUptimeId",
+	_, err := UpdateUptimeStatus("anyUptimeId", 3, "anyTableName", mockDynamoDBClient{
+		threshold:   "3",
+		failCounter: "1",
+		version:     "7",
+		putItems:    &putItems,
 	})
 
 	// Then
 	assert.Nil(t, err, "Error was not expected to be returned")
-	assert.True(t, res, "Result was expected to be true")
+	assert.Len(t, putItems, 1, "Status was expected to be put once")
+	assert.Equal(t, "#version = :version", *putItems[0].ConditionExpression, "Unexpected condition")
+	assert.Equal(t, "7", *putItems[0].ExpressionAttributeValues[":version"].N, "Unexpected expected version")
+	assert.Equal(t, "8", *putItems[0].Item["version"].N, "Unexpected version")
+	assert.Equal(t, "FAILING", *putItems[0].Item["state"].S, "Unexpected state")
+}
+
+// Given uptime status is updated concurrently by another invocation which announces FAIL
+// When uptime status is updated
+//      and conditional put fails
+// Then update is retried with fresh status
+//      and false is returned
+func TestUpdateUptimeStatusConcurrentUpdate(t *testing.T) {
+	// Given
+	reads := 0
+	db := mockDynamoDBClientConcurrent{
+		reads: &reads,
+		items: []map[string]*dynamodb.AttributeValue{
+			{
+				"uptimeId":    {S: aws.String("anyUptimeId")},
+				"failCounter": {N: aws.String("2")},
+				"threshold":   {N: aws.String("2")},
+				"state":       {S: aws.String("FAILING")},
+				"version":     {N: aws.String("2")},
+			},
+			{
+				"uptimeId":    {S: aws.String("anyUptimeId")},
+				"failCounter": {N: aws.String("3")},
+				"threshold":   {N: aws.String("2")},
+				"state":       {S: aws.String("DOWN")},
+				"status":      {S: aws.String("FAIL")},
+				"version":     {N: aws.String("3")},
+			},
+		},
+	}
+
+	// When
+	res, err := UpdateUptimeStatus("anyUptimeId", 2, "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, 2, reads, "Status was expected to be read again")
+	assert.False(t, res, "FAIL was not expected to be announced twice")
+}
+
+// Given uptime status is updated concurrently by every attempt
+// When uptime status is updated
+// Then non-nil error is returned
+func TestUpdateUptimeStatusConflict(t *testing.T) {
+	// Given
+	reads := 0
+	item := map[string]*dynamodb.AttributeValue{
+		"uptimeId":    {S: aws.String("anyUptimeId")},
+		"failCounter": {N: aws.String("1")},
+		"threshold":   {N: aws.String("2")},
+	}
+	db := mockDynamoDBClientConcurrent{reads: &reads, items: []map[string]*dynamodb.AttributeValue{item, item, item, item}}
+
+	// When
+	_, err := UpdateUptimeStatus("anyUptimeId", 2, "anyTableName", db)
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+	assert.Equal(t, STATUS_UPDATE_ATTEMPTS, reads, "Unexpected number of attempts")
+}
+
+// Given FAIL status has been already announced
+// When uptime status is updated
+//      and provided threshold is crossed
+// Then false is returned
+func TestUpdateUptimeStatusAlreadyAnnounced(t *testing.T) {
+	// When
+	res, err := UpdateUptimeStatus("anyUptimeId", 2, "anyTableName", mockDynamoDBClient{
+		threshold:   "2",
+		failCounter: "3",
+		status:      "FAIL",
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.False(t, res, "Result was expected to be false")
 }
 
 // Given uptime status does not exist
-// When uptime status is cleared
-// Then uptime status is cleared
+// When uptime status is recovered
+// Then false is returned
+func TestRecoverUptimeStatusMissing(t *testing.T) {
+	// When
+	res, err := RecoverUptimeStatus("anyUptimeId", 1, "anyTableName", mockDynamoDBClient{
+		missingStatus: true,
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.False(t, res, "Result was expected to be false")
+}
+
+// Given uptime status has not crossed threshold
+// When uptime status is recovered
+// Then uptime monitor is OK silently
 //      and false is returned
-func TestClearUptimeStatusNotCleared(t *testing.T) {
+func TestRecoverUptimeStatusThresholdNotCrossed(t *testing.T) {
+	// When
+	res, err := RecoverUptimeStatus("anyUptimeId", 1, "anyTableName", mockDynamoDBClient{
+		threshold:         "3",
+		failCounter:       "2",
+		recoveryThreshold: "1",
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.False(t, res, "Result was expected to be false")
+}
+
+// Given uptime status has crossed threshold
+// When uptime status is recovered
+//      and recovery threshold is not reached
+// Then false is returned
+func TestRecoverUptimeStatusRecoveryThresholdNotReached(t *testing.T) {
+	// When
+	res, err := RecoverUptimeStatus("anyUptimeId", 3, "anyTableName", mockDynamoDBClient{
+		threshold:         "3",
+		failCounter:       "4",
+		successCounter:    "1",
+		recoveryThreshold: "3",
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.False(t, res, "Result was expected to be false")
+}
+
+// Given uptime status has crossed threshold
+// When uptime status is recovered
+//      and recovery threshold is reached
+// Then true is returned
+func TestRecoverUptimeStatusRecoveryThresholdReached(t *testing.T) {
+	// When
+	res, err := RecoverUptimeStatus("anyUptimeId", 3, "anyTableName", mockDynamoDBClient{
+		threshold:         "3",
+		failCounter:       "4",
+		successCounter:    "2",
+		recoveryThreshold: "3",
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, res, "Result was expected to be true")
+}
+
+// When uptime status is recovered
+//      and error occurs
+// Then non-nil error is returned
+func TestRecoverUptimeStatusFailure(t *testing.T) {
+	// When
+	_, err := RecoverUptimeStatus("anyUptimeId", 1, "anyTableName", mockDynamoDBClientBroken{})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given uptime status has announced FAIL status
+// When uptime status is recovered
+//      and failCounter has been reset by degraded runs
+//      and recovery threshold is reached
+// Then true is returned
+func TestRecoverUptimeStatusFromAnnouncedStatus(t *testing.T) {
+	// When
+	res, err := RecoverUptimeStatus("anyUptimeId", 1, "anyTableName", mockDynamoDBClient{
+		recoveryThreshold: "1",
+		status:            "FAIL",
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, res, "Result was expected to be true")
+}
+
+// Given DEGRADED status has not been announced
+// When uptime status is degraded
+//      and degraded threshold is reached
+// Then true is returned
+func TestDegradeUptimeStatusThresholdReached(t *testing.T) {
+	// When
+	res, err := DegradeUptimeStatus("anyUptimeId", 2, "anyTableName", mockDynamoDBClient{
+		degradedCounter:   "1",
+		degradedThreshold: "2",
+		status:            "FAIL",
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, res, "Result was expected to be true")
+}
+
+// Given DEGRADED status has been already announced
+// When uptime status is degraded
+//      and degraded threshold is reached
+// Then false is returned
+func TestDegradeUptimeStatusAlreadyAnnounced(t *testing.T) {
+	// When
+	res, err := DegradeUptimeStatus("anyUptimeId", 2, "anyTableName", mockDynamoDBClient{
+		degradedCounter:   "4",
+		degradedThreshold: "2",
+		status:            "DEGRADED",
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.False(t, res, "Result was expected to be false")
+}
+
+// When uptime status is degraded
+//      and degraded threshold is not reached
+// Then false is returned
+func TestDegradeUptimeStatusThresholdNotReached(t *testing.T) {
 	// When
-	res, err := ClearUptimeStatus("anyUptimeId", "anyTableName", mockDynamoDBClient{})
+	res, err := DegradeUptimeStatus("anyUptimeId", 3, "anyTableName", mockDynamoDBClient{
+		degradedCounter:   "1",
+		degradedThreshold: "3",
+	})
 
 	// Then
 	assert.Nil(t, err, "Error was not expected to be returned")
 	assert.False(t, res, "Result was expected to be false")
 }
 
-// When uptime status is cleared
+// When uptime status is degraded
+//      and error occurs
+// Then non-nil error is returned
+func TestDegradeUptimeStatusFailure(t *testing.T) {
+	// When
+	_, err := DegradeUptimeStatus("anyUptimeId", 3, "anyTableName", mockDynamoDBClientBroken{})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given uptime results are stored on multiple pages
+// When uptime results are listed
+// Then results from all pages are returned
+func TestListUptimeResultsSuccess(t *testing.T) {
+	// When
+	res, err := ListUptimeResults("anyUptimeId", 0, 300, "anyTableName", "anyIndexName", mockDynamoDBClient{})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Len(t, res, 2, "Unexpected number of results")
+	assert.Equal(t, "anyUptimeId", res[0].UptimeID, "Unexpected uptime ID")
+	assert.Equal(t, 500, res[1].StatusCode, "Unexpected status code")
+}
+
+// When uptime results are listed
+//      and error occurs
+// Then non-nil error is returned
+func TestListUptimeResultsFailure(t *testing.T) {
+	// When
+	_, err := ListUptimeResults("anyUptimeId", 0, 300, "anyTableName", "anyIndexName", mockDynamoDBClientBroken{})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given storage
+// When uptime status is updated with threshold
+// Then threshold is stored as number
+//      and true is returned once it is crossed
+func TestStorageUpdateUptimeStatus(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	store := NewStorage(mockDynamoDBClient{failCounter: "2", threshold: "2", putItems: &putItems},
+		"", "anyIndexName", "anyTableName", "", "anyIndexName")
+
+	// When
+	res, err := store.UpdateUptimeStatus("anyUptimeId", 2)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, res, "Result was expected to be true")
+	assert.Equal(t, "2", *putItems[0].Item["threshold"].N, "Unexpected threshold")
+}
+
+// Given storage without executions table
+// When uptime result is stored
+// Then result is not stored
+//      and nil is returned
+func TestStorageStoreUptimeResultWithoutTable(t *testing.T) {
+	// Given
+	store := NewStorage(mockDynamoDBClientBroken{}, "", "anyIndexName", "anyTableName", "", "anyIndexName")
+
+	// When
+	err := store.StoreUptimeResult(&UptimeResultItem{UptimeID: "anyUptimeId"})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+}
+
+// Given uptime status exists
+// When uptime status is read
+// Then stored counters are returned
+func TestGetUptimeStatusSuccess(t *testing.T) {
+	// When
+	res, err := GetUptimeStatus("anyUptimeId", "anyTableName", mockDynamoDBClient{
+		threshold:   "2",
+		failCounter: "3",
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "anyUptimeId", res.UptimeID, "Unexpected uptime ID")
+	assert.Equal(t, 3, res.FailCounter, "Unexpected fail counter")
+	assert.Equal(t, 2, res.Threshold, "Unexpected threshold")
+}
+
+// Given uptime status does not exist
+// When uptime status is read
+// Then nil is returned
+func TestGetUptimeStatusMissing(t *testing.T) {
+	// When
+	res, err := GetUptimeStatus("anyUptimeId", "anyTableName", mockDynamoDBClient{})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Nil(t, res, "Result was expected to be nil")
+}
+
+// When uptime status is read
 //      and error occurs
 // Then non-nil error is returned
-func TestClearUptimeStatusFailure(t *testing.T) {
+func TestGetUptimeStatusFailure(t *testing.T) {
+	// When
+	_, err := GetUptimeStatus("anyUptimeId", "anyTableName", mockDynamoDBClientBroken{})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// When notification is recorded
+// Then no error is returned
+func TestRecordNotificationSuccess(t *testing.T) {
+	// When
+	err := RecordNotification("anyUptimeId", "reminder", 1600000000, "anyTableName", mockDynamoDBClient{})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+}
+
+// Given uptime status does not exist
+// When notification is recorded
+// Then no error is returned
+func TestRecordNotificationMissing(t *testing.T) {
+	// When
+	err := RecordNotification("anyUptimeId", "transition", 1600000000, "anyTableName", mockDynamoDBClient{
+		missingStatus: true,
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+}
+
+// When notification of unsupported kind is recorded
+//      or error occurs
+// Then non-nil error is returned
+func TestRecordNotificationFailure(t *testing.T) {
+	// When
+	unsupportedErr := RecordNotification("anyUptimeId", "unknown", 1600000000, "anyTableName", mockDynamoDBClient{})
+	err := RecordNotification("anyUptimeId", "escalation", 1600000000, "anyTableName", mockDynamoDBClientBroken{})
+
+	// Then
+	assert.NotNil(t, unsupportedErr, "Error was expected to be returned for unsupported kind")
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given uptime status with announced status
+//       or uptime status without announced status
+// When uptime status is acknowledged
+// Then true is returned only for announced status
+func TestAcknowledgeUptimeStatus(t *testing.T) {
+	// When
+	acknowledged, err := AcknowledgeUptimeStatus("anyUptimeId", "alice", "deploying fix", 100, "anyTableName", mockDynamoDBClient{})
+	notAcknowledged, notErr := AcknowledgeUptimeStatus("anyUptimeId", "alice", "", 100, "anyTableName", mockDynamoDBClient{
+		missingStatus: true,
+	})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Nil(t, notErr, "Error was not expected to be returned")
+	assert.True(t, acknowledged, "Announced status was expected to be acknowledged")
+	assert.False(t, notAcknowledged, "Not announced status was not expected to be acknowledged")
+}
+
+// Given uptime status with alternating outcomes of recent runs
+// When flapping is detected
+// Then FLAPPING is returned
+//      and status with flap score is put
+func TestDetectFlapping(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	db := mockDynamoDBClient{successCounter: "1", version: "4", history: "FOFO", putItems: &putItems}
+
+	// When
+	announce, err := DetectFlapping("anyUptimeId", 4, 50, 25, "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, storage.ANNOUNCED_FLAPPING, announce, "FLAPPING was expected to be returned")
+	assert.Len(t, putItems, 1, "Status was expected to be put once")
+	assert.Equal(t, "FLAPPING", *putItems[0].Item["state"].S, "Unexpected state")
+	assert.Equal(t, "100", *putItems[0].Item["flapScore"].N, "Unexpected flap score")
+}
+
+// Given failing uptime status
+// When uptime monitor is marked unreachable due to its parent
+// Then status with parent is put
+func TestMarkUnreachable(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	db := mockDynamoDBClient{threshold: "3", failCounter: "1", version: "2", putItems: &putItems}
+
+	// When
+	marked, err := MarkUnreachable("anyUptimeId", "gateway", "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, marked, "Uptime monitor was expected to be marked")
+	assert.Equal(t, "UNREACHABLE_DUE_TO_PARENT", *putItems[0].Item["state"].S, "Unexpected state")
+	assert.Equal(t, "gateway", *putItems[0].Item["parent"].S, "Unexpected parent")
+	assert.Equal(t, "1", *putItems[0].Item["failCounter"].N, "Fail counter was expected to be kept")
+}
+
+// Given uptime status without maintenance window
+// When maintenance window is set
+//      and ended
+// Then status with window is put
+//      and ending window of status without window is not put
+func TestSetMaintenance(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	db := mockDynamoDBClient{threshold: "3", failCounter: "1", version: "2", putItems: &putItems}
+
+	// When
+	started, err := SetMaintenance("anyUptimeId", "deploy", "anyTableName", db)
+	ended, _ := SetMaintenance("anyUptimeId", "", "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, started, "Start of maintenance window was expected to be reported")
+	assert.False(t, ended, "Maintenance window was not expected to change")
+	assert.Len(t, putItems, 1, "Status was expected to be put once")
+	assert.Equal(t, "deploy", *putItems[0].Item["maintenance"].S, "Unexpected maintenance window")
+}
+
+// Given uptime monitor has no silences
+// When silence is stored
+// Then silences item of uptime monitor is created with expiration of silence
+func TestPutSilence(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	db := mockDynamoDBClientSilences{putItems: &putItems}
+
+	// When
+	err := PutSilence(&storage.SilenceItem{ID: "a", UptimeID: "uptime-1", Until: 200}, "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Len(t, putItems, 1, "Silences were expected to be put once")
+	assert.Equal(t, "attribute_not_exists(uptimeId)", *putItems[0].ConditionExpression, "Silences were expected to be created")
+	assert.Equal(t, "silence#uptime-1", *putItems[0].Item["uptimeId"].S, "Unexpected key")
+	assert.Equal(t, "200", *putItems[0].Item["expiresAt"].N, "Unexpected expiration")
+	assert.Equal(t, "uptime-1", *putItems[0].Item["silences"].M["a"].M["uptimeId"].S, "Unexpected silenced uptime monitor")
+}
+
+// Given uptime monitor has silence
+// When another silence is stored
+// Then both silences are put with incremented version
+//      and item expires with the last silence
+func TestPutSilenceExisting(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	db := mockDynamoDBClientSilences{
+		items: map[string]silencesItem{
+			"silence#uptime-1": {Key: "silence#uptime-1", Version: 2, Silences: map[string]storage.SilenceItem{
+				"a": {ID: "a", UptimeID: "uptime-1", Until: 300},
+			}},
+		},
+		putItems: &putItems,
+	}
+
+	// When
+	err := PutSilence(&storage.SilenceItem{ID: "b", UptimeID: "uptime-1", Until: 200}, "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "#version = :version", *putItems[0].ConditionExpression, "Silences were expected to be put conditionally")
+	assert.Equal(t, "2", *putItems[0].ExpressionAttributeValues[":version"].N, "Unexpected expected version")
+	assert.Equal(t, "3", *putItems[0].Item["version"].N, "Unexpected version")
+	assert.Len(t, putItems[0].Item["silences"].M, 2, "Unexpected number of silences")
+	assert.Equal(t, "300", *putItems[0].Item["expiresAt"].N, "Unexpected expiration")
+}
+
+// Given silences of uptime monitor and silences matching tags only are stored
+// When silences of uptime monitor are listed
+// Then they are read by their keys
+//      and silences which have not expired are returned ordered by ID
+func TestListSilences(t *testing.T) {
+	// Given
+	var gets []string
+	db := mockDynamoDBClientSilences{
+		items: map[string]silencesItem{
+			"silence#*": {Key: "silence#*", Silences: map[string]storage.SilenceItem{
+				"b": {ID: "b", Match: map[string]string{"team": "payments"}, Until: 300},
+			}},
+			"silence#uptime-1": {Key: "silence#uptime-1", Silences: map[string]storage.SilenceItem{
+				"a": {ID: "a", UptimeID: "uptime-1", Until: 200},
+				"c": {ID: "c", UptimeID: "uptime-1", Until: 100},
+			}},
+		},
+		gets: &gets,
+	}
+
+	// When
+	res, err := ListSilences("uptime-1", 150, "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.ElementsMatch(t, []string{"silence#*", "silence#uptime-1"}, gets, "Unexpected keys of read silences")
+	assert.Len(t, res, 2, "Unexpected number of silences")
+	assert.Equal(t, "uptime-1", res[0].UptimeID, "Unexpected first silence")
+	assert.Equal(t, "payments", res[1].Match["team"], "Unexpected second silence")
+}
+
+// When silences are listed
+//      and error occurs
+// Then non-nil error is returned
+func TestListSilencesFailure(t *testing.T) {
+	// When
+	_, err := ListSilences("uptime-1", 150, "anyTableName", mockDynamoDBClientBroken{})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given uptime monitor has only expired silences
+// When its silences are pruned
+// Then silences item is deleted conditionally
+func TestPruneSilences(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	var deleteItems []*dynamodb.DeleteItemInput
+	db := mockDynamoDBClientSilences{
+		items: map[string]silencesItem{
+			"silence#uptime-1": {Key: "silence#uptime-1", Version: 4, Silences: map[string]storage.SilenceItem{
+				"a": {ID: "a", UptimeID: "uptime-1", Until: 100},
+			}},
+		},
+		putItems:    &putItems,
+		deleteItems: &deleteItems,
+	}
+
+	// When
+	err := PruneSilences("uptime-1", 150, "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Empty(t, putItems, "Silences were not expected to be put")
+	assert.Len(t, deleteItems, 1, "Silences were expected to be deleted")
+	assert.Equal(t, "silence#uptime-1", *deleteItems[0].Key["uptimeId"].S, "Unexpected deleted key")
+	assert.Equal(t, "4", *deleteItems[0].ExpressionAttributeValues[":version"].N, "Unexpected expected version")
+}
+
+// Given uptime monitor has no silence
+// When silence is deleted
+// Then nothing is written
+//      and false is returned
+func TestDeleteSilenceMissing(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	var deleteItems []*dynamodb.DeleteItemInput
+	db := mockDynamoDBClientSilences{putItems: &putItems, deleteItems: &deleteItems}
+
+	// When
+	deleted, err := DeleteSilence("uptime-1", "a", "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.False(t, deleted, "Silence was not expected to be deleted")
+	assert.Empty(t, putItems, "Silences were not expected to be put")
+	assert.Empty(t, deleteItems, "Silences were not expected to be deleted")
+}
+
+// Given failing uptime status
+// When incident is opened
+// Then incident is put into incidents table
+//      and it is linked to uptime status
+func TestOpenIncident(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	db := mockDynamoDBClient{threshold: "3", failCounter: "3", version: "2", putItems: &putItems}
+
+	// When
+	err := OpenIncident(storage.NewIncident("incident-1", "anyUptimeId", 100, 160), "incidentsTable", "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Len(t, putItems, 2, "Incident and status were expected to be put")
+	assert.Equal(t, "incidentsTable", *putItems[0].TableName, "Incident was expected to be put into incidents table")
+	assert.Len(t, putItems[0].Item["timeline"].L, 2, "Unexpected timeline")
+	assert.Equal(t, "incident-1", *putItems[1].Item["incidentId"].S, "Incident was expected to be linked to status")
+}
+
+// Given uptime status with open incident
+// When incident is closed
+// Then closing time, duration and recovered event are set by update of incident
+//      and incident is unlinked from uptime status
+func TestCloseIncident(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	var updateItems []*dynamodb.UpdateItemInput
+	db := mockDynamoDBClient{successCounter: "1", version: "5", incidentID: "incident-1", putItems: &putItems, updateItems: &updateItems}
+
+	// When
+	closed, err := CloseIncident("anyUptimeId", 400, "incidentsTable", "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, closed, "Incident was expected to be closed")
+	assert.Equal(t, "incident-1", *updateItems[0].Key["id"].S, "Unexpected updated incident")
+	assert.Equal(t, "400", *updateItems[0].ExpressionAttributeValues[":at"].N, "Unexpected closing time")
+	assert.Equal(t, storage.EVENT_RECOVERED, *updateItems[0].ExpressionAttributeValues[":events"].L[0].M["type"].S, "Unexpected event")
+	assert.Len(t, putItems, 1, "Status was expected to be put once")
+	assert.Nil(t, putItems[0].Item["incidentId"], "Incident was expected to be unlinked from status")
+}
+
+// Given uptime status without open incident
+// When event is added and incident is closed
+// Then incident is not updated
+//      and false is returned
+func TestIncidentEventWithoutIncident(t *testing.T) {
+	// Given
+	var updateItems []*dynamodb.UpdateItemInput
+	db := mockDynamoDBClient{successCounter: "1", version: "5", updateItems: &updateItems}
+
+	// When
+	added, err := AddIncidentEvent("anyUptimeId", storage.IncidentEvent{Type: storage.EVENT_NOTIFIED, At: 100}, "incidentsTable", "anyTableName", db)
+	closed, _ := CloseIncident("anyUptimeId", 400, "incidentsTable", "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.False(t, added, "Event was not expected to be added")
+	assert.False(t, closed, "Incident was not expected to be closed")
+	assert.Empty(t, updateItems, "Incident was not expected to be updated")
+}
+
+// Given incident exists
+//       or incident does not exist
+// When note is added
+// Then true is returned only for existing incident
+func TestAddIncidentNote(t *testing.T) {
+	// Given
+	var updateItems []*dynamodb.UpdateItemInput
+	note := storage.IncidentNote{At: 200, By: "alice", Text: "disk full"}
+
+	// When
+	added, err := AddIncidentNote("incident-1", note, "incidentsTable", mockDynamoDBClient{updateItems: &updateItems})
+	missing, missingErr := AddIncidentNote("incident-2", note, "incidentsTable", mockDynamoDBClient{missingStatus: true})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Nil(t, missingErr, "Error was not expected to be returned")
+	assert.True(t, added, "Note was expected to be added")
+	assert.False(t, missing, "Note of missing incident was not expected to be added")
+	assert.Equal(t, "disk full", *updateItems[0].ExpressionAttributeValues[":notes"].L[0].M["text"].S, "Unexpected note")
+}
+
+// Given storage without incidents table
+// When incident is opened and incidents are listed
+// Then nothing is stored nor queried
+func TestStorageIncidentsWithoutTable(t *testing.T) {
+	// Given
+	store := NewStorage(mockDynamoDBClientBroken{}, "", "anyIndexName", "anyTableName", "", "anyIndexName")
+
+	// When
+	err := store.OpenIncident(storage.NewIncident("incident-1", "anyUptimeId", 100, 160))
+	incidents, listErr := store.ListIncidents("anyUptimeId", 0, 200)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Nil(t, listErr, "Error was not expected to be returned")
+	assert.Empty(t, incidents, "No incidents were expected to be listed")
+}
+
+// Given uptime monitor has recorded delivery attempt
+// When further delivery attempts are recorded
+// Then all attempts are put with incremented version
+//      and item is put only if it has not been changed concurrently
+func TestRecordDeliveryAttempts(t *testing.T) {
+	// Given
+	var putItems []*dynamodb.PutItemInput
+	db := mockDynamoDBClientDeliveries{
+		items: map[string]deliveryAttemptsItem{
+			"delivery#uptime-1": {Key: "delivery#uptime-1", Version: 1, Attempts: []storage.DeliveryAttemptItem{
+				{DeliveryID: "a", Attempt: 1, StatusCode: 200},
+			}},
+		},
+		putItems: &putItems,
+	}
+
+	// When
+	err := RecordDeliveryAttempts("uptime-1", []storage.DeliveryAttemptItem{
+		{DeliveryID: "b", Attempt: 1, StatusCode: 502, Error: "unexpected status code 502"},
+		{DeliveryID: "b", Attempt: 2, StatusCode: 200},
+	}, "anyTableName", db)
+	attempts, listErr := ListDeliveryAttempts("uptime-1", "anyTableName", db)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Nil(t, listErr, "Error was not expected to be returned")
+	assert.Len(t, putItems, 1, "Delivery attempts were expected to be put once")
+	assert.Equal(t, "#version = :version", *putItems[0].ConditionExpression, "Delivery attempts were expected to be put conditionally")
+	assert.Equal(t, "1", *putItems[0].ExpressionAttributeValues[":version"].N, "Unexpected expected version")
+	assert.Equal(t, "2", *putItems[0].Item["version"].N, "Unexpected version")
+	assert.Len(t, putItems[0].Item["attempts"].L, 3, "Unexpected number of delivery attempts")
+	assert.Equal(t, "b", *putItems[0].Item["attempts"].L[2].M["deliveryId"].S, "Unexpected last delivery attempt")
+	assert.Len(t, attempts, 1, "Stored delivery attempts were expected to be listed")
+}
+
+// Given DynamoDB which cannot be read
+// When delivery attempts are recorded
+// Then error is returned
+func TestRecordDeliveryAttemptsFailure(t *testing.T) {
 	// When
-	_, err := ClearUptimeStatus("anyUptimeId", "anyTableName", mockDynamoDBClientBroken{})
+	err := RecordDeliveryAttempts("uptime-1", []storage.DeliveryAttemptItem{{DeliveryID: "a", Attempt: 1}}, "anyTableName", mockDynamoDBClientBroken{})
 
 	// Then
 	assert.NotNil(t, err, "Error was expected to be returned")
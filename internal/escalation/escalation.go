@@ -0,0 +1,64 @@
+package escalation
+
+import (
+	"monitor-uptime/internal/notifier"
+	"monitor-uptime/internal/storage"
+	"time"
+)
+
+// Represents escalation policy of persisting outage
+// FAIL transition is announced to primary channels of uptime monitor. While uptime monitor keeps failing, reminder
+// is sent every repeat interval, and once outage is not resolved within escalation delay, it is escalated to
+// secondary channels. Reminders and escalation stop once outage is acknowledged or uptime monitor recovers.
+type Policy struct {
+	RepeatInterval int                `json:"repeatInterval"` // Minutes between reminders while uptime monitor is failing, 0 means no reminders
+	EscalateAfter  int                `json:"escalateAfter"`  // Minutes after FAIL announcement when outage is escalated, 0 means no escalation
+	Channels       []notifier.Channel `json:"channels"`       // Secondary channels to which outage is escalated
+}
+
+// Represents notifications due according to escalation policy
+type Action struct {
+	Remind   bool // Whether reminder of outage is due
+	Escalate bool // Whether outage should be escalated to secondary channels
+}
+
+// Returns notifications due at time for uptime status, which is read before failed run is counted
+// Nothing is due unless FAIL has been announced, it has not been acknowledged and uptime monitor is not flapping. Outage which has not been
+// notified yet (e.g. notification failed) is reminded immediately. Escalation delay is measured from FAIL
+// announcement, or from outage start for statuses which do not record it.
+func (p *Policy) Next(status *storage.UptimeStatusItem, now time.Time) Action {
+	var action Action
+	if p == nil || status.AnnouncedStatus() != storage.ANNOUNCED_FAIL || status.IsAcknowledged() ||
+		status.CurrentState() == storage.STATE_FLAPPING {
+		return action
+	}
+	if p.RepeatInterval > 0 {
+		action.Remind = status.NotifiedAt == 0 || elapsed(status.NotifiedAt, now) >= minutes(p.RepeatInterval)
+	}
+	if p.EscalateAfter > 0 && len(p.Channels) > 0 && status.EscalatedAt == 0 {
+		since := status.AnnouncedAt
+		if since == 0 {
+			since = status.FailingSince
+		}
+		action.Escalate = since != 0 && elapsed(since, now) >= minutes(p.EscalateAfter)
+	}
+	return action
+}
+
+// Returns secondary channels which are notified about recovery from outage, none if outage has not been escalated
+func (p *Policy) RecoveryChannels(previous *storage.UptimeStatusItem) []notifier.Channel {
+	if p == nil || previous == nil || previous.EscalatedAt == 0 {
+		return nil
+	}
+	return p.Channels
+}
+
+// Returns duration elapsed since timestamp
+func elapsed(timestamp int64, now time.Time) time.Duration {
+	return now.Sub(time.Unix(timestamp, 0))
+}
+
+// Returns duration of minutes
+func minutes(value int) time.Duration {
+	return time.Duration(value) * time.Minute
+}
@@ -0,0 +1,118 @@
+package escalation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/notifier"
+	"monitor-uptime/internal/storage"
+	"testing"
+	"time"
+)
+
+var (
+	now    = time.Date(2020, 9, 14, 10, 0, 0, 0, time.UTC)
+	policy = &Policy{
+		RepeatInterval: 15,
+		EscalateAfter:  30,
+		Channels:       []notifier.Channel{{Type: notifier.CHANNEL_PAGERDUTY}},
+	}
+)
+
+// Returns timestamp of minutes before now
+func minutesAgo(value int) int64 {
+	return now.Add(-time.Duration(value) * time.Minute).Unix()
+}
+
+// Given FAIL has been announced 10 minutes ago
+// When next notifications are evaluated
+// Then nothing is due
+func TestNextNothingDue(t *testing.T) {
+	// Given
+	status := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_FAIL, AnnouncedAt: minutesAgo(10), NotifiedAt: minutesAgo(10)}
+
+	// When
+	action := policy.Next(status, now)
+
+	// Then
+	assert.Equal(t, Action{}, action, "Nothing was expected to be due")
+}
+
+// Given FAIL has been announced 20 minutes ago
+// When next notifications are evaluated
+// Then reminder is due but outage is not escalated yet
+func TestNextRemind(t *testing.T) {
+	// Given
+	status := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_FAIL, AnnouncedAt: minutesAgo(20), NotifiedAt: minutesAgo(20)}
+
+	// When
+	action := policy.Next(status, now)
+
+	// Then
+	assert.Equal(t, Action{Remind: true}, action, "Only reminder was expected to be due")
+}
+
+// Given FAIL has been announced 40 minutes ago and reminded 10 minutes ago
+// When next notifications are evaluated
+// Then outage is escalated once
+func TestNextEscalate(t *testing.T) {
+	// Given
+	status := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_FAIL, AnnouncedAt: minutesAgo(40), NotifiedAt: minutesAgo(10)}
+	escalated := *status
+	escalated.EscalatedAt = minutesAgo(5)
+
+	// When
+	action := policy.Next(status, now)
+	again := policy.Next(&escalated, now)
+
+	// Then
+	assert.Equal(t, Action{Escalate: true}, action, "Escalation was expected to be due")
+	assert.Equal(t, Action{}, again, "Outage was not expected to be escalated again")
+}
+
+// Given FAIL has been announced by status which does not record notifications
+// When next notifications are evaluated
+// Then reminder is due immediately and escalation delay is measured from outage start
+func TestNextNotRecorded(t *testing.T) {
+	// Given
+	status := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_FAIL, FailingSince: minutesAgo(45)}
+
+	// When
+	action := policy.Next(status, now)
+
+	// Then
+	assert.Equal(t, Action{Remind: true, Escalate: true}, action, "Reminder and escalation were expected to be due")
+}
+
+// Given uptime status without announced FAIL
+//       or acknowledged outage
+//       or flapping uptime monitor
+//       or uptime monitor without escalation policy
+// When next notifications are evaluated
+// Then nothing is due
+func TestNextNotFailing(t *testing.T) {
+	// Given
+	var none *Policy
+	degraded := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_DEGRADED, AnnouncedAt: minutesAgo(60)}
+	failing := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_FAIL, AnnouncedAt: minutesAgo(60)}
+	acknowledged := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_FAIL, AnnouncedAt: minutesAgo(60), AcknowledgedAt: minutesAgo(50)}
+	flapping := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_FAIL, State: storage.STATE_FLAPPING, AnnouncedAt: minutesAgo(60)}
+
+	// Then
+	assert.Equal(t, Action{}, policy.Next(degraded, now), "Nothing was expected to be due for DEGRADED status")
+	assert.Equal(t, Action{}, policy.Next(nil, now), "Nothing was expected to be due for missing status")
+	assert.Equal(t, Action{}, policy.Next(acknowledged, now), "Nothing was expected to be due for acknowledged outage")
+	assert.Equal(t, Action{}, policy.Next(flapping, now), "Nothing was expected to be due for flapping uptime monitor")
+	assert.Equal(t, Action{}, none.Next(failing, now), "Nothing was expected to be due without policy")
+}
+
+// Given escalated and not escalated outages
+// When recovery channels are requested
+// Then secondary channels are returned for escalated outage only
+func TestRecoveryChannels(t *testing.T) {
+	// Given
+	escalated := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_FAIL, EscalatedAt: minutesAgo(5)}
+	notEscalated := &storage.UptimeStatusItem{Status: storage.ANNOUNCED_FAIL}
+
+	// Then
+	assert.Len(t, policy.RecoveryChannels(escalated), 1, "Secondary channels were expected to be notified")
+	assert.Empty(t, policy.RecoveryChannels(notEscalated), "Secondary channels were not expected to be notified")
+}
@@ -0,0 +1,153 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"monitor-uptime/internal/tags"
+	"time"
+	// Time zones of windows are embedded, as Lambda runtime may not provide time zone database
+	_ "time/tzdata"
+)
+
+// Maximal duration of recurring window in minutes (one week)
+const MAX_DURATION = 7 * 24 * 60
+
+// Layout of one-off window's start and end in window's time zone, RFC 3339 is accepted as well
+const LOCAL_LAYOUT = "2006-01-02 15:04"
+
+// Represents maintenance windows shared by uptime monitors, which are matched by their IDs or tags
+type Config struct {
+	Windows []Window `json:"windows"`
+}
+
+// Represents maintenance window, either one-off (start and end) or recurring (cron schedule and duration)
+// During maintenance window runs are stored flagged as maintenance, but they are not counted towards
+// thresholds and no notifications are sent.
+type Window struct {
+	Name      string            `json:"name"`
+	UptimeIDs []string          `json:"uptimeIds"` // Uptime monitors in maintenance, windows of monitor itself do not need them
+	Match     map[string]string `json:"match"`     // Tags which uptime monitor must have, "*" matches any value
+	TimeZone  string            `json:"timeZone"`  // IANA time zone of start, end and schedule, e.g. Europe/Prague, UTC if empty
+	Start     string            `json:"start"`     // Start of one-off window, e.g. 2020-09-14 22:00
+	End       string            `json:"end"`       // End of one-off window (exclusive)
+	Schedule  string            `json:"schedule"`  // Cron expression (minute hour day-of-month month day-of-week) of recurring window starts
+	Duration  int               `json:"duration"`  // Duration of recurring window in minutes
+	Announce  bool              `json:"announce"`  // Whether start and end of window are announced
+}
+
+// Parses maintenance configuration from JSON and validates its windows
+// Returns error if configuration cannot be parsed or any window is not valid
+func Parse(content []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+	for _, window := range config.Windows {
+		if err := window.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &config, nil
+}
+
+// Loads maintenance configuration from JSON file
+// Value starting with { is parsed as inline JSON configuration instead
+func Load(pathOrJSON string) (*Config, error) {
+	content, err := tags.ReadConfig(pathOrJSON)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// Returns window of configuration active at time for uptime monitor with tags, nil if there is none
+// Window matches uptime monitor if it lists its ID or if uptime monitor has all its tags
+func (c *Config) Active(uptimeID string, tags map[string]string, at time.Time) *Window {
+	for i := range c.Windows {
+		if c.Windows[i].matches(uptimeID, tags) && c.Windows[i].IsActive(at) {
+			return &c.Windows[i]
+		}
+	}
+	return nil
+}
+
+// Returns the first of windows active at time, nil if there is none
+func Active(windows []Window, at time.Time) *Window {
+	for i := range windows {
+		if windows[i].IsActive(at) {
+			return &windows[i]
+		}
+	}
+	return nil
+}
+
+// Validates that window is either one-off or recurring and its times, schedule and time zone are well-formed
+func (w *Window) Validate() error {
+	location, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return err
+	}
+	if w.Schedule != "" {
+		if w.Start != "" || w.End != "" {
+			return errors.New("window " + w.Name + " is either one-off or recurring")
+		}
+		if w.Duration <= 0 || w.Duration > MAX_DURATION {
+			return errors.New("window " + w.Name + " has invalid duration")
+		}
+		_, err = parseSchedule(w.Schedule)
+		return err
+	}
+	start, err := parseTime(w.Start, location)
+	if err != nil {
+		return err
+	}
+	end, err := parseTime(w.End, location)
+	if err != nil {
+		return err
+	}
+	if !end.After(start) {
+		return errors.New("window " + w.Name + " ends before it starts")
+	}
+	return nil
+}
+
+// Returns true if window is active at time, invalid window is never active
+// Recurring window is active if its latest start by schedule is within its duration before time.
+func (w *Window) IsActive(at time.Time) bool {
+	location, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return false
+	}
+	if w.Schedule == "" {
+		start, startErr := parseTime(w.Start, location)
+		end, endErr := parseTime(w.End, location)
+		return startErr == nil && endErr == nil && !at.Before(start) && at.Before(end)
+	}
+
+	schedule, err := parseSchedule(w.Schedule)
+	if err != nil {
+		return false
+	}
+	minute := at.In(location).Truncate(time.Minute)
+	_, started := schedule.previous(minute, minute.Add(-time.Duration(w.Duration-1)*time.Minute))
+	return started
+}
+
+// Returns true if window lists uptime monitor or uptime monitor has all its tags
+// Window without uptime IDs and tags does not match any uptime monitor
+func (w *Window) matches(uptimeID string, monitorTags map[string]string) bool {
+	for _, id := range w.UptimeIDs {
+		if id == uptimeID {
+			return true
+		}
+	}
+	return len(w.Match) > 0 && tags.Matches(w.Match, monitorTags)
+}
+
+// Parses time in RFC 3339 format, or in LOCAL_LAYOUT format in location
+func parseTime(value string, location *time.Location) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.ParseInLocation(LOCAL_LAYOUT, value, location)
+}
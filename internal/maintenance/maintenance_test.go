@@ -0,0 +1,174 @@
+package maintenance
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// Maintenance configuration with weekly deploy window of payments team and one-off migration of single monitor
+const testConfig = `{
+	"windows": [
+		{"name": "deploy", "match": {"team": "payments"}, "timeZone": "Europe/Prague", "schedule": "0 22 * * 2", "duration": 60, "announce": true},
+		{"name": "migration", "uptimeIds": ["uptime-1"], "timeZone": "Europe/Prague", "start": "2020-09-14 20:00", "end": "2020-09-14 21:00"}
+	]
+}`
+
+var (
+	tuesdayDeploy = time.Date(2020, 9, 15, 20, 30, 0, 0, time.UTC) // 22:30 in Prague
+	tuesdayAfter  = time.Date(2020, 9, 15, 21, 0, 0, 0, time.UTC)  // 23:00 in Prague
+	mondayEvening = time.Date(2020, 9, 14, 18, 15, 0, 0, time.UTC) // 20:15 in Prague
+)
+
+// Given maintenance configuration
+// When active window is looked up for payments monitor during deploy window
+// Then recurring deploy window is active until its duration elapses
+func TestActiveRecurringWindow(t *testing.T) {
+	// Given
+	config, err := Parse([]byte(testConfig))
+	assert.Nil(t, err, "Error was not expected to be returned")
+	tags := map[string]string{"team": "payments"}
+
+	// When
+	active := config.Active("uptime-2", tags, tuesdayDeploy)
+	after := config.Active("uptime-2", tags, tuesdayAfter)
+	otherTeam := config.Active("uptime-2", map[string]string{"team": "search"}, tuesdayDeploy)
+
+	// Then
+	assert.Equal(t, "deploy", active.Name, "Deploy window was expected to be active")
+	assert.Nil(t, after, "Deploy window was not expected to be active after its duration")
+	assert.Nil(t, otherTeam, "Deploy window was not expected to match other team")
+}
+
+// Given maintenance configuration
+// When active window is looked up for migrated monitor
+// Then one-off window is active between its start and end
+func TestActiveOneOffWindow(t *testing.T) {
+	// Given
+	config, err := Parse([]byte(testConfig))
+	assert.Nil(t, err, "Error was not expected to be returned")
+
+	// When
+	active := config.Active("uptime-1", nil, mondayEvening)
+	before := config.Active("uptime-1", nil, mondayEvening.Add(-time.Hour))
+
+	// Then
+	assert.Equal(t, "migration", active.Name, "Migration window was expected to be active")
+	assert.Nil(t, before, "Migration window was not expected to be active before its start")
+}
+
+// Given windows of uptime monitor
+// When active window is looked up
+// Then window is active regardless of uptime IDs and tags
+func TestActiveMonitorWindows(t *testing.T) {
+	// Given
+	windows := []Window{{Name: "nightly", Schedule: "*/30 1-2 * * *", Duration: 10}}
+
+	// Then
+	assert.NotNil(t, Active(windows, time.Date(2020, 9, 14, 1, 35, 0, 0, time.UTC)), "Window was expected to be active")
+	assert.Nil(t, Active(windows, time.Date(2020, 9, 14, 1, 45, 0, 0, time.UTC)), "Window was not expected to be active")
+	assert.Nil(t, Active(windows, time.Date(2020, 9, 14, 3, 5, 0, 0, time.UTC)), "Window was not expected to be active")
+}
+
+// Given cron expressions restricting both day of month and day of week
+// When schedule is matched
+// Then either of them has to match
+func TestScheduleDays(t *testing.T) {
+	// Given
+	schedule, err := parseSchedule("0 0 1 * 7")
+	assert.Nil(t, err, "Error was not expected to be returned")
+
+	// Then
+	assert.True(t, schedule.matches(time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)), "First day of month was expected to match")
+	assert.True(t, schedule.matches(time.Date(2020, 9, 13, 0, 0, 0, 0, time.UTC)), "Sunday was expected to match")
+	assert.False(t, schedule.matches(time.Date(2020, 9, 14, 0, 0, 0, 0, time.UTC)), "Monday was not expected to match")
+}
+
+// Given cron expression with day of month step matching every day
+// When schedule is matched
+// Then day of month is not restricted and only day of week has to match
+func TestScheduleDayStep(t *testing.T) {
+	// Given
+	schedule, err := parseSchedule("0 0 */1 * 1")
+	assert.Nil(t, err, "Error was not expected to be returned")
+
+	// Then
+	assert.True(t, schedule.matches(time.Date(2020, 9, 14, 0, 0, 0, 0, time.UTC)), "Monday was expected to match")
+	assert.False(t, schedule.matches(time.Date(2020, 9, 15, 0, 0, 0, 0, time.UTC)), "Tuesday was not expected to match")
+}
+
+// Given recurring windows with step expressions
+// When active window is looked up
+// Then window is active within its duration after every step
+func TestActiveStepWindows(t *testing.T) {
+	// Given
+	quarterly := []Window{{Name: "quarterly", Schedule: "*/15 * * * *", Duration: 5}}
+	sixHourly := []Window{{Name: "six-hourly", Schedule: "30 */6 * * *", Duration: 90}}
+
+	// Then
+	assert.NotNil(t, Active(quarterly, time.Date(2020, 9, 14, 10, 49, 0, 0, time.UTC)), "Window was expected to be active")
+	assert.Nil(t, Active(quarterly, time.Date(2020, 9, 14, 10, 50, 0, 0, time.UTC)), "Window was not expected to be active")
+	assert.NotNil(t, Active(sixHourly, time.Date(2020, 9, 14, 13, 59, 0, 0, time.UTC)), "Window was expected to be active")
+	assert.Nil(t, Active(sixHourly, time.Date(2020, 9, 14, 14, 0, 0, 0, time.UTC)), "Window was not expected to be active")
+	assert.Nil(t, Active(sixHourly, time.Date(2020, 9, 14, 12, 29, 0, 0, time.UTC)), "Window was not expected to be active")
+}
+
+// Given recurring window lasting several days
+// When active window is looked up
+// Then window is active across midnights until its duration elapses
+func TestActiveMultiDayWindow(t *testing.T) {
+	// Given
+	windows := []Window{{Name: "weekend", Schedule: "0 22 * * 5", Duration: 3 * 24 * 60}}
+
+	// Then
+	assert.NotNil(t, Active(windows, time.Date(2020, 9, 18, 22, 0, 0, 0, time.UTC)), "Window was expected to be active")
+	assert.NotNil(t, Active(windows, time.Date(2020, 9, 21, 21, 59, 0, 0, time.UTC)), "Window was expected to be active")
+	assert.Nil(t, Active(windows, time.Date(2020, 9, 21, 22, 0, 0, 0, time.UTC)), "Window was not expected to be active")
+	assert.Nil(t, Active(windows, time.Date(2020, 9, 18, 21, 59, 0, 0, time.UTC)), "Window was not expected to be active")
+}
+
+// Given cron expressions
+// When the latest start of window is looked up for minutes of a week
+// Then it is the latest minute matched by schedule within window's duration
+func TestSchedulePrevious(t *testing.T) {
+	for _, expression := range []string{"*/15 * * * *", "30 */6 * * *", "0 22 * * 2", "5,50 9-17 1,15 * 1-5", "0 0 */1 * 7"} {
+		// Given
+		schedule, err := parseSchedule(expression)
+		assert.Nil(t, err, "Error was not expected to be returned")
+		start := time.Date(2020, 9, 14, 0, 0, 0, 0, time.UTC)
+
+		for minute := start; minute.Before(start.AddDate(0, 0, 7)); minute = minute.Add(7 * time.Minute) {
+			// When
+			earliest := minute.Add(-2 * 24 * time.Hour)
+			previous, ok := schedule.previous(minute, earliest)
+
+			// Then
+			expected := minute
+			for !expected.Before(earliest) && !schedule.matches(expected) {
+				expected = expected.Add(-time.Minute)
+			}
+			if expected.Before(earliest) {
+				assert.False(t, ok, "No start was expected for "+expression+" at "+minute.String())
+			} else {
+				assert.Equal(t, expected, previous, "Unexpected start for "+expression+" at "+minute.String())
+			}
+		}
+	}
+}
+
+// When configuration with invalid window is parsed
+// Then error is returned
+func TestParseInvalid(t *testing.T) {
+	for _, config := range []string{
+		`{"windows": [{"name": "tz", "timeZone": "Mars/Base", "schedule": "0 0 * * *", "duration": 10}]}`,
+		`{"windows": [{"name": "cron", "schedule": "0 0 * *", "duration": 10}]}`,
+		`{"windows": [{"name": "range", "schedule": "0 25 * * *", "duration": 10}]}`,
+		`{"windows": [{"name": "duration", "schedule": "0 0 * * *"}]}`,
+		`{"windows": [{"name": "both", "schedule": "0 0 * * *", "duration": 10, "start": "2020-09-14 20:00"}]}`,
+		`{"windows": [{"name": "order", "start": "2020-09-14 21:00", "end": "2020-09-14 20:00"}]}`,
+		`{"windows": [{"name": "time", "start": "tomorrow", "end": "2020-09-14 20:00"}]}`,
+	} {
+		_, err := Parse([]byte(config))
+		assert.NotNil(t, err, "Error was expected to be returned for "+config)
+	}
+}
@@ -0,0 +1,159 @@
+package maintenance
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Represents parsed cron schedule, every field holds matched values
+type schedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+	anyDay      bool // Whether day of month is not restricted, i.e. field matches every day (*, */1, 1-31)
+	anyWeekday  bool // Whether day of week is not restricted, i.e. field matches every day of week
+}
+
+// Parses cron expression with five fields: minute, hour, day of month, month and day of week
+// Fields may contain *, values, ranges (1-5), lists (1,15) and steps (*/15, 0-30/10). Sunday is 0 or 7.
+func parseSchedule(expression string) (*schedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, errors.New("cron expression must have 5 fields: " + expression)
+	}
+	var s schedule
+	var err error
+	for i, field := range []struct {
+		values   *map[int]bool
+		min, max int
+	}{
+		{&s.minutes, 0, 59},
+		{&s.hours, 0, 23},
+		{&s.daysOfMonth, 1, 31},
+		{&s.months, 1, 12},
+		{&s.daysOfWeek, 0, 7},
+	} {
+		if *field.values, err = parseField(fields[i], field.min, field.max); err != nil {
+			return nil, errors.New("invalid cron expression " + expression + ": " + err.Error())
+		}
+	}
+	if s.daysOfWeek[7] {
+		s.daysOfWeek[0] = true
+	}
+	s.anyDay = coversRange(s.daysOfMonth, 1, 31)
+	s.anyWeekday = coversRange(s.daysOfWeek, 0, 6)
+	return &s, nil
+}
+
+// Returns true if values contain every value between min and max
+func coversRange(values map[int]bool, min int, max int) bool {
+	for value := min; value <= max; value++ {
+		if !values[value] {
+			return false
+		}
+	}
+	return true
+}
+
+// Parses single cron field into set of values between min and max
+func parseField(field string, min int, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if index := strings.Index(part, "/"); index >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[index+1:]); err != nil || step <= 0 {
+				return nil, errors.New("invalid step: " + part)
+			}
+			part = part[:index]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, errors.New("invalid value: " + part)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, errors.New("invalid range: " + part)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, errors.New("value out of range: " + part)
+		}
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// Returns true if schedule matches minute of time
+func (s *schedule) matches(t time.Time) bool {
+	return s.minutes[t.Minute()] && s.hours[t.Hour()] && s.matchesDay(t)
+}
+
+// Returns the latest minute matched by schedule at or before time, but not before earliest
+// Days are walked backwards from time and the latest matching hour and minute of matching day are looked up directly.
+// Returns false if schedule does not match any minute between earliest and time.
+func (s *schedule) previous(t time.Time, earliest time.Time) (time.Time, bool) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	earliestDay := time.Date(earliest.Year(), earliest.Month(), earliest.Day(), 0, 0, 0, 0, t.Location())
+	hour, minute := t.Hour(), t.Minute()
+	for !day.Before(earliestDay) {
+		if s.matchesDay(day) {
+			if matchedHour, matchedMinute, ok := s.latest(hour, minute); ok {
+				match := time.Date(day.Year(), day.Month(), day.Day(), matchedHour, matchedMinute, 0, 0, t.Location())
+				return match, !match.Before(earliest)
+			}
+		}
+		day = day.AddDate(0, 0, -1)
+		hour, minute = 23, 59
+	}
+	return time.Time{}, false
+}
+
+// Returns the latest hour and minute matched by schedule at or before hour and minute of day
+// Returns false if schedule does not match any minute of day until then
+func (s *schedule) latest(hour int, minute int) (int, int, bool) {
+	for ; hour >= 0; hour-- {
+		if s.hours[hour] {
+			for ; minute >= 0; minute-- {
+				if s.minutes[minute] {
+					return hour, minute, true
+				}
+			}
+		}
+		minute = 59
+	}
+	return 0, 0, false
+}
+
+// Returns true if schedule matches month and day of time
+// If both day of month and day of week are restricted, then either of them has to match (as in cron)
+func (s *schedule) matchesDay(t time.Time) bool {
+	if !s.months[int(t.Month())] {
+		return false
+	}
+	day := s.daysOfMonth[t.Day()]
+	weekday := s.daysOfWeek[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
@@ -0,0 +1,349 @@
+package memory
+
+import (
+	"monitor-uptime/internal/storage"
+	"sort"
+	"sync"
+)
+
+// Represents storage of uptime monitor results and statuses kept in memory
+// Stored data are lost once process exits, storage is meant for tests and local runs
+type Storage struct {
+	mutex     sync.Mutex
+	results   []storage.UptimeResultItem
+	statuses  map[string]storage.UptimeStatusItem
+	silences  map[string]map[string]storage.SilenceItem // Silences by key of their uptime monitor and ID
+	attempts  map[string][]storage.DeliveryAttemptItem  // Delivery attempts by uptime ID
+	incidents map[string]storage.IncidentItem
+}
+
+// Creates empty in-memory storage
+func NewStorage() *Storage {
+	return &Storage{
+		statuses:  map[string]storage.UptimeStatusItem{},
+		silences:  map[string]map[string]storage.SilenceItem{},
+		attempts:  map[string][]storage.DeliveryAttemptItem{},
+		incidents: map[string]storage.IncidentItem{},
+	}
+}
+
+// Store uptime monitor result from single execution
+func (s *Storage) StoreUptimeResult(uptime *storage.UptimeResultItem) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.results = append(s.results, *uptime)
+	return nil
+}
+
+// List uptime monitor results of uptime monitor run between from and to timestamps (inclusive), ordered by run time
+func (s *Storage) ListUptimeResults(uptimeID string, from int64, to int64) ([]storage.UptimeResultItem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var results []storage.UptimeResultItem
+	for _, result := range s.results {
+		if result.UptimeID == uptimeID && result.RunAt >= from && result.RunAt <= to {
+			results = append(results, result)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RunAt < results[j].RunAt
+	})
+	return results, nil
+}
+
+// Get uptime status, nil is returned if it does not exist
+func (s *Storage) GetUptimeStatus(uptimeID string) (*storage.UptimeStatusItem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if status, ok := s.statuses[uptimeID]; ok {
+		return &status, nil
+	}
+	return nil, nil
+}
+
+// Record notification of kind sent at timestamp, nothing is recorded if uptime status does not exist
+func (s *Storage) RecordNotification(uptimeID string, kind string, at int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if status, ok := s.statuses[uptimeID]; ok {
+		status.RecordNotification(kind, at)
+		s.statuses[uptimeID] = status
+	}
+	return nil
+}
+
+// Count failed run, returns true if FAIL status should be announced
+func (s *Storage) UpdateUptimeStatus(uptimeID string, threshold int) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := s.status(uptimeID)
+	notify := status.Fail(threshold)
+	s.statuses[uptimeID] = status
+	return notify, nil
+}
+
+// Count degraded run, returns true if DEGRADED status should be announced
+func (s *Storage) DegradeUptimeStatus(uptimeID string, degradedThreshold int) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := s.status(uptimeID)
+	notify := status.Degrade(degradedThreshold)
+	s.statuses[uptimeID] = status
+	return notify, nil
+}
+
+// Count successful run, returns true if OK status should be announced
+// If uptime status does not exist, then there is nothing to recover from, OK state is stored and false is returned.
+func (s *Storage) RecoverUptimeStatus(uptimeID string, recoveryThreshold int) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := s.status(uptimeID)
+	notify := status.Recover(recoveryThreshold)
+	s.statuses[uptimeID] = status
+	return notify, nil
+}
+
+// Evaluate flap score of the last window runs, returns FLAPPING if uptime monitor started flapping,
+// announced status (OK, DEGRADED or FAIL) if it is stable again, empty status otherwise
+func (s *Storage) DetectFlapping(uptimeID string, window int, threshold int, stableThreshold int) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status, ok := s.statuses[uptimeID]
+	if !ok {
+		return "", nil
+	}
+	announce := status.DetectFlapping(window, threshold, stableThreshold)
+	s.statuses[uptimeID] = status
+	return announce, nil
+}
+
+// Mark uptime monitor UNREACHABLE_DUE_TO_PARENT because of failing parent, returns true if it has not been marked yet
+func (s *Storage) MarkUnreachable(uptimeID string, parent string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := s.status(uptimeID)
+	marked := status.MarkUnreachable(parent)
+	s.statuses[uptimeID] = status
+	return marked, nil
+}
+
+// Acknowledge announced status of uptime monitor, returns false if no status (FAIL or DEGRADED) has been announced
+func (s *Storage) AcknowledgeUptimeStatus(uptimeID string, by string, note string, at int64) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status, ok := s.statuses[uptimeID]
+	if !ok || !status.Acknowledge(by, note, at) {
+		return false, nil
+	}
+	s.statuses[uptimeID] = status
+	return true, nil
+}
+
+// Set maintenance window whose start has been announced, empty window ends it, returns true if it has changed
+func (s *Storage) SetMaintenance(uptimeID string, window string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status := s.status(uptimeID)
+	if !status.SetMaintenance(window) {
+		return false, nil
+	}
+	s.statuses[uptimeID] = status
+	return true, nil
+}
+
+// Store incident opened for uptime monitor and link it to uptime status as its open incident
+func (s *Storage) OpenIncident(incident *storage.IncidentItem) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.incidents[incident.ID] = *incident
+	status := s.status(incident.UptimeID)
+	status.IncidentID = incident.ID
+	s.statuses[incident.UptimeID] = status
+	return nil
+}
+
+// Append event to open incident of uptime monitor, returns false if uptime monitor has no open incident
+func (s *Storage) AddIncidentEvent(uptimeID string, event storage.IncidentEvent) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	incident, ok := s.openIncident(uptimeID)
+	if !ok {
+		return false, nil
+	}
+	// Timeline is copied, so incidents returned before are not modified
+	incident.Timeline = append(incident.Timeline[:len(incident.Timeline):len(incident.Timeline)], event)
+	s.incidents[incident.ID] = incident
+	return true, nil
+}
+
+// Close open incident of uptime monitor at timestamp, returns false if uptime monitor has no open incident
+func (s *Storage) CloseIncident(uptimeID string, at int64) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	incident, ok := s.openIncident(uptimeID)
+	if !ok {
+		return false, nil
+	}
+	incident.Timeline = incident.Timeline[:len(incident.Timeline):len(incident.Timeline)]
+	incident.Close(at)
+	s.incidents[incident.ID] = incident
+	status := s.statuses[uptimeID]
+	status.IncidentID = ""
+	s.statuses[uptimeID] = status
+	return true, nil
+}
+
+// Add note to incident, returns false if incident does not exist
+func (s *Storage) AddIncidentNote(id string, note storage.IncidentNote) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	incident, ok := s.incidents[id]
+	if !ok {
+		return false, nil
+	}
+	incident.Notes = append(incident.Notes[:len(incident.Notes):len(incident.Notes)], note)
+	s.incidents[id] = incident
+	return true, nil
+}
+
+// Get incident, nil is returned if it does not exist
+func (s *Storage) GetIncident(id string) (*storage.IncidentItem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if incident, ok := s.incidents[id]; ok {
+		return &incident, nil
+	}
+	return nil, nil
+}
+
+// List incidents of uptime monitor opened between from and to timestamps (inclusive), ordered by opening time
+func (s *Storage) ListIncidents(uptimeID string, from int64, to int64) ([]storage.IncidentItem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var incidents []storage.IncidentItem
+	for _, incident := range s.incidents {
+		if incident.UptimeID == uptimeID && incident.OpenedAt >= from && incident.OpenedAt <= to {
+			incidents = append(incidents, incident)
+		}
+	}
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].OpenedAt < incidents[j].OpenedAt
+	})
+	return incidents, nil
+}
+
+// Store silence, silence with the same ID is replaced
+func (s *Storage) PutSilence(silence *storage.SilenceItem) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := storage.SilencesKey(silence.UptimeID)
+	if s.silences[key] == nil {
+		s.silences[key] = map[string]storage.SilenceItem{}
+	}
+	s.silences[key][silence.ID] = *silence
+	return nil
+}
+
+// List silences of uptime monitor and silences without uptime monitor, which have not expired at timestamp, ordered by ID
+func (s *Storage) ListSilences(uptimeID string, at int64) ([]storage.SilenceItem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys := []string{storage.SilencesKey("")}
+	if uptimeID != "" {
+		keys = append(keys, storage.SilencesKey(uptimeID))
+	}
+	var silences []storage.SilenceItem
+	for _, key := range keys {
+		for _, silence := range s.silences[key] {
+			if silence.Until > at {
+				silences = append(silences, silence)
+			}
+		}
+	}
+	sort.Slice(silences, func(i, j int) bool {
+		return silences[i].ID < silences[j].ID
+	})
+	return silences, nil
+}
+
+// Remove silence of uptime monitor, returns true if it existed
+func (s *Storage) DeleteSilence(uptimeID string, id string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := storage.SilencesKey(uptimeID)
+	_, ok := s.silences[key][id]
+	delete(s.silences[key], id)
+	return ok, nil
+}
+
+// Remove silences of uptime monitor which expired at timestamp
+func (s *Storage) PruneSilences(uptimeID string, at int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := storage.SilencesKey(uptimeID)
+	for id, silence := range s.silences[key] {
+		if silence.Until <= at {
+			delete(s.silences[key], id)
+		}
+	}
+	if len(s.silences[key]) == 0 {
+		delete(s.silences, key)
+	}
+	return nil
+}
+
+// Record delivery attempts of notification of uptime monitor, only the last DELIVERY_HISTORY attempts are kept
+func (s *Storage) RecordDeliveryAttempts(uptimeID string, attempts []storage.DeliveryAttemptItem) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attempts[uptimeID] = storage.AppendDeliveryAttempts(s.attempts[uptimeID], attempts)
+	return nil
+}
+
+// List recorded delivery attempts of uptime monitor in order of their recording
+func (s *Storage) ListDeliveryAttempts(uptimeID string) ([]storage.DeliveryAttemptItem, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]storage.DeliveryAttemptItem(nil), s.attempts[uptimeID]...), nil
+}
+
+// Get open incident of uptime monitor linked to its uptime status, false is returned if there is none
+func (s *Storage) openIncident(uptimeID string) (storage.IncidentItem, bool) {
+	status, ok := s.statuses[uptimeID]
+	if !ok || status.IncidentID == "" {
+		return storage.IncidentItem{}, false
+	}
+	incident, ok := s.incidents[status.IncidentID]
+	return incident, ok
+}
+
+// Get uptime status, new status is returned if it does not exist yet
+func (s *Storage) status(uptimeID string) storage.UptimeStatusItem {
+	if status, ok := s.statuses[uptimeID]; ok {
+		return status
+	}
+	return storage.UptimeStatusItem{UptimeID: uptimeID}
+}
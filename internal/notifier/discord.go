@@ -0,0 +1,74 @@
+package notifier
+
+import (
+	"net/http"
+	"time"
+)
+
+// Represents notifier posting embeds to Discord webhook
+type Discord struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// Discord message with embeds
+type discordMessage struct {
+	Embeds []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []discordField `json:"fields"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// Creates notifier posting to Discord webhook URL
+func NewDiscord(webhookURL string, client *http.Client) *Discord {
+	return &Discord{webhookURL: webhookURL, client: client}
+}
+
+// Post uptime notification to Discord channel
+func (n *Discord) Notify(notification *Notification) error {
+	payload, err := discordPayload(notification)
+	if err != nil {
+		return err
+	}
+	return postJSON(n.client, n.webhookURL, payload)
+}
+
+// Renders uptime notification as Discord embed colored by status with text as description
+// Reason is rendered on its own line
+func discordPayload(notification *Notification) (*discordMessage, error) {
+	msg, err := renderMessage(notification)
+	if err != nil {
+		return nil, err
+	}
+	var embedFields []discordField
+	for _, field := range notification.Fields() {
+		embedFields = append(embedFields, discordField{Name: field.Name, Value: field.Value, Inline: field.Name != "Reason"})
+	}
+	embed := discordEmbed{
+		Title:       msg.Title,
+		Description: msg.Text,
+		Color:       color(notification.Status),
+		Fields:      embedFields,
+		Footer:      &discordFooter{Text: "Uptime ID: " + notification.UptimeID},
+	}
+	if !notification.Time.IsZero() {
+		embed.Timestamp = notification.Time.UTC().Format(time.RFC3339)
+	}
+	return &discordMessage{Embeds: []discordEmbed{embed}}, nil
+}
@@ -0,0 +1,266 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"monitor-uptime/internal/templates"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// SMTP authentication methods
+const (
+	SMTP_AUTH_PLAIN = "plain"
+	SMTP_AUTH_LOGIN = "login"
+)
+
+// Default timeout of SMTP session, from connecting to server until message is sent
+const SMTP_TIMEOUT = 10 * time.Second
+
+// Represents templates of email channel overriding templates of uptime monitor, empty templates are ignored
+type EmailTemplates struct {
+	Subject string `json:"subject,omitempty"` // text/template of subject
+	Text    string `json:"text,omitempty"`    // text/template of plain-text body
+	HTML    string `json:"html,omitempty"`    // html/template of HTML body
+}
+
+// Represents SMTP server and credentials used to send emails
+type SMTPConfig struct {
+	Addr       string // Address of SMTP server, host:port
+	Username   string // Username, authentication is skipped if empty
+	Password   string
+	AuthMethod string // Either plain (default) or login
+	StartTLS   bool   // Whether STARTTLS is required, otherwise it is used only if server supports it
+	TLSConfig  *tls.Config
+	Timeout    time.Duration // Timeout of whole SMTP session, SMTP_TIMEOUT if 0
+}
+
+// Represents notifier sending multipart HTML and plain-text emails through SMTP server
+type Email struct {
+	config    SMTPConfig
+	from      string
+	to        []string
+	templates EmailTemplates
+}
+
+// Creates notifier sending emails from sender to recipients, templates may override default ones
+func NewEmail(config SMTPConfig, from string, to []string, templates *EmailTemplates) *Email {
+	email := &Email{config: config, from: from, to: to}
+	if templates != nil {
+		email.templates = *templates
+	}
+	return email
+}
+
+// Send uptime notification as email to all recipients
+func (n *Email) Notify(notification *Notification) error {
+	message, err := n.message(notification)
+	if err != nil {
+		return err
+	}
+	return n.send(message)
+}
+
+// Renders uptime notification as MIME message with multipart/alternative plain-text and HTML body
+func (n *Email) message(notification *Notification) ([]byte, error) {
+	notification, err := n.withTemplates(notification)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := render(notification, templates.EMAIL_SUBJECT)
+	if err != nil {
+		return nil, err
+	}
+	text, err := render(notification, templates.EMAIL_TEXT)
+	if err != nil {
+		return nil, err
+	}
+	html, err := render(notification, templates.EMAIL_HTML)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(partWriter)
+		if _, err = encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", n.from},
+		{"To", strings.Join(n.to, ", ")},
+		{"Subject", mime.QEncoding.Encode("UTF-8", subject)},
+		{"Date", notificationTime(notification).Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + writer.Boundary()},
+	} {
+		message.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// Returns copy of notification with templates of uptime monitor overridden by templates of email channel
+func (n *Email) withTemplates(notification *Notification) (*Notification, error) {
+	if n.templates == (EmailTemplates{}) {
+		return notification, nil
+	}
+	set := notification.Templates
+	if set == nil {
+		set = templates.Default()
+	}
+	set, err := set.With(map[string]string{
+		templates.EMAIL_SUBJECT: n.templates.Subject,
+		templates.EMAIL_TEXT:    n.templates.Text,
+		templates.EMAIL_HTML:    n.templates.HTML,
+	})
+	if err != nil {
+		return nil, err
+	}
+	copied := *notification
+	copied.Templates = set
+	return &copied, nil
+}
+
+// Sends message through SMTP server, STARTTLS is used whenever server supports it
+// Connection has deadline of session timeout, so unresponsive server cannot block sending
+func (n *Email) send(message []byte) error {
+	host, _, err := net.SplitHostPort(n.config.Addr)
+	if err != nil {
+		return err
+	}
+	timeout := n.config.Timeout
+	if timeout <= 0 {
+		timeout = SMTP_TIMEOUT
+	}
+	conn, err := net.DialTimeout("tcp", n.config.Addr, timeout)
+	if err != nil {
+		return err
+	}
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		tlsConfig := n.config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: host}
+		}
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	} else if n.config.StartTLS {
+		return errors.New("SMTP server " + n.config.Addr + " does not support STARTTLS")
+	}
+
+	if n.config.Username != "" {
+		var auth smtp.Auth
+		if n.config.AuthMethod == SMTP_AUTH_LOGIN {
+			auth = &loginAuth{host: host, username: n.config.Username, password: n.config.Password}
+		} else {
+			auth = smtp.PlainAuth("", n.config.Username, n.config.Password, host)
+		}
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(n.from); err != nil {
+		return err
+	}
+	for _, recipient := range n.to {
+		if err = client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Represents SMTP AUTH LOGIN mechanism, which is not provided by net/smtp
+// Same as PLAIN mechanism, credentials are sent only over TLS or to localhost
+type loginAuth struct {
+	host     string
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, errors.New("unexpected server challenge: " + string(fromServer))
+}
+
+// Returns true if host is loopback address or localhost
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// Returns time of notification, current time if it is not set
+func notificationTime(notification *Notification) time.Time {
+	if notification.Time.IsZero() {
+		return time.Now()
+	}
+	return notification.Time
+}
@@ -0,0 +1,217 @@
+package notifier
+
+import (
+	"bufio"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"monitor-uptime/internal/storage"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Represents email received by fake SMTP server
+type receivedEmail struct {
+	auth []string // Decoded authentication exchange
+	from string
+	to   []string
+	data string
+}
+
+// Starts fake SMTP server accepting single email, received email is sent to returned channel
+// Server supports AUTH PLAIN and LOGIN, but does not support STARTTLS
+func newSMTPServer(t *testing.T) (string, chan receivedEmail) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "Fake SMTP server was expected to listen")
+	received := make(chan receivedEmail, 1)
+
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		readLine := func() string {
+			line, _ := reader.ReadString('\n')
+			return strings.TrimRight(line, "\r\n")
+		}
+		decode := func(value string) string {
+			decoded, _ := base64.StdEncoding.DecodeString(value)
+			return string(decoded)
+		}
+
+		var email receivedEmail
+		reply("220 localhost fake SMTP")
+		for {
+			line := readLine()
+			command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch {
+			case command == "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN LOGIN")
+			case strings.HasPrefix(strings.ToUpper(line), "AUTH PLAIN"):
+				email.auth = append(email.auth, decode(strings.Fields(line)[2]))
+				reply("235 Authentication successful")
+			case strings.HasPrefix(strings.ToUpper(line), "AUTH LOGIN"):
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+				email.auth = append(email.auth, decode(readLine()))
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+				email.auth = append(email.auth, decode(readLine()))
+				reply("235 Authentication successful")
+			case command == "MAIL":
+				email.from = line
+				reply("250 OK")
+			case command == "RCPT":
+				email.to = append(email.to, line)
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for line = readLine(); line != "."; line = readLine() {
+					data.WriteString(line + "\r\n")
+				}
+				email.data = data.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				received <- email
+				return
+			default:
+				reply("500 Unknown command")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+// Returns decoded subject and parts of received email keyed by content type
+func parseEmail(t *testing.T, data string) (string, map[string]string) {
+	message, err := mail.ReadMessage(strings.NewReader(data))
+	assert.Nil(t, err, "Email was expected to be valid message")
+	subject, _ := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	_, params, _ := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	parts := map[string]string{}
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for part, err := reader.NextRawPart(); err == nil; part, err = reader.NextRawPart() {
+		content, _ := io.ReadAll(quotedprintable.NewReader(part))
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[mediaType] = string(content)
+	}
+	return subject, parts
+}
+
+// Given SMTP server supporting AUTH PLAIN
+// When FAIL notification with recent runs is sent
+// Then multipart email with plain-text and HTML body is sent to all recipients
+//      and both bodies contain transition, reason and recent runs
+func TestEmailNotify(t *testing.T) {
+	// Given
+	addr, received := newSMTPServer(t)
+	email := NewEmail(SMTPConfig{Addr: addr, Username: "user", Password: "secret"},
+		"uptime@example.com", []string{"ops@example.com", "dev@example.com"}, nil)
+	notification := failNotification()
+	notification.Reason = "body <b>broken</b>"
+	notification.RecentRuns = []storage.UptimeResultItem{{RunAt: 1600000000, StatusCode: 503, TTFB: 120, Reason: "unexpected status code 503"}}
+
+	// When
+	err := email.Notify(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	res := <-received
+	assert.Equal(t, []string{"\x00user\x00secret"}, res.auth, "Unexpected PLAIN authentication")
+	assert.Len(t, res.to, 2, "Unexpected number of recipients")
+	subject, parts := parseEmail(t, res.data)
+	assert.Equal(t, "[FAIL] https://example.com", subject, "Unexpected subject")
+	assert.Contains(t, parts["text/plain"], "changed status OK → FAIL", "Plain-text body was expected to contain transition")
+	assert.Contains(t, parts["text/plain"], "- 2020-09-13 12:26:40 UTC status code 503", "Plain-text body was expected to contain recent run")
+	assert.Contains(t, parts["text/html"], "body &lt;b&gt;broken&lt;/b&gt;", "HTML body was expected to contain escaped reason")
+	assert.Contains(t, parts["text/html"], "<h3>Recent runs</h3>", "HTML body was expected to contain recent runs")
+}
+
+// Given SMTP server supporting AUTH LOGIN
+//       and overridden subject template
+// When notification is sent
+// Then credentials are sent by LOGIN exchange
+//      and subject is rendered from overridden template
+func TestEmailNotifyLoginAuthAndTemplate(t *testing.T) {
+	// Given
+	addr, received := newSMTPServer(t)
+	email := NewEmail(SMTPConfig{Addr: addr, Username: "user", Password: "secret", AuthMethod: SMTP_AUTH_LOGIN},
+		"uptime@example.com", []string{"ops@example.com"}, &EmailTemplates{Subject: "{{.UptimeID}} is {{.Status}}"})
+
+	// When
+	err := email.Notify(failNotification())
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	res := <-received
+	assert.Equal(t, []string{"user", "secret"}, res.auth, "Unexpected LOGIN authentication")
+	subject, _ := parseEmail(t, res.data)
+	assert.Equal(t, "uptime-1 is FAIL", subject, "Unexpected subject")
+}
+
+// Given SMTP server without STARTTLS support
+// When notification is sent by channel requiring STARTTLS
+// Then error is returned
+func TestEmailNotifyRequiredStartTLS(t *testing.T) {
+	// Given
+	addr, _ := newSMTPServer(t)
+	email := NewEmail(SMTPConfig{Addr: addr, StartTLS: true}, "uptime@example.com", []string{"ops@example.com"}, nil)
+
+	// When
+	err := email.Notify(failNotification())
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given SMTP server which accepts connection but never responds
+// When notification is sent
+// Then error is returned once session times out
+func TestEmailNotifyTimeout(t *testing.T) {
+	// Given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "Fake SMTP server was expected to listen")
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	email := NewEmail(SMTPConfig{Addr: listener.Addr().String(), Timeout: 100 * time.Millisecond}, "uptime@example.com", []string{"ops@example.com"}, nil)
+
+	// When
+	start := time.Now()
+	err = email.Notify(failNotification())
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+	assert.True(t, time.Since(start) < time.Second, "Sending was expected to time out")
+}
+
+// Given invalid overridden template
+// When notification is sent
+// Then error is returned
+func TestEmailNotifyInvalidTemplate(t *testing.T) {
+	// Given
+	email := NewEmail(SMTPConfig{Addr: "127.0.0.1:0"}, "uptime@example.com", []string{"ops@example.com"},
+		&EmailTemplates{Text: "{{.Missing"})
+
+	// When
+	err := email.Notify(failNotification())
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
@@ -0,0 +1,281 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/storage"
+	"monitor-uptime/internal/templates"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of notification channels
+const (
+	CHANNEL_SNS       = "sns"
+	CHANNEL_SLACK     = "slack"
+	CHANNEL_TEAMS     = "teams"
+	CHANNEL_DISCORD   = "discord"
+	CHANNEL_PAGERDUTY = "pagerduty"
+	CHANNEL_OPSGENIE  = "opsgenie"
+	CHANNEL_WEBHOOK   = "webhook"
+	CHANNEL_EMAIL     = "email"
+)
+
+// Actions of incident channels (PagerDuty and Opsgenie)
+const (
+	INCIDENT_TRIGGER = "trigger" // Incident is opened, or updated if it is already open
+	INCIDENT_RESOLVE = "resolve" // Incident is resolved
+)
+
+// Represents notification channel configured per uptime monitor
+type Channel struct {
+	Type           string          `json:"type"`                     // Type of channel, either sns, slack, teams, discord, pagerduty, opsgenie, webhook or email
+	WebhookURL     string          `json:"webhookUrl,omitempty"`     // Webhook URL of Slack, Teams, Discord or generic webhook channel, may be secret reference (env:, secretsmanager:, ssm:)
+	Secret         string          `json:"secret,omitempty"`         // Shared secret signing payloads of generic webhook, may be secret reference
+	MaxAttempts    int             `json:"maxAttempts,omitempty"`    // Maximal number of delivery attempts of generic webhook (default: 3)
+	SMTPAddr       string          `json:"smtpAddr,omitempty"`       // Address (host:port) of SMTP server of email channel, SMTP_ADDR environment variable is used if empty
+	Username       string          `json:"username,omitempty"`       // SMTP username, SMTP_USERNAME environment variable is used if empty, may be secret reference
+	Password       string          `json:"password,omitempty"`       // SMTP password, SMTP_PASSWORD environment variable is used if empty, may be secret reference
+	AuthMethod     string          `json:"authMethod,omitempty"`     // SMTP authentication method, either plain (default) or login
+	From           string          `json:"from,omitempty"`           // Sender of emails, SMTP_FROM environment variable is used if empty
+	To             []string        `json:"to,omitempty"`             // Recipients of emails
+	Templates      *EmailTemplates `json:"templates,omitempty"`      // Templates overriding default email subject and bodies
+	TopicARN       string          `json:"topicArn,omitempty"`       // ARN of SNS topic, SNS_TOPIC environment variable is used if empty
+	IntegrationKey string          `json:"integrationKey,omitempty"` // Routing key of PagerDuty Events API v2 integration, may be secret reference
+	APIKey         string          `json:"apiKey,omitempty"`         // Opsgenie API integration key, may be secret reference
+	APIURL         string          `json:"apiUrl,omitempty"`         // Base URL of PagerDuty or Opsgenie API, public API is used if empty (e.g. https://api.eu.opsgenie.com for EU)
+}
+
+// Returns identity of channel, i.e. its type and destination
+// Channels with the same identity deliver notification to the same destination, regardless of their other settings
+func (c *Channel) Key() string {
+	return strings.Join([]string{
+		c.Type, c.WebhookURL, c.TopicARN, c.IntegrationKey, c.APIKey, c.APIURL, c.SMTPAddr, strings.Join(c.To, ","),
+	}, "|")
+}
+
+// Represents transition of uptime status announced to notification channels
+type Notification struct {
+	UptimeID       string                     `json:"uptimeId"`
+	Host           string                     `json:"host"`
+	Tags           map[string]string          `json:"tags,omitempty"` // Tags of uptime monitor, e.g. team, env or severity
+	PreviousStatus sns.UptimeStatus           `json:"previousStatus"` // Status announced before this transition, OK if none has been announced
+	Status         sns.UptimeStatus           `json:"status"`
+	Reason         string                     `json:"reason,omitempty"`       // Reason of failure or degradation, empty for OK status
+	ErrorClass     string                     `json:"errorClass,omitempty"`   // Class of probe failure (e.g. dns_error, timeout), empty if host has been probed
+	StatusCode     int                        `json:"statusCode,omitempty"`   // Status code of last run, 0 if host has not responded
+	TTFB           int64                      `json:"ttfb,omitempty"`         // Time To First Byte of last run in milliseconds
+	DNSLookup      int64                      `json:"dnslookup,omitempty"`    // Duration of DNS lookup of last run in milliseconds
+	TCPConnect     int64                      `json:"tcpConnect,omitempty"`   // Duration of TCP connect of last run in milliseconds
+	TLSHandshake   int64                      `json:"tlshandshake,omitempty"` // Duration of TLS handshake of last run in milliseconds
+	Total          int64                      `json:"total,omitempty"`        // Duration of last run in milliseconds
+	FailCounter    int                        `json:"failCounter,omitempty"`  // Number of consecutive failed runs
+	Time           time.Time                  `json:"time"`
+	OutageStart    time.Time                  `json:"outageStart,omitempty"` // Time of the first failed run of outage, zero if uptime monitor has not failed
+	Reminder       bool                       `json:"reminder,omitempty"`    // Whether notification reminds persisting status instead of announcing transition
+	Escalated      bool                       `json:"escalated,omitempty"`   // Whether outage has been escalated to secondary channels
+	Maintenance    string                     `json:"maintenance,omitempty"` // Maintenance window whose start (MAINTENANCE status) or end is announced
+	FlapScore      int                        `json:"flapScore,omitempty"`   // Percentage of outcome changes among recent runs, set when flapping starts
+	Parent         string                     `json:"parent,omitempty"`      // Failing parent uptime monitor into whose incident notification is folded
+	RecentRuns     []storage.UptimeResultItem `json:"recentRuns,omitempty"`  // Last runs of uptime monitor ordered by run time, provided to channels rendering context (e.g. email)
+	Templates      *templates.Set             `json:"-"`                     // Templates of uptime monitor rendering notification, default templates are used if nil
+}
+
+// Represents channel to which transitions of uptime status are announced
+type Notifier interface {
+	// Announce transition of uptime status, returns error if notification cannot be delivered
+	Notify(notification *Notification) error
+}
+
+// Represents single labelled value of notification rendered by channels
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Represents texts of notification rendered from templates
+type message struct {
+	Title   string
+	Summary string
+	Text    string
+}
+
+// Renders title, summary and text of notification using its templates
+// Returns error if any template cannot be rendered
+func renderMessage(notification *Notification) (*message, error) {
+	var msg message
+	for name, out := range map[string]*string{
+		templates.TITLE:   &msg.Title,
+		templates.SUMMARY: &msg.Summary,
+		templates.TEXT:    &msg.Text,
+	} {
+		var err error
+		if *out, err = render(notification, name); err != nil {
+			return nil, err
+		}
+	}
+	return &msg, nil
+}
+
+// Renders template of notification by its name, default template is used unless it is overridden
+func render(notification *Notification, name string) (string, error) {
+	set := notification.Templates
+	if set == nil {
+		set = templates.Default()
+	}
+	text, err := set.Render(name, notification)
+	return strings.TrimSpace(text), err
+}
+
+// Returns status transition, e.g. "OK → FAIL"
+func (n *Notification) Transition() string {
+	if n.PreviousStatus == "" {
+		return string(n.Status)
+	}
+	return string(n.PreviousStatus) + " → " + string(n.Status)
+}
+
+// Returns key of incident to which notification belongs, i.e. uptime ID or ID of failing parent uptime monitor
+// Notification of uptime monitor unreachable due to its parent is folded into parent's incident
+func (n *Notification) IncidentKey() string {
+	if n.Parent != "" {
+		return n.Parent
+	}
+	return n.UptimeID
+}
+
+// Returns action of incident channels (PagerDuty and Opsgenie) on notification, empty if nothing should be sent
+// Incident is triggered on FAIL, including uptime monitor unreachable due to its failing parent, and it is resolved
+// on OK or on DEGRADED following FAIL. DEGRADED and FLAPPING do not trigger incidents, maintenance windows are ignored.
+func incidentAction(notification *Notification) string {
+	switch {
+	case notification.Maintenance != "":
+		return ""
+	case notification.Status == sns.STATUS_FAIL, notification.Status == sns.STATUS_UNREACHABLE:
+		return INCIDENT_TRIGGER
+	case notification.Status == sns.STATUS_OK,
+		notification.Status == sns.STATUS_DEGRADED && notification.PreviousStatus == sns.STATUS_FAIL:
+		return INCIDENT_RESOLVE
+	}
+	return ""
+}
+
+// Returns measured latency, e.g. "TTFB 120 ms, total 350 ms", empty if nothing has been measured
+func (n *Notification) Latency() string {
+	var parts []string
+	if n.TTFB > 0 {
+		parts = append(parts, "TTFB "+templates.Latency(n.TTFB))
+	}
+	if n.Total > 0 {
+		parts = append(parts, "total "+templates.Latency(n.Total))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Returns duration of outage which ended by recovery or which persists (for reminders),
+// 0 if notification neither announces recovery from outage nor reminds it
+func (n *Notification) OutageDuration() time.Duration {
+	if (n.Status != sns.STATUS_OK && !n.Reminder) || n.OutageStart.IsZero() || n.Time.IsZero() {
+		return 0
+	}
+	return n.Time.Sub(n.OutageStart)
+}
+
+// Returns fields rendered by all channels, fields without value are omitted
+func (n *Notification) Fields() []Field {
+	fields := []Field{
+		{Name: "Status", Value: n.Transition()},
+		{Name: "Host", Value: n.Host},
+	}
+	if n.StatusCode != 0 {
+		fields = append(fields, Field{Name: "Status code", Value: strconv.Itoa(n.StatusCode)})
+	}
+	if value := n.Latency(); value != "" {
+		fields = append(fields, Field{Name: "Latency", Value: value})
+	}
+	if duration := n.OutageDuration(); duration > 0 {
+		fields = append(fields, Field{Name: "Outage duration", Value: templates.Duration(duration)})
+	}
+	if n.Reason != "" {
+		fields = append(fields, Field{Name: "Reason", Value: n.Reason})
+	}
+	return fields
+}
+
+// Returns details of last run, e.g. status code, TTFB and error, details without value are omitted
+func details(notification *Notification) map[string]string {
+	details := map[string]string{"host": notification.Host}
+	if notification.StatusCode != 0 {
+		details["statusCode"] = strconv.Itoa(notification.StatusCode)
+	}
+	if notification.TTFB > 0 {
+		details["ttfb"] = strconv.FormatInt(notification.TTFB, 10) + " ms"
+	}
+	if notification.Total > 0 {
+		details["total"] = strconv.FormatInt(notification.Total, 10) + " ms"
+	}
+	if notification.Reason != "" {
+		details["reason"] = notification.Reason
+	}
+	if notification.ErrorClass != "" {
+		details["error"] = notification.ErrorClass
+	}
+	return details
+}
+
+// Returns true if host is URL which can be linked from notification
+func isLinkable(host string) bool {
+	return strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://")
+}
+
+// Returns RGB color of status, green for OK, orange for DEGRADED and red for FAIL
+func color(status sns.UptimeStatus) int {
+	switch status {
+	case sns.STATUS_OK:
+		return 0x2eb67d
+	case sns.STATUS_DEGRADED:
+		return 0xecb22e
+	}
+	return 0xe01e5a
+}
+
+// Post payload encoded as JSON to webhook URL
+// Returns error if payload cannot be delivered or webhook does not respond with 2xx status code
+func postJSON(client *http.Client, url string, payload interface{}) error {
+	req, err := newJSONRequest(url, payload)
+	if err != nil {
+		return err
+	}
+	return send(client, req)
+}
+
+// Creates POST request with payload encoded as JSON
+func newJSONRequest(url string, payload interface{}) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// Send request, returns error if request cannot be delivered or it is not responded with 2xx status code
+func send(client *http.Client, req *http.Request) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s responded with status code %d", req.URL.Host, res.StatusCode)
+	}
+	return nil
+}
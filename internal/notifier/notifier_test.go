@@ -0,0 +1,319 @@
+package notifier
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	uptimeSNS "monitor-uptime/internal/sns"
+	"monitor-uptime/internal/templates"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// SNS client mock
+type mockSNSClient struct {
+	mock.Mock
+	snsiface.SNSAPI
+}
+
+func (m *mockSNSClient) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*sns.PublishOutput), args.Error(1)
+}
+
+// Represents request received by webhook receiver
+type receivedRequest struct {
+	path    string
+	header  http.Header
+	payload map[string]interface{}
+}
+
+// Starts webhook receiver responding with status code, received request bodies are sent to returned channel
+func newWebhookReceiver(statusCode int) (*httptest.Server, chan map[string]interface{}) {
+	server, requests := newReceiver(statusCode)
+	received := make(chan map[string]interface{}, 1)
+	go func() {
+		for req := range requests {
+			received <- req.payload
+		}
+	}()
+	return server, received
+}
+
+// Starts receiver responding with status code, received requests are sent to returned channel
+func newReceiver(statusCode int) (*httptest.Server, chan receivedRequest) {
+	received := make(chan receivedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		_ = json.Unmarshal(body, &payload)
+		received <- receivedRequest{path: r.URL.RequestURI(), header: r.Header, payload: payload}
+		w.WriteHeader(statusCode)
+	}))
+	return server, received
+}
+
+func failNotification() *Notification {
+	return &Notification{
+		UptimeID:       "uptime-1",
+		Host:           "https://example.com",
+		PreviousStatus: uptimeSNS.STATUS_OK,
+		Status:         uptimeSNS.STATUS_FAIL,
+		Reason:         "unexpected status code 503",
+		StatusCode:     503,
+		TTFB:           120,
+		Total:          350,
+		Time:           time.Unix(1600000000, 0),
+	}
+}
+
+// Given FAIL notification
+// When notification fields are rendered
+// Then status transition, host, status code, latency and reason are included
+func TestFields(t *testing.T) {
+	// When
+	res := failNotification().Fields()
+
+	// Then
+	assert.Equal(t, []Field{
+		{Name: "Status", Value: "OK → FAIL"},
+		{Name: "Host", Value: "https://example.com"},
+		{Name: "Status code", Value: "503"},
+		{Name: "Latency", Value: "TTFB 120 ms, total 350 ms"},
+		{Name: "Reason", Value: "unexpected status code 503"},
+	}, res, "Unexpected fields")
+}
+
+// Given reminder of escalated outage
+// When notification message is rendered
+// Then text reminds persisting status with its duration and escalation
+func TestRenderMessageReminder(t *testing.T) {
+	// Given
+	notification := failNotification()
+	notification.PreviousStatus = uptimeSNS.STATUS_FAIL
+	notification.OutageStart = notification.Time.Add(-45 * time.Minute)
+	notification.Reminder = true
+	notification.Escalated = true
+
+	// When
+	res, err := renderMessage(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "Uptime monitor uptime-1 is still FAIL after 45m. Outage has been escalated as it has not been resolved.",
+		res.Text, "Unexpected text")
+}
+
+// Given announcements of maintenance window start and end
+// When notification messages are rendered
+// Then texts describe entered and left maintenance window
+func TestRenderMessageMaintenance(t *testing.T) {
+	// Given
+	start := failNotification()
+	start.PreviousStatus, start.Status, start.Maintenance = uptimeSNS.STATUS_OK, uptimeSNS.STATUS_MAINTENANCE, "deploy"
+	end := failNotification()
+	end.PreviousStatus, end.Status, end.Maintenance = uptimeSNS.STATUS_MAINTENANCE, uptimeSNS.STATUS_OK, "deploy"
+
+	// When
+	startRes, startErr := renderMessage(start)
+	endRes, endErr := renderMessage(end)
+
+	// Then
+	assert.Nil(t, startErr, "Error was not expected to be returned")
+	assert.Nil(t, endErr, "Error was not expected to be returned")
+	assert.Equal(t, "Uptime monitor uptime-1 entered maintenance window deploy.", startRes.Text, "Unexpected start text")
+	assert.Equal(t, "Uptime monitor uptime-1 left maintenance window deploy.", endRes.Text, "Unexpected end text")
+}
+
+// Given notification of uptime monitor unreachable due to its parent
+// When notification message is rendered
+// Then text refers to parent
+func TestRenderMessageUnreachable(t *testing.T) {
+	// Given
+	notification := failNotification()
+	notification.Status, notification.Parent = uptimeSNS.STATUS_UNREACHABLE, "gateway"
+
+	// When
+	res, err := renderMessage(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "Uptime monitor uptime-1 is unreachable as its parent gateway is down.", res.Text, "Unexpected text")
+}
+
+// Given notification of uptime monitor which started flapping
+// When notification message is rendered
+// Then text describes flap score
+func TestRenderMessageFlapping(t *testing.T) {
+	// Given
+	notification := failNotification()
+	notification.PreviousStatus, notification.Status, notification.FlapScore = uptimeSNS.STATUS_OK, uptimeSNS.STATUS_FLAPPING, 75
+
+	// When
+	res, err := renderMessage(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "Uptime monitor uptime-1 is flapping (75% of recent runs changed status), "+
+		"transitions are not announced until it is stable.", res.Text, "Unexpected text")
+}
+
+// Given Slack webhook receiver
+// When FAIL notification is sent
+// Then Block Kit message with header, text and fields is posted
+func TestSlackNotify(t *testing.T) {
+	// Given
+	server, received := newWebhookReceiver(http.StatusOK)
+	defer server.Close()
+
+	// When
+	err := NewSlack(server.URL, server.Client()).Notify(failNotification())
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	payload := <-received
+	blocks := payload["blocks"].([]interface{})
+	header := blocks[0].(map[string]interface{})["text"].(map[string]interface{})
+	assert.Equal(t, "FAIL: https://example.com", header["text"], "Unexpected header")
+	text := blocks[1].(map[string]interface{})["text"].(map[string]interface{})
+	assert.Equal(t, "Uptime monitor uptime-1 changed status OK → FAIL.", text["text"], "Unexpected text")
+	sectionFields := blocks[2].(map[string]interface{})["fields"].([]interface{})
+	assert.Equal(t, "*Status*\nOK → FAIL", sectionFields[0].(map[string]interface{})["text"], "Unexpected status field")
+}
+
+// Given Teams webhook receiver
+// When FAIL notification is sent
+// Then Adaptive Card with attention title and fact set is posted
+func TestTeamsNotify(t *testing.T) {
+	// Given
+	server, received := newWebhookReceiver(http.StatusOK)
+	defer server.Close()
+
+	// When
+	err := NewTeams(server.URL, server.Client()).Notify(failNotification())
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	payload := <-received
+	attachment := payload["attachments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", attachment["contentType"], "Unexpected content type")
+	body := attachment["content"].(map[string]interface{})["body"].([]interface{})
+	assert.Equal(t, "Attention", body[0].(map[string]interface{})["color"], "Unexpected title color")
+	facts := body[2].(map[string]interface{})["facts"].([]interface{})
+	assert.Len(t, facts, 6, "Unexpected number of facts")
+}
+
+// Given Discord webhook receiver
+// When FAIL notification is sent
+// Then red embed with fields and timestamp is posted
+func TestDiscordNotify(t *testing.T) {
+	// Given
+	server, received := newWebhookReceiver(http.StatusNoContent)
+	defer server.Close()
+
+	// When
+	err := NewDiscord(server.URL, server.Client()).Notify(failNotification())
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	embed := (<-received)["embeds"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(0xe01e5a), embed["color"], "Unexpected embed color")
+	assert.Equal(t, "2020-09-13T12:26:40Z", embed["timestamp"], "Unexpected timestamp")
+}
+
+// Given webhook receiver responding with server error
+// When notification is sent
+// Then error is returned
+func TestWebhookNotifyFailure(t *testing.T) {
+	// Given
+	server, _ := newWebhookReceiver(http.StatusInternalServerError)
+	defer server.Close()
+
+	// When
+	err := NewSlack(server.URL, server.Client()).Notify(failNotification())
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given SNS topic
+// When notification is sent
+// Then uptime notification is published to SNS topic
+func TestSNSNotify(t *testing.T) {
+	// Given
+	snsClient := &mockSNSClient{}
+	snsClient.On("Publish", mock.MatchedBy(func(input *sns.PublishInput) bool {
+		return *input.TopicArn == "topic-ARN-1" && *input.MessageAttributes["uptimeId"].StringValue == "uptime-1"
+	})).Return(&sns.PublishOutput{}, nil)
+
+	// When
+	err := NewSNS("topic-ARN-1", snsClient).Notify(failNotification())
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	snsClient.AssertExpectations(t)
+}
+
+// Given OK notification announcing recovery from outage
+// When notification is converted into SNS uptime notification
+// Then transition, outage start and outage duration in seconds are included
+func TestSNSNotificationOfRecovery(t *testing.T) {
+	// Given
+	notification := failNotification()
+	notification.PreviousStatus, notification.Status = uptimeSNS.STATUS_FAIL, uptimeSNS.STATUS_OK
+	notification.Reason = ""
+	notification.OutageStart = notification.Time.Add(-5 * time.Minute)
+
+	// When
+	res, err := snsNotificationOf(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "OK: https://example.com", res.Message, "Unexpected message")
+	assert.Equal(t, uptimeSNS.UptimeStatus(uptimeSNS.STATUS_FAIL), res.PreviousStatus, "Unexpected previous status")
+	assert.Equal(t, "https://example.com", res.Host, "Unexpected host")
+	assert.Equal(t, int64(1600000000-300), res.OutageStart, "Unexpected outage start")
+	assert.Equal(t, int64(300), res.OutageDuration, "Unexpected outage duration")
+}
+
+// Given Discord webhook receiver
+//       and uptime monitor overriding title template
+// When notification is sent
+// Then embed title is rendered from overridden template
+//      and description is rendered from default text template
+func TestNotifyWithTemplates(t *testing.T) {
+	// Given
+	server, received := newWebhookReceiver(http.StatusNoContent)
+	defer server.Close()
+	notification := failNotification()
+	notification.Templates, _ = templates.New(map[string]string{templates.TITLE: "{{upper .Status}} {{.UptimeID}} ({{latency .Total}})"})
+
+	// When
+	err := NewDiscord(server.URL, server.Client()).Notify(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	embed := (<-received)["embeds"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "FAIL uptime-1 (350 ms)", embed["title"], "Unexpected title")
+	assert.Equal(t, "Uptime monitor uptime-1 changed status OK → FAIL.", embed["description"], "Unexpected description")
+}
+
+// Given template failing to render
+// When notification is sent
+// Then error is returned
+func TestNotifyWithBrokenTemplate(t *testing.T) {
+	// Given
+	notification := failNotification()
+	notification.Templates, _ = templates.New(map[string]string{templates.TITLE: "{{.Missing}}"})
+
+	// When
+	err := NewSlack("http://127.0.0.1:0", http.DefaultClient).Notify(notification)
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
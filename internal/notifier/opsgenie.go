@@ -0,0 +1,97 @@
+package notifier
+
+import (
+	"monitor-uptime/internal/sns"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Public Opsgenie API URL
+const OPSGENIE_API_URL = "https://api.opsgenie.com"
+
+// Represents notifier creating and closing Opsgenie alerts through Alert API
+// Uptime ID serves as alert alias, so repeated transitions of uptime monitor are deduplicated into single alert
+type Opsgenie struct {
+	apiKey string
+	apiURL string
+	client *http.Client
+}
+
+// Opsgenie alert created on FAIL status
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority"`
+	Source      string            `json:"source"`
+	Entity      string            `json:"entity,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// Opsgenie request closing alert on recovery
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Creates notifier using Opsgenie API integration key
+// If apiURL is empty, then public Opsgenie API is used
+func NewOpsgenie(apiKey string, apiURL string, client *http.Client) *Opsgenie {
+	if apiURL == "" {
+		apiURL = OPSGENIE_API_URL
+	}
+	return &Opsgenie{apiKey: apiKey, apiURL: strings.TrimSuffix(apiURL, "/"), client: client}
+}
+
+// Create Opsgenie alert on FAIL status, close it on recovery
+// DEGRADED, FLAPPING and announcements of maintenance windows are not sent, as they do not open nor close alerts
+func (n *Opsgenie) Notify(notification *Notification) error {
+	action := incidentAction(notification)
+	if action == "" {
+		return nil
+	}
+	msg, err := renderMessage(notification)
+	if err != nil {
+		return err
+	}
+	var alertURL string
+	var payload interface{}
+	if action == INCIDENT_RESOLVE {
+		alertURL = n.apiURL + "/v2/alerts/" + url.PathEscape(notification.UptimeID) + "/close?identifierType=alias"
+		payload = &opsgenieClose{Source: "uptime-monitor", Note: msg.Summary}
+	} else {
+		alertURL = n.apiURL + "/v2/alerts"
+		payload = opsgenieAlertOf(notification, msg)
+	}
+
+	req, err := newJSONRequest(alertURL, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "GenieKey "+n.apiKey)
+	return send(n.client, req)
+}
+
+// Renders uptime notification as Opsgenie alert with mapped priority and details of last run
+// Summary of message is used as alert message and text as its description. Alert of uptime monitor unreachable
+// due to its parent has parent's alias, so it is deduplicated into parent's alert.
+func opsgenieAlertOf(notification *Notification, msg *message) *opsgenieAlert {
+	return &opsgenieAlert{
+		Message:     msg.Summary,
+		Alias:       notification.IncidentKey(),
+		Description: msg.Text,
+		Priority:    opsgeniePriority(notification.Status),
+		Source:      "uptime-monitor",
+		Entity:      notification.Host,
+		Details:     details(notification),
+	}
+}
+
+// Maps uptime status to Opsgenie priority, FAIL is P1, uptime monitor unreachable due to its parent is P5
+func opsgeniePriority(status sns.UptimeStatus) string {
+	if status == sns.STATUS_FAIL {
+		return "P1"
+	}
+	return "P5"
+}
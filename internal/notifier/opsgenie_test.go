@@ -0,0 +1,101 @@
+package notifier
+
+import (
+	"github.com/stretchr/testify/assert"
+	uptimeSNS "monitor-uptime/internal/sns"
+	"net/http"
+	"testing"
+)
+
+// Given Opsgenie Alert API
+// When FAIL notification is sent
+// Then P1 alert is created with uptime ID as alias
+//      and request is authorized by API key
+func TestOpsgenieCreateAlert(t *testing.T) {
+	// Given
+	server, received := newReceiver(http.StatusAccepted)
+	defer server.Close()
+
+	// When
+	err := NewOpsgenie("api-key", server.URL, server.Client()).Notify(failNotification())
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	req := <-received
+	assert.Equal(t, "/v2/alerts", req.path, "Unexpected path")
+	assert.Equal(t, "GenieKey api-key", req.header.Get("Authorization"), "Unexpected authorization")
+	assert.Equal(t, "uptime-1", req.payload["alias"], "Unexpected alias")
+	assert.Equal(t, "P1", req.payload["priority"], "Unexpected priority")
+	assert.Equal(t, "350 ms", req.payload["details"].(map[string]interface{})["total"], "Unexpected total detail")
+}
+
+// Given Opsgenie Alert API
+// When OK notification is sent
+// Then alert with uptime ID as alias is closed
+func TestOpsgenieCloseAlert(t *testing.T) {
+	// Given
+	server, received := newReceiver(http.StatusAccepted)
+	defer server.Close()
+	notification := failNotification()
+	notification.PreviousStatus, notification.Status = uptimeSNS.STATUS_FAIL, uptimeSNS.STATUS_OK
+
+	// When
+	err := NewOpsgenie("api-key", server.URL, server.Client()).Notify(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "/v2/alerts/uptime-1/close?identifierType=alias", (<-received).path, "Unexpected path")
+}
+
+// Given Opsgenie Alert API
+// When DEGRADED or FLAPPING notification is sent
+// Then no alert is created
+func TestOpsgenieNotCreated(t *testing.T) {
+	for _, status := range []uptimeSNS.UptimeStatus{uptimeSNS.STATUS_DEGRADED, uptimeSNS.STATUS_FLAPPING} {
+		// Given
+		server, received := newReceiver(http.StatusAccepted)
+		notification := failNotification()
+		notification.Status = status
+
+		// When
+		err := NewOpsgenie("api-key", server.URL, server.Client()).Notify(notification)
+
+		// Then
+		assert.Nil(t, err, "Error was not expected to be returned")
+		assert.Len(t, received, 0, "No alert was expected to be created for "+string(status))
+		server.Close()
+	}
+}
+
+// Given Opsgenie Alert API
+// When DEGRADED notification following FAIL is sent
+// Then alert is closed
+func TestOpsgenieCloseAlertDegraded(t *testing.T) {
+	// Given
+	server, received := newReceiver(http.StatusAccepted)
+	defer server.Close()
+	notification := failNotification()
+	notification.PreviousStatus, notification.Status = uptimeSNS.STATUS_FAIL, uptimeSNS.STATUS_DEGRADED
+
+	// When
+	err := NewOpsgenie("api-key", server.URL, server.Client()).Notify(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "/v2/alerts/uptime-1/close?identifierType=alias", (<-received).path, "Unexpected path")
+}
+
+// Given Opsgenie Alert API rejecting API key
+// When notification is sent
+// Then error is returned
+func TestOpsgenieFailure(t *testing.T) {
+	// Given
+	server, _ := newReceiver(http.StatusUnauthorized)
+	defer server.Close()
+
+	// When
+	err := NewOpsgenie("api-key", server.URL, server.Client()).Notify(failNotification())
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
@@ -0,0 +1,106 @@
+package notifier
+
+import (
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/templates"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Public PagerDuty Events API v2 URL
+const PAGERDUTY_API_URL = "https://events.pagerduty.com"
+
+// Represents notifier triggering and resolving PagerDuty incidents through Events API v2
+// Uptime ID serves as deduplication key, so all transitions of uptime monitor belong to single incident
+type PagerDuty struct {
+	routingKey string
+	apiURL     string
+	client     *http.Client
+}
+
+// PagerDuty Events API v2 event
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Creates notifier sending events with routing key of PagerDuty integration
+// If apiURL is empty, then public PagerDuty Events API is used
+func NewPagerDuty(routingKey string, apiURL string, client *http.Client) *PagerDuty {
+	if apiURL == "" {
+		apiURL = PAGERDUTY_API_URL
+	}
+	return &PagerDuty{routingKey: routingKey, apiURL: strings.TrimSuffix(apiURL, "/"), client: client}
+}
+
+// Trigger PagerDuty incident on FAIL status, resolve it on recovery
+// DEGRADED, FLAPPING and announcements of maintenance windows are not sent, as they do not open nor resolve incidents
+func (n *PagerDuty) Notify(notification *Notification) error {
+	if incidentAction(notification) == "" {
+		return nil
+	}
+	event, err := pagerDutyEventOf(n.routingKey, notification)
+	if err != nil {
+		return err
+	}
+	return postJSON(n.client, n.apiURL+"/v2/enqueue", event)
+}
+
+// Renders uptime notification as PagerDuty event
+// Recovery resolves incident, FAIL triggers (or updates) incident with mapped severity
+// Uptime monitor unreachable due to its parent triggers event deduplicated into parent's incident.
+func pagerDutyEventOf(routingKey string, notification *Notification) (*pagerDutyEvent, error) {
+	event := &pagerDutyEvent{
+		RoutingKey:  routingKey,
+		EventAction: incidentAction(notification),
+		DedupKey:    notification.IncidentKey(),
+	}
+	if event.EventAction == INCIDENT_RESOLVE {
+		return event, nil
+	}
+
+	summary, err := render(notification, templates.SUMMARY)
+	if err != nil {
+		return nil, err
+	}
+	event.Payload = &pagerDutyPayload{
+		Summary:       summary,
+		Source:        notification.Host,
+		Severity:      pagerDutySeverity(notification.Status),
+		Component:     notification.UptimeID,
+		CustomDetails: details(notification),
+	}
+	if !notification.Time.IsZero() {
+		event.Payload.Timestamp = notification.Time.UTC().Format(time.RFC3339)
+	}
+	if isLinkable(notification.Host) {
+		event.Links = []pagerDutyLink{{Href: notification.Host, Text: "Monitored host"}}
+	}
+	return event, nil
+}
+
+// Maps uptime status to PagerDuty severity, FAIL is critical, uptime monitor unreachable due to its parent is info
+func pagerDutySeverity(status sns.UptimeStatus) string {
+	if status == sns.STATUS_FAIL {
+		return "critical"
+	}
+	return "info"
+}
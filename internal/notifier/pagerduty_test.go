@@ -0,0 +1,131 @@
+package notifier
+
+import (
+	"github.com/stretchr/testify/assert"
+	uptimeSNS "monitor-uptime/internal/sns"
+	"net/http"
+	"testing"
+)
+
+// Given PagerDuty Events API
+// When FAIL notification is sent
+// Then critical event is triggered with uptime ID as deduplication key
+//      and details of last run
+func TestPagerDutyTrigger(t *testing.T) {
+	// Given
+	server, received := newReceiver(http.StatusAccepted)
+	defer server.Close()
+
+	// When
+	err := NewPagerDuty("routing-key", server.URL, server.Client()).Notify(failNotification())
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	req := <-received
+	assert.Equal(t, "/v2/enqueue", req.path, "Unexpected path")
+	assert.Equal(t, "trigger", req.payload["event_action"], "Unexpected event action")
+	assert.Equal(t, "uptime-1", req.payload["dedup_key"], "Unexpected deduplication key")
+	payload := req.payload["payload"].(map[string]interface{})
+	assert.Equal(t, "critical", payload["severity"], "Unexpected severity")
+	assert.Equal(t, "503", payload["custom_details"].(map[string]interface{})["statusCode"], "Unexpected status code detail")
+	assert.Len(t, req.payload["links"], 1, "Link to host was expected")
+}
+
+// Given PagerDuty Events API
+// When OK notification is sent
+// Then incident with uptime ID as deduplication key is resolved
+func TestPagerDutyResolve(t *testing.T) {
+	// Given
+	server, received := newReceiver(http.StatusAccepted)
+	defer server.Close()
+	notification := failNotification()
+	notification.PreviousStatus, notification.Status = uptimeSNS.STATUS_FAIL, uptimeSNS.STATUS_OK
+
+	// When
+	err := NewPagerDuty("routing-key", server.URL, server.Client()).Notify(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	req := <-received
+	assert.Equal(t, "resolve", req.payload["event_action"], "Unexpected event action")
+	assert.Equal(t, "uptime-1", req.payload["dedup_key"], "Unexpected deduplication key")
+	assert.Nil(t, req.payload["payload"], "Resolve event was not expected to have payload")
+}
+
+// Given PagerDuty Events API
+// When start of maintenance window is announced
+// Then no event is sent
+func TestPagerDutyMaintenance(t *testing.T) {
+	// Given
+	server, received := newReceiver(http.StatusAccepted)
+	defer server.Close()
+	notification := failNotification()
+	notification.Status, notification.Maintenance = uptimeSNS.STATUS_MAINTENANCE, "deploy"
+
+	// When
+	err := NewPagerDuty("routing-key", server.URL, server.Client()).Notify(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Len(t, received, 0, "No event was expected to be sent")
+}
+
+// Given PagerDuty Events API
+// When notification of uptime monitor unreachable due to its parent is sent
+// Then event is deduplicated into parent's incident
+func TestPagerDutyUnreachable(t *testing.T) {
+	// Given
+	server, received := newReceiver(http.StatusAccepted)
+	defer server.Close()
+	notification := failNotification()
+	notification.Status, notification.Parent = uptimeSNS.STATUS_UNREACHABLE, "gateway"
+
+	// When
+	err := NewPagerDuty("routing-key", server.URL, server.Client()).Notify(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	req := <-received
+	assert.Equal(t, "trigger", req.payload["event_action"], "Unexpected event action")
+	assert.Equal(t, "gateway", req.payload["dedup_key"], "Parent's deduplication key was expected")
+	payload := req.payload["payload"].(map[string]interface{})
+	assert.Equal(t, "uptime-1", payload["component"], "Unexpected component")
+}
+
+// Given PagerDuty Events API
+// When DEGRADED or FLAPPING notification is sent
+// Then no event is sent
+func TestPagerDutyNotTriggered(t *testing.T) {
+	for _, status := range []uptimeSNS.UptimeStatus{uptimeSNS.STATUS_DEGRADED, uptimeSNS.STATUS_FLAPPING} {
+		// Given
+		server, received := newReceiver(http.StatusAccepted)
+		notification := failNotification()
+		notification.Status = status
+
+		// When
+		err := NewPagerDuty("routing-key", server.URL, server.Client()).Notify(notification)
+
+		// Then
+		assert.Nil(t, err, "Error was not expected to be returned")
+		assert.Len(t, received, 0, "No event was expected to be sent for "+string(status))
+		server.Close()
+	}
+}
+
+// Given PagerDuty Events API
+// When DEGRADED notification following FAIL is sent
+// Then incident is resolved
+func TestPagerDutyResolveDegraded(t *testing.T) {
+	// Given
+	server, received := newReceiver(http.StatusAccepted)
+	defer server.Close()
+	notification := failNotification()
+	notification.PreviousStatus, notification.Status = uptimeSNS.STATUS_FAIL, uptimeSNS.STATUS_DEGRADED
+
+	// When
+	err := NewPagerDuty("routing-key", server.URL, server.Client()).Notify(notification)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "resolve", (<-received).payload["event_action"], "Unexpected event action")
+}
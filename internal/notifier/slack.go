@@ -0,0 +1,65 @@
+package notifier
+
+import (
+	"net/http"
+)
+
+// Represents notifier posting Block Kit messages to Slack incoming webhook
+type Slack struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// Slack message with Block Kit blocks, text is shown in notifications only
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Creates notifier posting to Slack incoming webhook URL
+func NewSlack(webhookURL string, client *http.Client) *Slack {
+	return &Slack{webhookURL: webhookURL, client: client}
+}
+
+// Post uptime notification to Slack channel
+func (n *Slack) Notify(notification *Notification) error {
+	payload, err := slackPayload(notification)
+	if err != nil {
+		return err
+	}
+	return postJSON(n.client, n.webhookURL, payload)
+}
+
+// Renders uptime notification as Slack message with header, text, fields and context with uptime ID
+// Summary is used as text of notifications
+func slackPayload(notification *Notification) (*slackMessage, error) {
+	msg, err := renderMessage(notification)
+	if err != nil {
+		return nil, err
+	}
+	var texts []slackText
+	for _, field := range notification.Fields() {
+		texts = append(texts, slackText{Type: "mrkdwn", Text: "*" + field.Name + "*\n" + field.Value})
+	}
+	return &slackMessage{
+		Text: msg.Summary,
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: msg.Title}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: msg.Text}},
+			{Type: "section", Fields: texts},
+			{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: "Uptime ID: " + notification.UptimeID}}},
+		},
+	}, nil
+}
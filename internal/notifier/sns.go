@@ -0,0 +1,63 @@
+package notifier
+
+import (
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/templates"
+)
+
+// Represents notifier publishing uptime notifications to SNS topic
+type SNS struct {
+	topicARN string
+	client   snsiface.SNSAPI
+}
+
+// Creates notifier publishing to SNS topic provided by its ARN
+func NewSNS(topicARN string, client snsiface.SNSAPI) *SNS {
+	return &SNS{topicARN: topicARN, client: client}
+}
+
+// Publish uptime notification to SNS topic
+func (n *SNS) Notify(notification *Notification) error {
+	uptimeNotification, err := snsNotificationOf(notification)
+	if err != nil {
+		return err
+	}
+	return sns.PublishUptimeStatus(uptimeNotification, notification.UptimeID, n.topicARN, n.client)
+}
+
+// Converts notification into SNS uptime notification, timestamps are Unix timestamps and outage duration is in seconds
+// Rendered summary is included as message
+func snsNotificationOf(notification *Notification) (*sns.UptimeNotification, error) {
+	summary, err := render(notification, templates.SUMMARY)
+	if err != nil {
+		return nil, err
+	}
+	uptimeNotification := &sns.UptimeNotification{
+		Status:         notification.Status,
+		PreviousStatus: notification.PreviousStatus,
+		UptimeID:       notification.UptimeID,
+		Host:           notification.Host,
+		Message:        summary,
+		Reason:         notification.Reason,
+		ErrorClass:     notification.ErrorClass,
+		StatusCode:     notification.StatusCode,
+		TTFB:           notification.TTFB,
+		DNSLookup:      notification.DNSLookup,
+		TCPConnect:     notification.TCPConnect,
+		TLSHandshake:   notification.TLSHandshake,
+		Total:          notification.Total,
+		FailCounter:    notification.FailCounter,
+		OutageDuration: int64(notification.OutageDuration().Seconds()),
+		Maintenance:    notification.Maintenance,
+		FlapScore:      notification.FlapScore,
+		Parent:         notification.Parent,
+	}
+	if !notification.Time.IsZero() {
+		uptimeNotification.Timestamp = notification.Time.Unix()
+	}
+	if !notification.OutageStart.IsZero() {
+		uptimeNotification.OutageStart = notification.OutageStart.Unix()
+	}
+	return uptimeNotification, nil
+}
@@ -0,0 +1,99 @@
+package notifier
+
+import (
+	"monitor-uptime/internal/sns"
+	"net/http"
+)
+
+// Represents notifier posting Adaptive Cards to Microsoft Teams incoming webhook
+type Teams struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// Teams message carrying single Adaptive Card attachment
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsElement `json:"body"`
+}
+
+type teamsElement struct {
+	Type   string      `json:"type"`
+	Text   string      `json:"text,omitempty"`
+	Weight string      `json:"weight,omitempty"`
+	Size   string      `json:"size,omitempty"`
+	Color  string      `json:"color,omitempty"`
+	Wrap   bool        `json:"wrap,omitempty"`
+	Facts  []teamsFact `json:"facts,omitempty"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Creates notifier posting to Microsoft Teams incoming webhook URL
+func NewTeams(webhookURL string, client *http.Client) *Teams {
+	return &Teams{webhookURL: webhookURL, client: client}
+}
+
+// Post uptime notification to Microsoft Teams channel
+func (n *Teams) Notify(notification *Notification) error {
+	payload, err := teamsPayload(notification)
+	if err != nil {
+		return err
+	}
+	return postJSON(n.client, n.webhookURL, payload)
+}
+
+// Renders uptime notification as Adaptive Card with colored title, text and fact set
+func teamsPayload(notification *Notification) (*teamsMessage, error) {
+	msg, err := renderMessage(notification)
+	if err != nil {
+		return nil, err
+	}
+	var facts []teamsFact
+	for _, field := range notification.Fields() {
+		facts = append(facts, teamsFact{Title: field.Name, Value: field.Value})
+	}
+	facts = append(facts, teamsFact{Title: "Uptime ID", Value: notification.UptimeID})
+	return &teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body: []teamsElement{
+					{Type: "TextBlock", Text: msg.Title, Weight: "Bolder", Size: "Medium", Color: teamsColor(notification.Status), Wrap: true},
+					{Type: "TextBlock", Text: msg.Text, Wrap: true},
+					{Type: "FactSet", Facts: facts},
+				},
+			},
+		}},
+	}, nil
+}
+
+// Returns Adaptive Card color of status
+func teamsColor(status sns.UptimeStatus) string {
+	switch status {
+	case sns.STATUS_OK:
+		return "Good"
+	case sns.STATUS_DEGRADED:
+		return "Warning"
+	}
+	return "Attention"
+}
@@ -0,0 +1,229 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/templates"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Version of webhook payload, incremented on incompatible changes of payload
+const WEBHOOK_PAYLOAD_VERSION = 1
+
+// Events of webhook payload
+const (
+	WEBHOOK_EVENT_CHANGED  = "uptime.status_changed"  // Uptime status has changed
+	WEBHOOK_EVENT_REMINDER = "uptime.status_reminder" // Uptime status persists, e.g. reminder or escalation of outage
+)
+
+// Headers of webhook request
+const (
+	HEADER_DELIVERY  = "X-Uptime-Delivery"  // Unique ID of delivery, it is the same for all attempts
+	HEADER_TIMESTAMP = "X-Uptime-Timestamp" // Unix timestamp of attempt, it is part of signature to prevent replay
+	HEADER_SIGNATURE = "X-Uptime-Signature" // HMAC-SHA256 of "timestamp.body" signed by shared secret, prefixed by "sha256="
+)
+
+// Default retry policy of webhook notifier
+const (
+	WEBHOOK_MAX_ATTEMPTS = 3
+	WEBHOOK_BACKOFF      = time.Second
+)
+
+// Represents notifier posting signed JSON payloads to generic webhook
+// Delivery is retried with exponential backoff when webhook times out or responds with 5xx (or 429) status code
+type Webhook struct {
+	url         string
+	secret      string
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration // Delay before second attempt, doubled for every other attempt
+	mutex       sync.Mutex
+	attempts    []DeliveryAttempt
+}
+
+// Represents single attempt to deliver webhook payload
+type DeliveryAttempt struct {
+	DeliveryID string
+	Attempt    int // Number of attempt starting with 1
+	Time       time.Time
+	StatusCode int // Status code responded by webhook, 0 if it has not responded
+	Duration   time.Duration
+	Error      string // Reason why attempt failed, empty if payload has been delivered
+}
+
+// Versioned payload posted to webhook
+type WebhookPayload struct {
+	Version        int              `json:"version"`
+	Event          string           `json:"event"`      // Type of event, either "uptime.status_changed" or "uptime.status_reminder"
+	DeliveryID     string           `json:"deliveryId"` // Unique ID of delivery, it is the same for all attempts
+	Timestamp      int64            `json:"timestamp"`  // Timestamp of status transition
+	UptimeID       string           `json:"uptimeId"`
+	Host           string           `json:"host"`
+	PreviousStatus sns.UptimeStatus `json:"previousStatus"`
+	Status         sns.UptimeStatus `json:"status"`
+	Message        string           `json:"message,omitempty"`     // Human readable summary of transition
+	Escalated      bool             `json:"escalated,omitempty"`   // Whether outage has been escalated to secondary channels
+	Maintenance    string           `json:"maintenance,omitempty"` // Maintenance window whose start or end is announced
+	FlapScore      int              `json:"flapScore,omitempty"`   // Percentage of outcome changes among recent runs, set when flapping starts
+	Parent         string           `json:"parent,omitempty"`      // Failing parent uptime monitor into whose outage notification is folded
+	Reason         string           `json:"reason,omitempty"`
+	ErrorClass     string           `json:"errorClass,omitempty"`
+	StatusCode     int              `json:"statusCode,omitempty"`
+	TTFB           int64            `json:"ttfb,omitempty"`
+	Total          int64            `json:"total,omitempty"`
+}
+
+// Creates notifier posting to webhook URL, payloads are signed by secret if it is not empty
+// Delivery is attempted maxAttempts times at most, default retry policy is used if maxAttempts is not positive
+func NewWebhook(url string, secret string, maxAttempts int, client *http.Client) *Webhook {
+	if maxAttempts <= 0 {
+		maxAttempts = WEBHOOK_MAX_ATTEMPTS
+	}
+	return &Webhook{url: url, secret: secret, client: client, maxAttempts: maxAttempts, backoff: WEBHOOK_BACKOFF}
+}
+
+// Post uptime notification to webhook, failed deliveries are retried
+// Returns error if payload cannot be delivered by any attempt
+func (n *Webhook) Notify(notification *Notification) error {
+	payload, err := webhookPayloadOf(uuid.New().String(), notification)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	delay := n.backoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := n.deliver(payload.DeliveryID, attempt, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= n.maxAttempts {
+			return fmt.Errorf("webhook delivery %s failed after %d attempt(s): %w", payload.DeliveryID, attempt, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Returns attempts to deliver notifications made by this notifier
+func (n *Webhook) Attempts() []DeliveryAttempt {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return append([]DeliveryAttempt(nil), n.attempts...)
+}
+
+// Makes single delivery attempt and records it
+// Returns whether failed attempt should be retried and error if payload has not been delivered
+func (n *Webhook) deliver(deliveryID string, attempt int, body []byte) (bool, error) {
+	record := DeliveryAttempt{DeliveryID: deliveryID, Attempt: attempt, Time: time.Now()}
+	retryable, err := n.post(deliveryID, body, &record)
+	record.Duration = time.Since(record.Time)
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	n.mutex.Lock()
+	n.attempts = append(n.attempts, record)
+	n.mutex.Unlock()
+	return retryable, err
+}
+
+// Posts signed payload, status code of response is recorded
+func (n *Webhook) post(deliveryID string, body []byte, record *DeliveryAttempt) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(record.Time.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HEADER_DELIVERY, deliveryID)
+	req.Header.Set(HEADER_TIMESTAMP, timestamp)
+	if n.secret != "" {
+		req.Header.Set(HEADER_SIGNATURE, Sign(n.secret, timestamp, body))
+	}
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		// Network errors including timeouts are transient
+		return true, err
+	}
+	defer res.Body.Close()
+	record.StatusCode = res.StatusCode
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook responded with status code %d", res.StatusCode)
+	return res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests, err
+}
+
+// Renders uptime notification as versioned webhook payload with rendered summary as message
+func webhookPayloadOf(deliveryID string, notification *Notification) (*WebhookPayload, error) {
+	summary, err := render(notification, templates.SUMMARY)
+	if err != nil {
+		return nil, err
+	}
+	payload := &WebhookPayload{
+		Version:        WEBHOOK_PAYLOAD_VERSION,
+		Event:          WEBHOOK_EVENT_CHANGED,
+		DeliveryID:     deliveryID,
+		UptimeID:       notification.UptimeID,
+		Host:           notification.Host,
+		PreviousStatus: notification.PreviousStatus,
+		Status:         notification.Status,
+		Message:        summary,
+		Reason:         notification.Reason,
+		ErrorClass:     notification.ErrorClass,
+		StatusCode:     notification.StatusCode,
+		TTFB:           notification.TTFB,
+		Total:          notification.Total,
+		Escalated:      notification.Escalated,
+		Maintenance:    notification.Maintenance,
+		FlapScore:      notification.FlapScore,
+		Parent:         notification.Parent,
+	}
+	if notification.Reminder {
+		payload.Event = WEBHOOK_EVENT_REMINDER
+	}
+	if !notification.Time.IsZero() {
+		payload.Timestamp = notification.Time.Unix()
+	}
+	return payload, nil
+}
+
+// Returns signature of webhook payload sent at timestamp
+// Signature is hex encoded HMAC-SHA256 of "timestamp.body" using shared secret, prefixed by "sha256="
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifies signature of webhook payload received at now
+// Returns error if signature does not match or timestamp differs from now more than tolerance (replayed payload)
+func Verify(secret string, timestamp string, body []byte, signature string, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp: " + timestamp)
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return errors.New("timestamp is outside of tolerance")
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
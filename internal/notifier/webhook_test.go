@@ -0,0 +1,138 @@
+package notifier
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Starts webhook receiver responding with status codes of consecutive requests, last one is repeated
+// Payload and signature of each received request are verified using secret
+func newSignedReceiver(t *testing.T, secret string, statusCodes ...int) (*httptest.Server, *[]WebhookPayload) {
+	var payloads []WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		err := Verify(secret, r.Header.Get(HEADER_TIMESTAMP), body, r.Header.Get(HEADER_SIGNATURE), time.Minute, time.Now())
+		assert.Nil(t, err, "Signature was expected to be valid")
+		var payload WebhookPayload
+		_ = json.Unmarshal(body, &payload)
+		payloads = append(payloads, payload)
+		statusCode := statusCodes[len(statusCodes)-1]
+		if len(payloads) <= len(statusCodes) {
+			statusCode = statusCodes[len(payloads)-1]
+		}
+		w.WriteHeader(statusCode)
+	}))
+	return server, &payloads
+}
+
+// Creates webhook notifier without delay between attempts
+func newTestWebhook(url string, client *http.Client) *Webhook {
+	webhook := NewWebhook(url, "secret", 3, client)
+	webhook.backoff = time.Millisecond
+	return webhook
+}
+
+// Given webhook
+// When FAIL notification is sent
+// Then signed versioned payload with transition is posted
+//      and single delivery attempt is recorded
+func TestWebhookNotify(t *testing.T) {
+	// Given
+	server, payloads := newSignedReceiver(t, "secret", http.StatusOK)
+	defer server.Close()
+	webhook := newTestWebhook(server.URL, server.Client())
+
+	// When
+	err := webhook.Notify(failNotification())
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Len(t, *payloads, 1, "Unexpected number of deliveries")
+	payload := (*payloads)[0]
+	assert.Equal(t, WEBHOOK_PAYLOAD_VERSION, payload.Version, "Unexpected payload version")
+	assert.Equal(t, "OK", string(payload.PreviousStatus), "Unexpected previous status")
+	assert.Equal(t, "FAIL", string(payload.Status), "Unexpected status")
+	assert.Equal(t, int64(1600000000), payload.Timestamp, "Unexpected timestamp")
+	assert.Len(t, webhook.Attempts(), 1, "Unexpected number of attempts")
+	assert.Equal(t, http.StatusOK, webhook.Attempts()[0].StatusCode, "Unexpected recorded status code")
+}
+
+// Given webhook failing with server errors
+// When notification is sent
+// Then delivery is retried until it succeeds
+//      and all attempts share delivery ID
+func TestWebhookNotifyRetry(t *testing.T) {
+	// Given
+	server, payloads := newSignedReceiver(t, "secret", http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+	defer server.Close()
+	webhook := newTestWebhook(server.URL, server.Client())
+
+	// When
+	err := webhook.Notify(failNotification())
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Len(t, *payloads, 3, "Unexpected number of deliveries")
+	attempts := webhook.Attempts()
+	assert.Equal(t, "webhook responded with status code 502", attempts[0].Error, "Unexpected recorded error")
+	assert.Equal(t, attempts[0].DeliveryID, attempts[2].DeliveryID, "Attempts were expected to share delivery ID")
+	assert.Equal(t, 3, attempts[2].Attempt, "Unexpected attempt number")
+}
+
+// Given webhook permanently failing with server error
+// When notification is sent
+// Then delivery is given up after maximal number of attempts
+//      and error is returned
+func TestWebhookNotifyExhausted(t *testing.T) {
+	// Given
+	server, payloads := newSignedReceiver(t, "secret", http.StatusInternalServerError)
+	defer server.Close()
+
+	// When
+	err := newTestWebhook(server.URL, server.Client()).Notify(failNotification())
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+	assert.Len(t, *payloads, 3, "Unexpected number of deliveries")
+}
+
+// Given webhook rejecting payload with client error
+// When notification is sent
+// Then delivery is not retried
+func TestWebhookNotifyClientError(t *testing.T) {
+	// Given
+	server, payloads := newSignedReceiver(t, "secret", http.StatusBadRequest)
+	defer server.Close()
+
+	// When
+	err := newTestWebhook(server.URL, server.Client()).Notify(failNotification())
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+	assert.Len(t, *payloads, 1, "Unexpected number of deliveries")
+}
+
+// Given signed payload sent long time ago
+// When signature is verified
+// Then payload is rejected as replayed
+//      and tampered payload is rejected as well
+func TestVerifyReplay(t *testing.T) {
+	// Given
+	body := []byte(`{"status":"FAIL"}`)
+	signature := Sign("secret", "1600000000", body)
+
+	// When
+	replayed := Verify("secret", "1600000000", body, signature, time.Minute, time.Unix(1600000000, 0).Add(time.Hour))
+	tampered := Verify("secret", "1600000000", []byte(`{"status":"OK"}`), signature, time.Minute, time.Unix(1600000000, 0))
+	valid := Verify("secret", "1600000000", body, signature, time.Minute, time.Unix(1600000030, 0))
+
+	// Then
+	assert.NotNil(t, replayed, "Replayed payload was expected to be rejected")
+	assert.NotNil(t, tampered, "Tampered payload was expected to be rejected")
+	assert.Nil(t, valid, "Valid payload was expected to be accepted")
+}
@@ -0,0 +1,108 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Output formats of report
+const (
+	FORMAT_JSON     = "json"
+	FORMAT_CSV      = "csv"
+	FORMAT_MARKDOWN = "markdown"
+)
+
+// Layout of period boundaries in Markdown report
+const periodLayout = "2006-01-02 15:04"
+
+// Writes report in format (json, csv or markdown)
+// Returns error if format is not supported or report cannot be written
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FORMAT_JSON:
+		return r.WriteJSON(w)
+	case FORMAT_CSV:
+		return r.WriteCSV(w)
+	case FORMAT_MARKDOWN:
+		return r.WriteMarkdown(w)
+	}
+	return errors.New("unsupported report format: " + format)
+}
+
+// Writes report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Writes report as CSV with header and single row per uptime monitor, durations are in minutes
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"uptimeId", "from", "to", "runs", "failedRuns", "maintenanceRuns", "unreachableRuns",
+		"availability", "downtimeMinutes", "outages", "recoveredOutages", "mttrMinutes", "mtbfMinutes", "ttfbP50", "ttfbP90", "ttfbP95", "ttfbP99"})
+	if err != nil {
+		return err
+	}
+	for _, m := range r.Monitors {
+		err = writer.Write([]string{
+			m.UptimeID,
+			strconv.FormatInt(r.From, 10),
+			strconv.FormatInt(r.To, 10),
+			strconv.Itoa(m.Runs),
+			strconv.Itoa(m.FailedRuns),
+			strconv.Itoa(m.MaintenanceRuns),
+			strconv.Itoa(m.UnreachableRuns),
+			strconv.FormatFloat(m.Availability, 'f', 3, 64),
+			strconv.FormatFloat(m.Downtime, 'f', 1, 64),
+			strconv.Itoa(m.Outages),
+			strconv.Itoa(m.RecoveredOutages),
+			strconv.FormatFloat(m.MTTR, 'f', 1, 64),
+			strconv.FormatFloat(m.MTBF, 'f', 1, 64),
+			strconv.FormatInt(m.TTFB.P50, 10),
+			strconv.FormatInt(m.TTFB.P90, 10),
+			strconv.FormatInt(m.TTFB.P95, 10),
+			strconv.FormatInt(m.TTFB.P99, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Writes report as Markdown table with single row per uptime monitor, period is shown in UTC
+// Metrics which are not defined (e.g. MTTR without recovered outages) are shown as dash
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# SLA report %s - %s UTC\n\n"+
+		"| Monitor | Availability | Downtime | Outages | MTTR | MTBF | TTFB p50 | TTFB p90 | TTFB p95 | TTFB p99 |\n"+
+		"|---|---:|---:|---:|---:|---:|---:|---:|---:|---:|\n",
+		time.Unix(r.From, 0).UTC().Format(periodLayout), time.Unix(r.To, 0).UTC().Format(periodLayout))
+	if err != nil {
+		return err
+	}
+	for _, m := range r.Monitors {
+		availability, mttr, mtbf := "-", "-", "-"
+		if m.Runs > 0 {
+			availability = fmt.Sprintf("%.3f%%", m.Availability)
+		}
+		if m.RecoveredOutages > 0 {
+			mttr = fmt.Sprintf("%.1f min", m.MTTR)
+		}
+		if m.Outages > 0 {
+			mtbf = fmt.Sprintf("%.1f min", m.MTBF)
+		}
+		_, err = fmt.Fprintf(w, "| %s | %s | %.1f min | %d | %s | %s | %d ms | %d ms | %d ms | %d ms |\n",
+			m.UptimeID, availability, m.Downtime, m.Outages, mttr, mtbf, m.TTFB.P50, m.TTFB.P90, m.TTFB.P95, m.TTFB.P99)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package report
+
+import (
+	"math"
+	"monitor-uptime/internal/storage"
+	"sort"
+	"time"
+)
+
+// Represents availability report of uptime monitors over period
+type Report struct {
+	From     int64     `json:"from"` // Timestamp of period start (inclusive)
+	To       int64     `json:"to"`   // Timestamp of period end (inclusive)
+	Monitors []Metrics `json:"monitors"`
+}
+
+// Represents availability metrics of single uptime monitor over period
+// Runs executed during maintenance windows and runs of monitors unreachable due to failing parent are excluded,
+// the time until the next run is not monitored then.
+type Metrics struct {
+	UptimeID         string      `json:"uptimeId"`
+	Runs             int         `json:"runs"` // Number of runs outside of maintenance windows and parent outages
+	FailedRuns       int         `json:"failedRuns"`
+	MaintenanceRuns  int         `json:"maintenanceRuns"`
+	UnreachableRuns  int         `json:"unreachableRuns"`  // Number of runs while monitor was unreachable due to failing parent
+	Availability     float64     `json:"availability"`     // Percentage of monitored time when uptime monitor was up, 0 if there are no runs
+	Downtime         float64     `json:"downtime"`         // Minutes when uptime monitor was down
+	Outages          int         `json:"outages"`          // Number of series of consecutive failed runs
+	RecoveredOutages int         `json:"recoveredOutages"` // Number of outages which ended by successful run within period
+	MTTR             float64     `json:"mttr"`             // Mean time to recovery of recovered outages in minutes, 0 if no outage recovered
+	MTBF             float64     `json:"mtbf"`             // Mean time between failures (uptime per outage) in minutes, 0 if there are no outages
+	TTFB             Percentiles `json:"ttfb"`             // Percentiles of TTFB of successful runs in milliseconds
+}
+
+// Represents percentiles of measured latency
+type Percentiles struct {
+	P50 int64 `json:"p50"`
+	P90 int64 `json:"p90"`
+	P95 int64 `json:"p95"`
+	P99 int64 `json:"p99"`
+}
+
+// Creates report of uptime monitors over period between from and to timestamps (inclusive)
+// Metrics are computed from uptime monitor results listed from storage
+func New(store storage.Storage, uptimeIDs []string, from int64, to int64) (*Report, error) {
+	report := &Report{From: from, To: to, Monitors: []Metrics{}}
+	for _, uptimeID := range uptimeIDs {
+		results, err := store.ListUptimeResults(uptimeID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		report.Monitors = append(report.Monitors, Compute(uptimeID, results, from, to))
+	}
+	return report, nil
+}
+
+// Returns period of calendar month in location as from and to timestamps (inclusive)
+func Month(year int, month time.Month, loc *time.Location) (int64, int64) {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return start.Unix(), start.AddDate(0, 1, 0).Unix() - 1
+}
+
+// Computes metrics of uptime monitor from its results over period between from and to timestamps (inclusive)
+// Every run represents state of uptime monitor until the next run. The last run represents it until the end of period,
+// but at most for interval since the previous run, so runs which stopped before the end of period are not extrapolated.
+// The only run of period represents its own instant, availability is computed from number of runs then.
+// Failed run is down, degraded run is up. Outage lasts from its first failed run until the next successful run,
+// maintenance windows and parent outages within outage do not end it, but their time is not counted as downtime.
+func Compute(uptimeID string, results []storage.UptimeResultItem, from int64, to int64) Metrics {
+	var runs []storage.UptimeResultItem
+	for _, result := range results {
+		if result.RunAt >= from && result.RunAt <= to {
+			runs = append(runs, result)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].RunAt < runs[j].RunAt
+	})
+
+	metrics := Metrics{UptimeID: uptimeID}
+	var monitored, downtime, outageDowntime, recoveredDowntime int64
+	var ttfbs []int64
+	down := false
+	for i, run := range runs {
+		until := to
+		switch {
+		case i+1 < len(runs):
+			until = runs[i+1].RunAt
+		case i > 0 && to-run.RunAt > run.RunAt-runs[i-1].RunAt:
+			until = run.RunAt + run.RunAt - runs[i-1].RunAt
+		case i == 0:
+			until = run.RunAt
+		}
+		span := until - run.RunAt
+		if run.Maintenance {
+			metrics.MaintenanceRuns++
+			continue
+		}
+		if run.Parent != "" {
+			metrics.UnreachableRuns++
+			continue
+		}
+
+		metrics.Runs++
+		monitored += span
+		if run.Reason != "" {
+			metrics.FailedRuns++
+			downtime += span
+			outageDowntime += span
+			if !down {
+				metrics.Outages++
+			}
+			down = true
+		} else {
+			ttfbs = append(ttfbs, run.TTFB)
+			if down {
+				metrics.RecoveredOutages++
+				recoveredDowntime += outageDowntime
+			}
+			outageDowntime = 0
+			down = false
+		}
+	}
+
+	switch {
+	case monitored > 0:
+		metrics.Availability = float64(monitored-downtime) / float64(monitored) * 100
+	case metrics.Runs > 0:
+		metrics.Availability = float64(metrics.Runs-metrics.FailedRuns) / float64(metrics.Runs) * 100
+	}
+	metrics.Downtime = minutes(downtime)
+	if metrics.RecoveredOutages > 0 {
+		metrics.MTTR = minutes(recoveredDowntime) / float64(metrics.RecoveredOutages)
+	}
+	if metrics.Outages > 0 {
+		metrics.MTBF = minutes(monitored-downtime) / float64(metrics.Outages)
+	}
+	metrics.TTFB = percentiles(ttfbs)
+	return metrics
+}
+
+// Converts seconds to minutes
+func minutes(seconds int64) float64 {
+	return float64(seconds) / 60
+}
+
+// Returns percentiles of values by nearest-rank method, zero percentiles if there are no values
+func percentiles(values []int64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+	rank := func(p float64) int64 {
+		return values[int(math.Ceil(p/100*float64(len(values))))-1]
+	}
+	return Percentiles{P50: rank(50), P90: rank(90), P95: rank(95), P99: rank(99)}
+}
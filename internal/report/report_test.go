@@ -0,0 +1,192 @@
+package report
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/memory"
+	"monitor-uptime/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Runs executed every minute with two outages and single run during maintenance window
+var testRuns = []storage.UptimeResultItem{
+	{UptimeID: "uptime-1", RunAt: 0, TTFB: 100},
+	{UptimeID: "uptime-1", RunAt: 60, Reason: "unexpected status code 500"},
+	{UptimeID: "uptime-1", RunAt: 120, Reason: "unexpected status code 500"},
+	{UptimeID: "uptime-1", RunAt: 180, TTFB: 200},
+	{UptimeID: "uptime-1", RunAt: 240, Reason: "timeout", Maintenance: true},
+	{UptimeID: "uptime-1", RunAt: 300, TTFB: 300},
+	{UptimeID: "uptime-1", RunAt: 360, Reason: "timeout"},
+	{UptimeID: "uptime-1", RunAt: 420, TTFB: 400, Warning: "TTFB 400ms exceeds 300ms"},
+	{UptimeID: "uptime-1", RunAt: 480, TTFB: 500},
+	{UptimeID: "uptime-1", RunAt: 540, TTFB: 600},
+}
+
+// Given runs of uptime monitor with outages and maintenance window
+// When metrics are computed
+// Then run during maintenance window is excluded
+//      and metrics are computed from time between runs
+func TestCompute(t *testing.T) {
+	// When
+	metrics := Compute("uptime-1", testRuns, 0, 600)
+
+	// Then
+	assert.Equal(t, 9, metrics.Runs, "Unexpected number of runs")
+	assert.Equal(t, 3, metrics.FailedRuns, "Unexpected number of failed runs")
+	assert.Equal(t, 1, metrics.MaintenanceRuns, "Unexpected number of maintenance runs")
+	assert.InDelta(t, 66.667, metrics.Availability, 0.001, "Unexpected availability")
+	assert.Equal(t, 3.0, metrics.Downtime, "Unexpected downtime")
+	assert.Equal(t, 2, metrics.Outages, "Unexpected number of outages")
+	assert.Equal(t, 1.5, metrics.MTTR, "Unexpected MTTR")
+	assert.Equal(t, 3.0, metrics.MTBF, "Unexpected MTBF")
+	assert.Equal(t, Percentiles{P50: 300, P90: 600, P95: 600, P99: 600}, metrics.TTFB, "Unexpected TTFB percentiles")
+}
+
+// Given outage interrupted by maintenance window
+// When metrics are computed
+// Then outage continues after maintenance window
+//      and maintenance window is not counted as downtime
+func TestComputeOutageDuringMaintenance(t *testing.T) {
+	// Given
+	runs := []storage.UptimeResultItem{
+		{RunAt: 0, Reason: "timeout"},
+		{RunAt: 60, Reason: "timeout", Maintenance: true},
+		{RunAt: 120, Reason: "timeout"},
+		{RunAt: 180},
+	}
+
+	// When
+	metrics := Compute("uptime-1", runs, 0, 240)
+
+	// Then
+	assert.Equal(t, 1, metrics.Outages, "Unexpected number of outages")
+	assert.Equal(t, 2.0, metrics.Downtime, "Unexpected downtime")
+	assert.InDelta(t, 33.333, metrics.Availability, 0.001, "Unexpected availability")
+}
+
+// Given runs of uptime monitor unreachable due to failing parent
+// When metrics are computed
+// Then unreachable runs are reported separately
+//      and they are counted neither as runs nor as downtime
+func TestComputeUnreachable(t *testing.T) {
+	// Given
+	runs := []storage.UptimeResultItem{
+		{RunAt: 0},
+		{RunAt: 60, Reason: "timeout", Parent: "gateway"},
+		{RunAt: 120, Reason: "timeout", Parent: "gateway"},
+		{RunAt: 180},
+	}
+
+	// When
+	metrics := Compute("uptime-1", runs, 0, 240)
+
+	// Then
+	assert.Equal(t, 2, metrics.Runs, "Unexpected number of runs")
+	assert.Equal(t, 2, metrics.UnreachableRuns, "Unexpected number of unreachable runs")
+	assert.Equal(t, 0, metrics.FailedRuns, "Unreachable runs were not expected to fail")
+	assert.Equal(t, 0, metrics.Outages, "Unexpected number of outages")
+	assert.Equal(t, 100.0, metrics.Availability, "Unexpected availability")
+}
+
+// Given runs of uptime monitor which stopped long before the end of period
+// When metrics are computed
+// Then the last run represents single interval between runs only
+func TestComputeLastRunCapped(t *testing.T) {
+	// Given
+	runs := []storage.UptimeResultItem{
+		{RunAt: 0},
+		{RunAt: 60},
+		{RunAt: 120, Reason: "timeout"},
+	}
+
+	// When
+	metrics := Compute("uptime-1", runs, 0, 3600)
+
+	// Then
+	assert.Equal(t, 1.0, metrics.Downtime, "Downtime of the last run was expected to be capped")
+	assert.InDelta(t, 66.667, metrics.Availability, 0.001, "Unexpected availability")
+}
+
+// Given outage which recovered and outage which lasts until the end of period
+// When metrics are computed
+// Then both outages count as downtime
+//      and only recovered outage counts to MTTR
+func TestComputeOpenOutage(t *testing.T) {
+	// Given
+	runs := []storage.UptimeResultItem{
+		{RunAt: 0},
+		{RunAt: 60, Reason: "timeout"},
+		{RunAt: 120},
+		{RunAt: 180, Reason: "timeout"},
+		{RunAt: 240, Reason: "timeout"},
+	}
+
+	// When
+	metrics := Compute("uptime-1", runs, 0, 300)
+
+	// Then
+	assert.Equal(t, 2, metrics.Outages, "Unexpected number of outages")
+	assert.Equal(t, 1, metrics.RecoveredOutages, "Unexpected number of recovered outages")
+	assert.Equal(t, 3.0, metrics.Downtime, "Unexpected downtime")
+	assert.Equal(t, 1.0, metrics.MTTR, "Open outage was not expected to count to MTTR")
+}
+
+// Given uptime monitor without runs
+// When metrics are computed
+// Then metrics are empty
+func TestComputeWithoutRuns(t *testing.T) {
+	// When
+	metrics := Compute("uptime-1", nil, 0, 600)
+
+	// Then
+	assert.Equal(t, Metrics{UptimeID: "uptime-1"}, metrics, "Metrics were expected to be empty")
+}
+
+// When period of month is computed
+// Then it lasts from the first second of month until the last one in location
+func TestMonth(t *testing.T) {
+	// Given
+	prague, _ := time.LoadLocation("Europe/Prague")
+
+	// When
+	from, to := Month(2020, time.September, prague)
+
+	// Then
+	assert.Equal(t, time.Date(2020, 8, 31, 22, 0, 0, 0, time.UTC).Unix(), from, "Unexpected start of month")
+	assert.Equal(t, time.Date(2020, 9, 30, 21, 59, 59, 0, time.UTC).Unix(), to, "Unexpected end of month")
+}
+
+// Given uptime results are stored
+// When report is created and written in all formats
+// Then every format contains metrics of reported uptime monitors
+func TestReportWrite(t *testing.T) {
+	// Given
+	store := memory.NewStorage()
+	for _, run := range testRuns {
+		assert.Nil(t, store.StoreUptimeResult(&run), "Error was not expected to be returned")
+	}
+	report, err := New(store, []string{"uptime-1", "uptime-2"}, 0, 600)
+	assert.Nil(t, err, "Error was not expected to be returned")
+
+	// When
+	var jsonOut, csvOut, markdownOut bytes.Buffer
+	jsonErr := report.Write(&jsonOut, FORMAT_JSON)
+	csvErr := report.Write(&csvOut, FORMAT_CSV)
+	markdownErr := report.Write(&markdownOut, FORMAT_MARKDOWN)
+	unsupportedErr := report.Write(&bytes.Buffer{}, "xml")
+
+	// Then
+	assert.Nil(t, jsonErr, "Error was not expected to be returned")
+	assert.Nil(t, csvErr, "Error was not expected to be returned")
+	assert.Nil(t, markdownErr, "Error was not expected to be returned")
+	assert.NotNil(t, unsupportedErr, "Error was expected to be returned for unsupported format")
+	assert.Contains(t, jsonOut.String(), `"maintenanceRuns": 1`, "Unexpected JSON report")
+	csvLines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	assert.Len(t, csvLines, 3, "CSV report was expected to have header and row per uptime monitor")
+	assert.Equal(t, "uptime-1,0,600,9,3,1,0,66.667,3.0,2,2,1.5,3.0,300,600,600,600", csvLines[1], "Unexpected CSV row")
+	assert.Contains(t, markdownOut.String(), "# SLA report 1970-01-01 00:00 - 1970-01-01 00:10 UTC", "Unexpected Markdown title")
+	assert.Contains(t, markdownOut.String(), "| uptime-1 | 66.667% | 3.0 min | 2 | 1.5 min | 3.0 min | 300 ms |", "Unexpected Markdown row")
+	assert.Contains(t, markdownOut.String(), "| uptime-2 | - | 0.0 min | 0 | - | - | 0 ms |", "Unexpected Markdown row without runs")
+}
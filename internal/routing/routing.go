@@ -0,0 +1,221 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"monitor-uptime/internal/notifier"
+	"monitor-uptime/internal/tags"
+	"strconv"
+	"strings"
+	"time"
+	// Time zones of business hours are embedded, as Lambda runtime may not provide time zone database
+	_ "time/tzdata"
+)
+
+// Times of day matched by route
+const (
+	TIME_ANY            = ""
+	TIME_BUSINESS_HOURS = "business"
+	TIME_AFTER_HOURS    = "after"
+)
+
+// Represents routing of status transitions to notification channels
+// Routes are evaluated in order, the first matching route wins unless it continues to the following routes.
+// If no route matches, then transition is routed to default channels.
+type Config struct {
+	Channels      map[string]notifier.Channel `json:"channels"`      // Notification channels by their names
+	Routes        []Route                     `json:"routes"`        // Routes evaluated in order
+	Default       []string                    `json:"default"`       // Names of channels used when no route matches
+	BusinessHours *BusinessHours              `json:"businessHours"` // Business hours, Monday to Friday 09:00-17:00 UTC if not set
+}
+
+// Represents route of matching status transitions to notification channels
+type Route struct {
+	Name     string            `json:"name"`
+	Match    map[string]string `json:"match"`    // Tags which uptime monitor must have, "*" matches any value
+	Statuses []string          `json:"statuses"` // Statuses matched by route (OK, DEGRADED, FAIL), any if empty
+	Time     string            `json:"time"`     // Either business (business hours), after (after hours) or empty (any time)
+	Channels []string          `json:"channels"` // Names of channels to which matched transitions are routed
+	Continue bool              `json:"continue"` // Whether following routes are evaluated as well once route matches
+}
+
+// Represents business hours in time zone
+type BusinessHours struct {
+	TimeZone string   `json:"timeZone"` // IANA time zone, e.g. Europe/Prague, UTC if empty
+	Days     []string `json:"days"`     // Business days, e.g. ["mon", "tue", "wed", "thu", "fri"]
+	Start    string   `json:"start"`    // Start of business hours, e.g. 09:00
+	End      string   `json:"end"`      // End of business hours (exclusive), e.g. 17:00
+}
+
+// Default business hours, Monday to Friday 09:00-17:00 UTC
+var DefaultBusinessHours = BusinessHours{
+	Days:  []string{"mon", "tue", "wed", "thu", "fri"},
+	Start: "09:00",
+	End:   "17:00",
+}
+
+// Parses routing configuration from JSON and validates it
+// Returns error if configuration cannot be parsed or route references unknown channel
+func Parse(content []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Loads routing configuration from JSON file
+// Value starting with { is parsed as inline JSON configuration instead
+func Load(pathOrJSON string) (*Config, error) {
+	content, err := tags.ReadConfig(pathOrJSON)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// Returns channels to which transition to status of uptime monitor with tags should be routed at time
+// Default channels are returned if no route matches
+func (c *Config) Route(tags map[string]string, status string, at time.Time) []notifier.Channel {
+	if channels := c.Match(tags, status, at); len(channels) > 0 {
+		return channels
+	}
+	return c.DefaultChannels()
+}
+
+// Returns channels of routes matching transition to status of uptime monitor with tags at time, without default channels
+func (c *Config) Match(tags map[string]string, status string, at time.Time) []notifier.Channel {
+	var names []string
+	for _, route := range c.Routes {
+		if !route.matches(tags, status, c.isBusinessHours(at)) {
+			continue
+		}
+		names = append(names, route.Channels...)
+		if !route.Continue {
+			break
+		}
+	}
+	return c.channels(names)
+}
+
+// Returns default channels, which are used when no route matches
+func (c *Config) DefaultChannels() []notifier.Channel {
+	return c.channels(c.Default)
+}
+
+// Returns channels by their names, each channel is returned once
+func (c *Config) channels(names []string) []notifier.Channel {
+	var channels []notifier.Channel
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			channels = append(channels, c.Channels[name])
+		}
+	}
+	return channels
+}
+
+// Returns true if route matches tags, status and time of day
+func (r *Route) matches(monitorTags map[string]string, status string, businessHours bool) bool {
+	if !tags.Matches(r.Match, monitorTags) {
+		return false
+	}
+	if len(r.Statuses) > 0 && !containsFold(r.Statuses, status) {
+		return false
+	}
+	switch r.Time {
+	case TIME_BUSINESS_HOURS:
+		return businessHours
+	case TIME_AFTER_HOURS:
+		return !businessHours
+	}
+	return true
+}
+
+// Returns true if time is within business hours
+func (c *Config) isBusinessHours(at time.Time) bool {
+	hours := c.BusinessHours
+	if hours == nil {
+		hours = &DefaultBusinessHours
+	}
+	location, err := time.LoadLocation(hours.TimeZone)
+	if err != nil {
+		location = time.UTC
+	}
+	local := at.In(location)
+	day := strings.ToLower(local.Weekday().String()[:3])
+	if !containsFold(hours.Days, day) {
+		return false
+	}
+	start, _ := minutesOfDay(hours.Start)
+	end, _ := minutesOfDay(hours.End)
+	minutes := local.Hour()*60 + local.Minute()
+	return minutes >= start && minutes < end
+}
+
+// Validates that routes reference configured channels and business hours are well-formed
+func (c *Config) validate() error {
+	for _, route := range c.Routes {
+		if err := c.validateChannels(route.Channels); err != nil {
+			return err
+		}
+		if route.Time != TIME_ANY && route.Time != TIME_BUSINESS_HOURS && route.Time != TIME_AFTER_HOURS {
+			return errors.New("route " + route.Name + " has unsupported time: " + route.Time)
+		}
+	}
+	if err := c.validateChannels(c.Default); err != nil {
+		return err
+	}
+	if c.BusinessHours != nil {
+		if _, err := time.LoadLocation(c.BusinessHours.TimeZone); err != nil {
+			return err
+		}
+		for _, value := range []string{c.BusinessHours.Start, c.BusinessHours.End} {
+			if _, err := minutesOfDay(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Validates that all channel names are configured
+func (c *Config) validateChannels(names []string) error {
+	for _, name := range names {
+		if _, ok := c.Channels[name]; !ok {
+			return errors.New("unknown channel: " + name)
+		}
+	}
+	return nil
+}
+
+// Parses time of day in HH:MM format into minutes since midnight
+func minutesOfDay(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, errors.New("invalid time of day: " + value)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 || hours > 24 {
+		return 0, errors.New("invalid time of day: " + value)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, errors.New("invalid time of day: " + value)
+	}
+	return hours*60 + minutes, nil
+}
+
+// Returns true if values contain value ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package routing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/notifier"
+	"testing"
+	"time"
+)
+
+// Routing configuration of payments team paging on-call after hours
+const testConfig = `{
+	"channels": {
+		"payments-slack": {"type": "slack", "webhookUrl": "https://hooks.slack.com/payments"},
+		"payments-pager": {"type": "pagerduty", "integrationKey": "key"},
+		"ops-email": {"type": "email", "to": ["ops@example.com"]},
+		"default-sns": {"type": "sns"}
+	},
+	"routes": [
+		{"name": "critical", "match": {"team": "payments", "severity": "critical"}, "statuses": ["FAIL", "OK"], "time": "after", "channels": ["payments-pager"], "continue": true},
+		{"name": "payments", "match": {"team": "payments"}, "channels": ["payments-slack"]},
+		{"name": "production", "match": {"env": "prod"}, "time": "business", "channels": ["ops-email"]}
+	],
+	"default": ["default-sns"],
+	"businessHours": {"timeZone": "Europe/Prague", "days": ["mon", "tue", "wed", "thu", "fri"], "start": "09:00", "end": "17:00"}
+}`
+
+var (
+	mondayNoon  = time.Date(2020, 9, 14, 10, 0, 0, 0, time.UTC) // 12:00 in Prague
+	mondayNight = time.Date(2020, 9, 14, 20, 0, 0, 0, time.UTC) // 22:00 in Prague
+	saturday    = time.Date(2020, 9, 12, 10, 0, 0, 0, time.UTC)
+)
+
+// Returns types of channels
+func channelTypes(channels []notifier.Channel) []string {
+	var types []string
+	for _, channel := range channels {
+		types = append(types, channel.Type)
+	}
+	return types
+}
+
+// Given routing configuration
+// When critical payments monitor fails after hours
+// Then transition is routed to pager and continues to payments Slack channel
+func TestRouteAfterHoursContinues(t *testing.T) {
+	// Given
+	config, err := Parse([]byte(testConfig))
+	assert.Nil(t, err, "Error was not expected to be returned")
+
+	// When
+	res := config.Route(map[string]string{"team": "payments", "severity": "critical"}, "FAIL", mondayNight)
+
+	// Then
+	assert.Equal(t, []string{"pagerduty", "slack"}, channelTypes(res), "Unexpected channels")
+}
+
+// Given routing configuration
+// When critical payments monitor fails during business hours
+//      or it is only degraded after hours
+// Then transition is routed to payments Slack channel only
+func TestRouteBusinessHoursAndStatus(t *testing.T) {
+	// Given
+	config, _ := Parse([]byte(testConfig))
+	tags := map[string]string{"team": "payments", "severity": "critical"}
+
+	// When
+	businessHours := config.Route(tags, "FAIL", mondayNoon)
+	degraded := config.Route(tags, "DEGRADED", mondayNight)
+
+	// Then
+	assert.Equal(t, []string{"slack"}, channelTypes(businessHours), "Unexpected channels during business hours")
+	assert.Equal(t, []string{"slack"}, channelTypes(degraded), "Unexpected channels of degraded monitor")
+}
+
+// Given routing configuration
+// When production monitor fails during weekend
+//      and monitor without tags fails
+// Then transitions are routed to default channels
+func TestRouteDefault(t *testing.T) {
+	// Given
+	config, _ := Parse([]byte(testConfig))
+
+	// When
+	weekend := config.Route(map[string]string{"env": "prod"}, "FAIL", saturday)
+	untagged := config.Route(nil, "FAIL", mondayNoon)
+	business := config.Route(map[string]string{"env": "PROD"}, "FAIL", mondayNoon)
+
+	// Then
+	assert.Equal(t, []string{"sns"}, channelTypes(weekend), "Unexpected channels during weekend")
+	assert.Equal(t, []string{"sns"}, channelTypes(untagged), "Unexpected channels of untagged monitor")
+	assert.Equal(t, []string{"email"}, channelTypes(business), "Unexpected channels during business hours")
+}
+
+// Given route matching any value of tag
+// When monitors with and without tag fail
+// Then only monitor with tag is routed
+func TestRouteAnyValue(t *testing.T) {
+	// Given
+	config, _ := Parse([]byte(`{
+		"channels": {"team": {"type": "slack"}},
+		"routes": [{"match": {"team": "*"}, "channels": ["team"]}]
+	}`))
+
+	// When
+	tagged := config.Route(map[string]string{"team": "search"}, "FAIL", mondayNoon)
+	untagged := config.Route(map[string]string{"env": "prod"}, "FAIL", mondayNoon)
+
+	// Then
+	assert.Len(t, tagged, 1, "Tagged monitor was expected to be routed")
+	assert.Len(t, untagged, 0, "Untagged monitor was not expected to be routed")
+}
+
+// Given routing configuration
+// When monitor without matching route fails
+// Then no channels are matched
+//      and default channels are available separately
+func TestMatchWithoutDefault(t *testing.T) {
+	// Given
+	config, _ := Parse([]byte(testConfig))
+
+	// When
+	res := config.Match(nil, "FAIL", mondayNoon)
+
+	// Then
+	assert.Empty(t, res, "Default channels were not expected to be matched")
+	assert.Equal(t, []string{"sns"}, channelTypes(config.DefaultChannels()), "Unexpected default channels")
+}
+
+// When routing configuration references unknown channel
+//      or has invalid business hours
+// Then error is returned
+func TestParseInvalid(t *testing.T) {
+	_, unknownChannel := Parse([]byte(`{"routes": [{"channels": ["missing"]}]}`))
+	_, invalidHours := Parse([]byte(`{"businessHours": {"start": "9am", "end": "17:00"}}`))
+	_, invalidTime := Parse([]byte(`{"routes": [{"time": "night"}]}`))
+
+	assert.NotNil(t, unknownChannel, "Unknown channel was expected to be rejected")
+	assert.NotNil(t, invalidHours, "Invalid business hours were expected to be rejected")
+	assert.NotNil(t, invalidTime, "Invalid time was expected to be rejected")
+}
+
+// When routing configuration is loaded from inline JSON
+// Then it is parsed
+func TestLoadInline(t *testing.T) {
+	config, err := Load(` {"default": []}`)
+
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.NotNil(t, config, "Configuration was expected to be loaded")
+}
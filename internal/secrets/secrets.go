@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"os"
+	"strings"
+)
+
+// Prefixes of secret references
+const (
+	PREFIX_ENV             = "env:"
+	PREFIX_SECRETS_MANAGER = "secretsmanager:"
+	PREFIX_SSM             = "ssm:"
+)
+
+// Resolves value which may reference secret stored outside of the uptime monitor request
+// Supported references are:
+//   - env:NAME - value of environment variable NAME
+//   - secretsmanager:ID - secret string of AWS Secrets Manager secret ID (name or ARN)
+//   - secretsmanager:ID#key - value of key of AWS Secrets Manager secret ID stored as JSON
+//   - ssm:NAME - decrypted value of AWS SSM parameter NAME
+//
+// Values without any of these prefixes are returned as is.
+// Returns error if referenced secret cannot be resolved
+func Resolve(value string, sm secretsmanageriface.SecretsManagerAPI, ssmClient ssmiface.SSMAPI) (string, error) {
+	switch {
+	case strings.HasPrefix(value, PREFIX_ENV):
+		return resolveEnv(strings.TrimPrefix(value, PREFIX_ENV))
+	case strings.HasPrefix(value, PREFIX_SECRETS_MANAGER):
+		return resolveSecretsManager(strings.TrimPrefix(value, PREFIX_SECRETS_MANAGER), sm)
+	case strings.HasPrefix(value, PREFIX_SSM):
+		return resolveSSM(strings.TrimPrefix(value, PREFIX_SSM), ssmClient)
+	}
+	return value, nil
+}
+
+// Resolves value of environment variable
+func resolveEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.New("environment variable " + name + " is not set")
+	}
+	return value, nil
+}
+
+// Resolves secret string of AWS Secrets Manager secret
+// If reference contains #key suffix, then secret string is decoded as JSON object and value of key is returned
+func resolveSecretsManager(reference string, sm secretsmanageriface.SecretsManagerAPI) (string, error) {
+	secretID, key := reference, ""
+	if i := strings.LastIndex(reference, "#"); i >= 0 {
+		secretID, key = reference[:i], reference[i+1:]
+	}
+
+	output, err := sm.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.SecretString == nil {
+		return "", errors.New("secret " + secretID + " does not contain secret string")
+	}
+	if key == "" {
+		return *output.SecretString, nil
+	}
+
+	var values map[string]interface{}
+	if err = json.Unmarshal([]byte(*output.SecretString), &values); err != nil {
+		return "", err
+	}
+	value, ok := values[key].(string)
+	if !ok {
+		return "", errors.New("secret " + secretID + " does not contain string key " + key)
+	}
+	return value, nil
+}
+
+// Resolves decrypted value of AWS SSM parameter
+func resolveSSM(name string, ssmClient ssmiface.SSMAPI) (string, error) {
+	output, err := ssmClient.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(output.Parameter.Value), nil
+}
@@ -0,0 +1,116 @@
+package secrets
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// Secrets Manager mock
+type mockSecretsManagerClient struct {
+	secrets map[string]string
+	secretsmanageriface.SecretsManagerAPI
+}
+
+func (m mockSecretsManagerClient) GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+	if secret, ok := m.secrets[*input.SecretId]; ok {
+		return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(secret)}, nil
+	}
+	return nil, errors.New("secret not found")
+}
+
+// SSM mock
+type mockSSMClient struct {
+	parameters map[string]string
+	ssmiface.SSMAPI
+}
+
+func (m mockSSMClient) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	if parameter, ok := m.parameters[*input.Name]; ok {
+		return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(parameter)}}, nil
+	}
+	return nil, errors.New("parameter not found")
+}
+
+// When plain value is resolved
+// Then value is returned as is
+func TestResolvePlainValue(t *testing.T) {
+	// When
+	value, err := Resolve("plain-value", mockSecretsManagerClient{}, mockSSMClient{})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "plain-value", value, "Unexpected resolved value")
+}
+
+// Given environment variable is set
+// When environment variable reference is resolved
+// Then value of environment variable is returned
+func TestResolveEnv(t *testing.T) {
+	// Given
+	_ = os.Setenv("UPTIME_TEST_TOKEN", "token-value")
+	defer os.Unsetenv("UPTIME_TEST_TOKEN")
+
+	// When
+	value, err := Resolve("env:UPTIME_TEST_TOKEN", mockSecretsManagerClient{}, mockSSMClient{})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "token-value", value, "Unexpected resolved value")
+}
+
+// When reference to environment variable which is not set is resolved
+// Then error is returned
+func TestResolveEnvMissing(t *testing.T) {
+	// When
+	_, err := Resolve("env:UPTIME_TEST_MISSING", mockSecretsManagerClient{}, mockSSMClient{})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given secret stored as JSON in Secrets Manager
+// When reference to secret and its key is resolved
+// Then value of key is returned
+func TestResolveSecretsManagerKey(t *testing.T) {
+	// Given
+	sm := mockSecretsManagerClient{secrets: map[string]string{"monitor/api": `{"password":"s3cr3t"}`}}
+
+	// When
+	value, err := Resolve("secretsmanager:monitor/api#password", sm, mockSSMClient{})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "s3cr3t", value, "Unexpected resolved value")
+}
+
+// Given parameter stored in SSM
+// When reference to parameter is resolved
+// Then value of parameter is returned
+func TestResolveSSM(t *testing.T) {
+	// Given
+	ssmClient := mockSSMClient{parameters: map[string]string{"/monitor/token": "ssm-token"}}
+
+	// When
+	value, err := Resolve("ssm:/monitor/token", mockSecretsManagerClient{}, ssmClient)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "ssm-token", value, "Unexpected resolved value")
+}
+
+// When reference to non-existing SSM parameter is resolved
+// Then error is returned
+func TestResolveSSMFailure(t *testing.T) {
+	// When
+	_, err := Resolve("ssm:/monitor/missing", mockSecretsManagerClient{}, mockSSMClient{})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
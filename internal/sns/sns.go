@@ -7,21 +7,45 @@ import (
 	"github.com/aws/aws-sdk-go/service/sns/snsiface"
 )
 
-// Represents uptime status. Either OK or FAIL.
+// Represents uptime status. Either OK, DEGRADED, FAIL, MAINTENANCE, FLAPPING or UNREACHABLE_DUE_TO_PARENT.
 type UptimeStatus string
 
 const (
-	STATUS_OK   = "OK"
-	STATUS_FAIL = "FAIL"
+	STATUS_OK          = "OK"
+	STATUS_DEGRADED    = "DEGRADED"
+	STATUS_FAIL        = "FAIL"
+	STATUS_MAINTENANCE = "MAINTENANCE"               // Uptime monitor is in maintenance window, its failures are not counted
+	STATUS_FLAPPING    = "FLAPPING"                  // Status of uptime monitor changes too often, transitions are not announced
+	STATUS_UNREACHABLE = "UNREACHABLE_DUE_TO_PARENT" // Uptime monitor fails while its parent is down
 )
 
 // Represents notification sent to SNS topic
+// Notification describes status transition together with the last run, so subscribers do not need to query storage
 type UptimeNotification struct {
-	Status UptimeStatus `json:"status"`
+	Status         UptimeStatus `json:"status"`
+	PreviousStatus UptimeStatus `json:"previousStatus,omitempty"` // Status announced before this transition
+	UptimeID       string       `json:"uptimeId,omitempty"`
+	Host           string       `json:"host,omitempty"`
+	Message        string       `json:"message,omitempty"`        // Human readable summary of transition
+	Reason         string       `json:"reason,omitempty"`         // Reason of failure or degradation, e.g. first failed assertion
+	ErrorClass     string       `json:"errorClass,omitempty"`     // Class of probe failure (e.g. dns_error, timeout), if host could not be probed
+	StatusCode     int          `json:"statusCode,omitempty"`     // Status code of the last run
+	TTFB           int64        `json:"ttfb,omitempty"`           // Time To First Byte of the last run in milliseconds
+	DNSLookup      int64        `json:"dnslookup,omitempty"`      // Duration of DNS lookup of the last run in milliseconds
+	TCPConnect     int64        `json:"tcpConnect,omitempty"`     // Duration of TCP connect of the last run in milliseconds
+	TLSHandshake   int64        `json:"tlshandshake,omitempty"`   // Duration of TLS handshake of the last run in milliseconds
+	Total          int64        `json:"total,omitempty"`          // Duration of the last run in milliseconds
+	FailCounter    int          `json:"failCounter,omitempty"`    // Number of consecutive failed runs
+	Timestamp      int64        `json:"timestamp,omitempty"`      // Timestamp of status transition
+	OutageStart    int64        `json:"outageStart,omitempty"`    // Timestamp of the first failed run of outage
+	OutageDuration int64        `json:"outageDuration,omitempty"` // Duration of outage in seconds, set on recovery (OK) and reminders of persisting outage
+	Maintenance    string       `json:"maintenance,omitempty"`    // Maintenance window whose start or end is announced
+	FlapScore      int          `json:"flapScore,omitempty"`      // Percentage of outcome changes among recent runs, set when flapping starts
+	Parent         string       `json:"parent,omitempty"`         // Failing parent uptime monitor into whose outage notification is folded
 }
 
 // Publish uptime notification to SNS topic provided by its ARN
-// Published message contains single attribute with uptime ID, which serves for filtering purposes
+// Published message contains attributes with uptime ID, status and host, which serve for filtering purposes
 // Returns error if uptime notification cannot be published to SNS topic, otherwise nil
 func PublishUptimeStatus(
 	uptimeNotification *UptimeNotification,
@@ -33,15 +57,28 @@ func PublishUptimeStatus(
 		return err
 	}
 
-	_, err = snsClient.Publish(&sns.PublishInput{
-		TopicArn: aws.String(topicARN),
-		Message:  aws.String(string(uptimeNotificationJson)),
-		MessageAttributes: map[string]*sns.MessageAttributeValue{
-			"uptimeId": {
-				DataType:    aws.String("String"),
-				StringValue: aws.String(uptimeID),
-			},
+	attributes := map[string]*sns.MessageAttributeValue{
+		"uptimeId": {
+			DataType:    aws.String("String"),
+			StringValue: aws.String(uptimeID),
+		},
+		"status": {
+			DataType:    aws.String("String"),
+			StringValue: aws.String(string(uptimeNotification.Status)),
 		},
+	}
+	// SNS does not accept empty attribute values
+	if uptimeNotification.Host != "" {
+		attributes["host"] = &sns.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(uptimeNotification.Host),
+		}
+	}
+
+	_, err = snsClient.Publish(&sns.PublishInput{
+		TopicArn:          aws.String(topicARN),
+		Message:           aws.String(string(uptimeNotificationJson)),
+		MessageAttributes: attributes,
 	})
 	if err != nil {
 		return err
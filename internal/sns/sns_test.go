@@ -19,26 +19,30 @@ type mockSNSClient struct {
 }
 
 // SNS erroneous client mock
-func (m mockSNSClient) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+func (m *mockSNSClient) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
 	args := m.Called(input)
 	return args.Get(0).(*sns.PublishOutput), args.Error(1)
 }
 
 // Given uptime notification,
 // When uptime notification is publish into SNS topic for specific uptime monitor,
-// Then published MSG contains uptime ID of that uptime monitor as an attribute
+// Then published MSG contains uptime ID of that uptime monitor, status and host as attributes
 func TestPublishUptimeContainsUptimeID(t *testing.T) {
 	// Given
 	topicARN := "topic-ARN-1"
 	uptimeID, _ := uuid.NewRandom()
 	uptimeNotification := UptimeNotification{
-		Status: STATUS_OK,
+		Status:         STATUS_OK,
+		PreviousStatus: STATUS_FAIL,
+		Host:           "https://example.com",
+		OutageStart:    1600000000,
+		OutageDuration: 300,
 	}
 	snsClient := mockSNSClient{}
 	snsClient.On("Publish", expectedPublishInput(uptimeID, topicARN, uptimeNotification)).Return(&sns.PublishOutput{}, nil)
 
 	// When
-	err := PublishUptimeStatus(&uptimeNotification, uptimeID.String(), topicARN, snsClient)
+	err := PublishUptimeStatus(&uptimeNotification, uptimeID.String(), topicARN, &snsClient)
 
 	// Then
 	assert.Nil(t, err, "Unexpected error has happened")
@@ -52,10 +56,18 @@ func expectedPublishInput(uptimeID uuid.UUID, expectedTopicARN string, expectedU
 		TopicArn: aws.String(expectedTopicARN),
 		Message:  aws.String(string(uptimeNotificationJson)),
 		MessageAttributes: map[string]*sns.MessageAttributeValue{
-			"uptimeID": {
+			"uptimeId": {
 				DataType:    aws.String("String"),
 				StringValue: aws.String(uptimeID.String()),
 			},
+			"status": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(string(expectedUptimeNotification.Status)),
+			},
+			"host": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(expectedUptimeNotification.Host),
+			},
 		},
 	}
 }
@@ -74,9 +86,31 @@ func TestPublishUptimeSNSFailure(t *testing.T) {
 	snsClient.On("Publish", mock.Anything).Return(&sns.PublishOutput{}, errors.New("cannot publish to SNS topic"))
 
 	// When
-	err := PublishUptimeStatus(&uptimeNotification, uptimeID.String(), "topic-ARN-1", snsClient)
+	err := PublishUptimeStatus(&uptimeNotification, uptimeID.String(), "topic-ARN-1", &snsClient)
 
 	// Then
 	assert.NotNil(t, err, "Error was expected to be returned")
 	snsClient.AssertExpectations(t)
 }
+
+// Given uptime notification without host
+// When uptime notification is published into SNS topic
+// Then published MSG does not contain empty host attribute
+func TestPublishUptimeWithoutHost(t *testing.T) {
+	// Given
+	uptimeNotification := UptimeNotification{
+		Status: STATUS_FAIL,
+	}
+	snsClient := mockSNSClient{}
+	snsClient.On("Publish", mock.MatchedBy(func(input *sns.PublishInput) bool {
+		_, hasHost := input.MessageAttributes["host"]
+		return !hasHost && *input.MessageAttributes["status"].StringValue == STATUS_FAIL
+	})).Return(&sns.PublishOutput{}, nil)
+
+	// When
+	err := PublishUptimeStatus(&uptimeNotification, "uptime-1", "topic-ARN-1", &snsClient)
+
+	// Then
+	assert.Nil(t, err, "Unexpected error has happened")
+	snsClient.AssertExpectations(t)
+}
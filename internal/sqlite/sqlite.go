@@ -0,0 +1,482 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	_ "modernc.org/sqlite"
+	"monitor-uptime/internal/storage"
+)
+
+// Schema of SQLite database
+// Items are stored as JSON documents, columns are kept only for keys and querying
+const schema = `
+CREATE TABLE IF NOT EXISTS executions (
+	request_id TEXT PRIMARY KEY,
+	uptime_id  TEXT NOT NULL,
+	run_at     INTEGER NOT NULL,
+	item       TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS executions_uptime_id_run_at ON executions (uptime_id, run_at);
+CREATE TABLE IF NOT EXISTS status (
+	uptime_id TEXT PRIMARY KEY,
+	item      TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS incidents (
+	id        TEXT PRIMARY KEY,
+	uptime_id TEXT NOT NULL,
+	opened_at INTEGER NOT NULL,
+	item      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS incidents_uptime_id_opened_at ON incidents (uptime_id, opened_at);
+CREATE TABLE IF NOT EXISTS silences (
+	uptime_id TEXT NOT NULL,
+	id        TEXT NOT NULL,
+	until     INTEGER NOT NULL,
+	item      TEXT NOT NULL,
+	PRIMARY KEY (uptime_id, id)
+);
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	uptime_id TEXT NOT NULL,
+	item      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS delivery_attempts_uptime_id ON delivery_attempts (uptime_id, id);
+`
+
+// Represents storage of uptime monitor results and statuses backed by SQLite database
+type Storage struct {
+	db *sql.DB
+}
+
+// Opens SQLite database stored in file at path and creates its schema if it does not exist yet
+// Path ":memory:" opens database kept in memory only
+// Returns error if database cannot be opened or schema cannot be created
+func NewStorage(path string) (*Storage, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite does not support concurrent writers, in-memory database is moreover private to single connection
+	db.SetMaxOpenConns(1)
+
+	if _, err = db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Storage{db: db}, nil
+}
+
+// Closes underlying SQLite database
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// Store uptime monitor result from single execution
+func (s *Storage) StoreUptimeResult(uptime *storage.UptimeResultItem) error {
+	item, err := json.Marshal(uptime)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		"INSERT OR REPLACE INTO executions (request_id, uptime_id, run_at, item) VALUES (?, ?, ?, ?)",
+		uptime.RequestID, uptime.UptimeID, uptime.RunAt, string(item))
+	return err
+}
+
+// List uptime monitor results of uptime monitor run between from and to timestamps (inclusive), ordered by run time
+func (s *Storage) ListUptimeResults(uptimeID string, from int64, to int64) ([]storage.UptimeResultItem, error) {
+	rows, err := s.db.Query(
+		"SELECT item FROM executions WHERE uptime_id = ? AND run_at BETWEEN ? AND ? ORDER BY run_at",
+		uptimeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []storage.UptimeResultItem
+	for rows.Next() {
+		var item string
+		if err = rows.Scan(&item); err != nil {
+			return nil, err
+		}
+		var result storage.UptimeResultItem
+		if err = json.Unmarshal([]byte(item), &result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// Get uptime status, nil is returned if it does not exist
+func (s *Storage) GetUptimeStatus(uptimeID string) (*storage.UptimeStatusItem, error) {
+	var item string
+	err := s.db.QueryRow("SELECT item FROM status WHERE uptime_id = ?", uptimeID).Scan(&item)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var status storage.UptimeStatusItem
+	if err = json.Unmarshal([]byte(item), &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Record notification of kind sent at timestamp, nothing is recorded if uptime status does not exist
+func (s *Storage) RecordNotification(uptimeID string, kind string, at int64) error {
+	return s.updateStatus(uptimeID, func(status *storage.UptimeStatusItem, exists bool) bool {
+		if !exists {
+			return true
+		}
+		status.RecordNotification(kind, at)
+		return false
+	})
+}
+
+// Count failed run, returns true if FAIL status should be announced
+func (s *Storage) UpdateUptimeStatus(uptimeID string, threshold int) (bool, error) {
+	var notify bool
+	err := s.updateStatus(uptimeID, func(status *storage.UptimeStatusItem, _ bool) bool {
+		notify = status.Fail(threshold)
+		return false
+	})
+	return notify, err
+}
+
+// Count degraded run, returns true if DEGRADED status should be announced
+func (s *Storage) DegradeUptimeStatus(uptimeID string, degradedThreshold int) (bool, error) {
+	var notify bool
+	err := s.updateStatus(uptimeID, func(status *storage.UptimeStatusItem, _ bool) bool {
+		notify = status.Degrade(degradedThreshold)
+		return false
+	})
+	return notify, err
+}
+
+// Count successful run, returns true if OK status should be announced
+// If uptime status does not exist, then there is nothing to recover from, OK state is stored and false is returned.
+func (s *Storage) RecoverUptimeStatus(uptimeID string, recoveryThreshold int) (bool, error) {
+	var notify bool
+	err := s.updateStatus(uptimeID, func(status *storage.UptimeStatusItem, exists bool) bool {
+		notify = status.Recover(recoveryThreshold)
+		return false
+	})
+	return notify, err
+}
+
+// Evaluate flap score of the last window runs, returns FLAPPING if uptime monitor started flapping,
+// announced status (OK, DEGRADED or FAIL) if it is stable again, empty status otherwise
+func (s *Storage) DetectFlapping(uptimeID string, window int, threshold int, stableThreshold int) (string, error) {
+	var announce string
+	err := s.updateStatus(uptimeID, func(status *storage.UptimeStatusItem, exists bool) bool {
+		if !exists {
+			return true
+		}
+		announce = status.DetectFlapping(window, threshold, stableThreshold)
+		return false
+	})
+	return announce, err
+}
+
+// Mark uptime monitor UNREACHABLE_DUE_TO_PARENT because of failing parent, returns true if it has not been marked yet
+func (s *Storage) MarkUnreachable(uptimeID string, parent string) (bool, error) {
+	var marked bool
+	err := s.updateStatus(uptimeID, func(status *storage.UptimeStatusItem, _ bool) bool {
+		marked = status.MarkUnreachable(parent)
+		return false
+	})
+	return marked, err
+}
+
+// Acknowledge announced status of uptime monitor, returns false if no status (FAIL or DEGRADED) has been announced
+func (s *Storage) AcknowledgeUptimeStatus(uptimeID string, by string, note string, at int64) (bool, error) {
+	var acknowledged bool
+	err := s.updateStatus(uptimeID, func(status *storage.UptimeStatusItem, exists bool) bool {
+		if !exists {
+			return true
+		}
+		acknowledged = status.Acknowledge(by, note, at)
+		return false
+	})
+	return acknowledged, err
+}
+
+// Set maintenance window whose start has been announced, empty window ends it, returns true if it has changed
+func (s *Storage) SetMaintenance(uptimeID string, window string) (bool, error) {
+	var changed bool
+	err := s.updateStatus(uptimeID, func(status *storage.UptimeStatusItem, exists bool) bool {
+		changed = status.SetMaintenance(window)
+		return !exists && !changed
+	})
+	return changed, err
+}
+
+// Store incident opened for uptime monitor and link it to uptime status as its open incident
+func (s *Storage) OpenIncident(incident *storage.IncidentItem) error {
+	if err := s.putIncident(s.db, incident); err != nil {
+		return err
+	}
+	return s.updateStatus(incident.UptimeID, func(status *storage.UptimeStatusItem, _ bool) bool {
+		status.IncidentID = incident.ID
+		return false
+	})
+}
+
+// Append event to open incident of uptime monitor, returns false if uptime monitor has no open incident
+func (s *Storage) AddIncidentEvent(uptimeID string, event storage.IncidentEvent) (bool, error) {
+	status, err := s.GetUptimeStatus(uptimeID)
+	if err != nil || status == nil || status.IncidentID == "" {
+		return false, err
+	}
+	return s.updateIncident(status.IncidentID, func(incident *storage.IncidentItem) {
+		incident.Timeline = append(incident.Timeline, event)
+	})
+}
+
+// Close open incident of uptime monitor at timestamp, returns false if uptime monitor has no open incident
+func (s *Storage) CloseIncident(uptimeID string, at int64) (bool, error) {
+	status, err := s.GetUptimeStatus(uptimeID)
+	if err != nil || status == nil || status.IncidentID == "" {
+		return false, err
+	}
+	closed, err := s.updateIncident(status.IncidentID, func(incident *storage.IncidentItem) {
+		incident.Close(at)
+	})
+	if err != nil {
+		return false, err
+	}
+	err = s.updateStatus(uptimeID, func(status *storage.UptimeStatusItem, _ bool) bool {
+		status.IncidentID = ""
+		return false
+	})
+	return closed, err
+}
+
+// Add note to incident, returns false if incident does not exist
+func (s *Storage) AddIncidentNote(id string, note storage.IncidentNote) (bool, error) {
+	return s.updateIncident(id, func(incident *storage.IncidentItem) {
+		incident.Notes = append(incident.Notes, note)
+	})
+}
+
+// Get incident, nil is returned if it does not exist
+func (s *Storage) GetIncident(id string) (*storage.IncidentItem, error) {
+	return s.getIncident(s.db, id)
+}
+
+// List incidents of uptime monitor opened between from and to timestamps (inclusive), ordered by opening time
+func (s *Storage) ListIncidents(uptimeID string, from int64, to int64) ([]storage.IncidentItem, error) {
+	rows, err := s.db.Query(
+		"SELECT item FROM incidents WHERE uptime_id = ? AND opened_at BETWEEN ? AND ? ORDER BY opened_at",
+		uptimeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []storage.IncidentItem
+	for rows.Next() {
+		var item string
+		if err = rows.Scan(&item); err != nil {
+			return nil, err
+		}
+		var incident storage.IncidentItem
+		if err = json.Unmarshal([]byte(item), &incident); err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, rows.Err()
+}
+
+// Store silence, silence with the same ID is replaced
+// Silences without uptime monitor are stored with empty uptime ID
+func (s *Storage) PutSilence(silence *storage.SilenceItem) error {
+	item, err := json.Marshal(silence)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT OR REPLACE INTO silences (uptime_id, id, until, item) VALUES (?, ?, ?, ?)",
+		silence.UptimeID, silence.ID, silence.Until, string(item))
+	return err
+}
+
+// List silences of uptime monitor and silences without uptime monitor, which have not expired at timestamp, ordered by ID
+func (s *Storage) ListSilences(uptimeID string, at int64) ([]storage.SilenceItem, error) {
+	rows, err := s.db.Query("SELECT item FROM silences WHERE uptime_id IN ('', ?) AND until > ? ORDER BY id", uptimeID, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var silences []storage.SilenceItem
+	for rows.Next() {
+		var item string
+		if err = rows.Scan(&item); err != nil {
+			return nil, err
+		}
+		var silence storage.SilenceItem
+		if err = json.Unmarshal([]byte(item), &silence); err != nil {
+			return nil, err
+		}
+		silences = append(silences, silence)
+	}
+	return silences, rows.Err()
+}
+
+// Remove silence of uptime monitor, returns true if it existed
+func (s *Storage) DeleteSilence(uptimeID string, id string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM silences WHERE uptime_id = ? AND id = ?", uptimeID, id)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// Remove silences of uptime monitor which expired at timestamp
+func (s *Storage) PruneSilences(uptimeID string, at int64) error {
+	_, err := s.db.Exec("DELETE FROM silences WHERE uptime_id = ? AND until <= ?", uptimeID, at)
+	return err
+}
+
+// Record delivery attempts of notification of uptime monitor, only the last DELIVERY_HISTORY attempts are kept
+func (s *Storage) RecordDeliveryAttempts(uptimeID string, attempts []storage.DeliveryAttemptItem) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, attempt := range attempts {
+		item, err := json.Marshal(attempt)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec("INSERT INTO delivery_attempts (uptime_id, item) VALUES (?, ?)", uptimeID, string(item)); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("DELETE FROM delivery_attempts WHERE uptime_id = ? AND id NOT IN "+
+		"(SELECT id FROM delivery_attempts WHERE uptime_id = ? ORDER BY id DESC LIMIT ?)",
+		uptimeID, uptimeID, storage.DELIVERY_HISTORY)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// List recorded delivery attempts of uptime monitor in order of their recording
+func (s *Storage) ListDeliveryAttempts(uptimeID string) ([]storage.DeliveryAttemptItem, error) {
+	rows, err := s.db.Query("SELECT item FROM delivery_attempts WHERE uptime_id = ? ORDER BY id", uptimeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []storage.DeliveryAttemptItem
+	for rows.Next() {
+		var item string
+		if err = rows.Scan(&item); err != nil {
+			return nil, err
+		}
+		var attempt storage.DeliveryAttemptItem
+		if err = json.Unmarshal([]byte(item), &attempt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
+// Reads uptime status, applies update and writes it back within single transaction
+// Update receives whether status existed and returns whether status should be deleted instead of written
+func (s *Storage) updateStatus(uptimeID string, update func(status *storage.UptimeStatusItem, exists bool) bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status := storage.UptimeStatusItem{UptimeID: uptimeID}
+	var item string
+	err = tx.QueryRow("SELECT item FROM status WHERE uptime_id = ?", uptimeID).Scan(&item)
+	exists := err == nil
+	if exists {
+		if err = json.Unmarshal([]byte(item), &status); err != nil {
+			return err
+		}
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	if update(&status, exists) {
+		if _, err = tx.Exec("DELETE FROM status WHERE uptime_id = ?", uptimeID); err != nil {
+			return err
+		}
+	} else {
+		encoded, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec("INSERT OR REPLACE INTO status (uptime_id, item) VALUES (?, ?)", uptimeID, string(encoded)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Represents database or transaction which queries are executed by
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Reads incident, nil is returned if it does not exist
+func (s *Storage) getIncident(db queryer, id string) (*storage.IncidentItem, error) {
+	var item string
+	err := db.QueryRow("SELECT item FROM incidents WHERE id = ?", id).Scan(&item)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var incident storage.IncidentItem
+	if err = json.Unmarshal([]byte(item), &incident); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// Writes incident, incident with the same ID is replaced
+func (s *Storage) putIncident(db queryer, incident *storage.IncidentItem) error {
+	item, err := json.Marshal(incident)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("INSERT OR REPLACE INTO incidents (id, uptime_id, opened_at, item) VALUES (?, ?, ?, ?)",
+		incident.ID, incident.UptimeID, incident.OpenedAt, string(item))
+	return err
+}
+
+// Reads incident, applies update and writes it back within single transaction
+// Returns false if incident does not exist
+func (s *Storage) updateIncident(id string, update func(incident *storage.IncidentItem)) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	incident, err := s.getIncident(tx, id)
+	if err != nil || incident == nil {
+		return false, err
+	}
+	update(incident)
+	if err = s.putIncident(tx, incident); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
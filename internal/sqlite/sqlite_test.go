@@ -0,0 +1,391 @@
+package sqlite
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Creates SQLite storage kept in memory
+func newTestStorage(t *testing.T) *Storage {
+	store, err := NewStorage(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// Given uptime results of multiple uptime monitors are stored
+// When uptime results are listed for single uptime monitor and period
+// Then only results of that uptime monitor in that period are returned ordered by run time
+func TestListUptimeResults(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	for _, item := range []storage.UptimeResultItem{
+		{RequestID: "1", UptimeID: "uptime-1", RunAt: 300, StatusCode: 500, Reason: "unexpected status code 500"},
+		{RequestID: "2", UptimeID: "uptime-1", RunAt: 100, StatusCode: 200},
+		{RequestID: "3", UptimeID: "uptime-2", RunAt: 200, StatusCode: 200},
+		{RequestID: "4", UptimeID: "uptime-1", RunAt: 500, StatusCode: 200},
+	} {
+		assert.Nil(t, store.StoreUptimeResult(&item), "Error was not expected to be returned")
+	}
+
+	// When
+	results, err := store.ListUptimeResults("uptime-1", 100, 300)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Len(t, results, 2, "Unexpected number of results")
+	assert.Equal(t, "2", results[0].RequestID, "Unexpected first result")
+	assert.Equal(t, "unexpected status code 500", results[1].Reason, "Unexpected second result")
+}
+
+// Given uptime monitor is failing
+// When failures cross threshold and uptime monitor recovers
+// Then FAIL and then OK status is announced
+//      and uptime monitor is OK
+func TestUptimeStatusFailAndRecover(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+
+	// When
+	first, _ := store.UpdateUptimeStatus("uptime-1", 1)
+	second, _ := store.UpdateUptimeStatus("uptime-1", 1)
+	notRecovered, _ := store.RecoverUptimeStatus("uptime-1", 2)
+	recovered, _ := store.RecoverUptimeStatus("uptime-1", 2)
+	status, _ := store.GetUptimeStatus("uptime-1")
+
+	// Then
+	assert.False(t, first, "FAIL was not expected to be announced")
+	assert.True(t, second, "FAIL was expected to be announced")
+	assert.False(t, notRecovered, "OK was not expected to be announced before recovery threshold")
+	assert.True(t, recovered, "OK was expected to be announced")
+	assert.Equal(t, storage.STATE_OK, status.State, "Uptime monitor was expected to be OK")
+}
+
+// Given uptime status does not exist
+// When uptime status is recovered
+// Then false is returned
+func TestRecoverUptimeStatusMissing(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+
+	// When
+	res, err := store.RecoverUptimeStatus("uptime-1", 1)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.False(t, res, "Result was expected to be false")
+}
+
+// Given uptime status is stored in database file
+// When database file is reopened
+// Then uptime status is preserved
+func TestStoragePersistence(t *testing.T) {
+	// Given
+	dir, _ := os.MkdirTemp("", "uptime-sqlite")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "uptime.db")
+	store, err := NewStorage(path)
+	assert.Nil(t, err, "Error was not expected to be returned")
+	_, _ = store.DegradeUptimeStatus("uptime-1", 1)
+	_ = store.Close()
+
+	// When
+	store, err = NewStorage(path)
+	assert.Nil(t, err, "Error was not expected to be returned")
+	defer store.Close()
+	announced, _ := store.DegradeUptimeStatus("uptime-1", 1)
+
+	// Then
+	assert.False(t, announced, "DEGRADED was not expected to be announced again")
+}
+
+// Given uptime monitor has failed
+// When uptime status is read
+// Then counted failure is returned
+//      and nil is returned for uptime monitor without status
+func TestGetUptimeStatus(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	_, _ = store.UpdateUptimeStatus("uptime-1", 3)
+
+	// When
+	status, err := store.GetUptimeStatus("uptime-1")
+	missing, _ := store.GetUptimeStatus("uptime-2")
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, 1, status.FailCounter, "Unexpected fail counter")
+	assert.Nil(t, missing, "Missing status was expected to be nil")
+}
+
+// Given uptime monitor has announced FAIL status
+// When notifications are recorded
+// Then they are stored in uptime status
+//      and nothing is recorded for uptime monitor without status
+func TestRecordNotification(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	_, _ = store.UpdateUptimeStatus("uptime-1", 0)
+
+	// When
+	err := store.RecordNotification("uptime-1", storage.NOTIFICATION_TRANSITION, 100)
+	_ = store.RecordNotification("uptime-1", storage.NOTIFICATION_ESCALATION, 200)
+	missingErr := store.RecordNotification("uptime-2", storage.NOTIFICATION_REMINDER, 300)
+
+	// Then
+	status, _ := store.GetUptimeStatus("uptime-1")
+	missing, _ := store.GetUptimeStatus("uptime-2")
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Nil(t, missingErr, "Error was not expected to be returned for missing status")
+	assert.Equal(t, int64(100), status.AnnouncedAt, "Unexpected announcement timestamp")
+	assert.Equal(t, int64(200), status.EscalatedAt, "Unexpected escalation timestamp")
+	assert.Nil(t, missing, "Status was not expected to be created")
+}
+
+// Given uptime monitor has announced FAIL status
+//       and other uptime monitor is failing without announced status
+// When uptime statuses are acknowledged
+// Then only announced status is acknowledged
+func TestAcknowledgeUptimeStatus(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	_, _ = store.UpdateUptimeStatus("uptime-1", 0)
+	_, _ = store.UpdateUptimeStatus("uptime-2", 3)
+
+	// When
+	acknowledged, err := store.AcknowledgeUptimeStatus("uptime-1", "alice", "deploying fix", 100)
+	notAcknowledged, _ := store.AcknowledgeUptimeStatus("uptime-2", "alice", "", 100)
+	missing, _ := store.AcknowledgeUptimeStatus("uptime-3", "alice", "", 100)
+
+	// Then
+	status, _ := store.GetUptimeStatus("uptime-1")
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, acknowledged, "Announced status was expected to be acknowledged")
+	assert.False(t, notAcknowledged, "Not announced status was not expected to be acknowledged")
+	assert.False(t, missing, "Missing status was not expected to be acknowledged")
+	assert.Equal(t, "alice", status.AcknowledgedBy, "Unexpected acknowledgement")
+}
+
+// Given uptime monitor alternating failed and successful runs
+// When flapping is detected
+// Then uptime monitor starts flapping with flap score stored in its status
+func TestDetectFlapping(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	for i := 0; i < 2; i++ {
+		_, _ = store.UpdateUptimeStatus("uptime-1", 0)
+		_, _ = store.RecoverUptimeStatus("uptime-1", 1)
+	}
+
+	// When
+	announce, err := store.DetectFlapping("uptime-1", 4, 50, 25)
+	missing, _ := store.DetectFlapping("uptime-2", 4, 50, 25)
+
+	// Then
+	status, _ := store.GetUptimeStatus("uptime-1")
+	notStored, _ := store.GetUptimeStatus("uptime-2")
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, storage.ANNOUNCED_FLAPPING, announce, "FLAPPING was expected to be announced")
+	assert.Empty(t, missing, "Nothing was expected to be announced for missing status")
+	assert.Nil(t, notStored, "Missing status was not expected to be stored")
+	assert.Equal(t, storage.STATE_FLAPPING, status.State, "Unexpected state")
+	assert.Equal(t, 100, status.FlapScore, "Unexpected flap score")
+}
+
+// Given failing uptime monitor
+// When it is marked unreachable due to its parent twice
+// Then it is marked only once
+//      and its counters are kept
+func TestMarkUnreachable(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	_, _ = store.UpdateUptimeStatus("uptime-1", 3)
+
+	// When
+	first, err := store.MarkUnreachable("uptime-1", "gateway")
+	second, _ := store.MarkUnreachable("uptime-1", "gateway")
+
+	// Then
+	status, _ := store.GetUptimeStatus("uptime-1")
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, first, "Uptime monitor was expected to be marked")
+	assert.False(t, second, "Uptime monitor was not expected to be marked again")
+	assert.Equal(t, storage.STATE_UNREACHABLE, status.State, "Unexpected state")
+	assert.Equal(t, "gateway", status.Parent, "Unexpected parent")
+	assert.Equal(t, 1, status.FailCounter, "Fail counter was expected to be kept")
+}
+
+// Given uptime status of failing uptime monitor
+// When maintenance window is set, set again and ended
+// Then only changes of maintenance window are reported
+//      and counters are kept
+func TestSetMaintenance(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	_, _ = store.UpdateUptimeStatus("uptime-1", 3)
+
+	// When
+	started, err := store.SetMaintenance("uptime-1", "deploy")
+	again, _ := store.SetMaintenance("uptime-1", "deploy")
+	status, _ := store.GetUptimeStatus("uptime-1")
+	ended, _ := store.SetMaintenance("uptime-1", "")
+	missing, _ := store.SetMaintenance("uptime-2", "")
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, started, "Start of maintenance window was expected to be reported")
+	assert.False(t, again, "Maintenance window was not expected to change")
+	assert.True(t, ended, "End of maintenance window was expected to be reported")
+	assert.False(t, missing, "Maintenance window of missing status was not expected to change")
+	assert.Equal(t, "deploy", status.Maintenance, "Unexpected maintenance window")
+	assert.Equal(t, 1, status.FailCounter, "Fail counter was expected to be kept")
+}
+
+// Given incident opened for failing uptime monitor
+// When events and note are added, incident is closed and incidents are listed
+// Then incident has timeline of its outage, duration and note
+//      and it is not open anymore
+func TestIncidents(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	_, _ = store.UpdateUptimeStatus("uptime-1", 1)
+	err := store.OpenIncident(storage.NewIncident("incident-1", "uptime-1", 100, 160))
+	assert.Nil(t, err, "Error was not expected to be returned")
+
+	// When
+	added, err := store.AddIncidentEvent("uptime-1", storage.IncidentEvent{Type: storage.EVENT_NOTIFIED, At: 160})
+	missing, _ := store.AddIncidentEvent("uptime-2", storage.IncidentEvent{Type: storage.EVENT_NOTIFIED, At: 160})
+	noted, _ := store.AddIncidentNote("incident-1", storage.IncidentNote{At: 200, By: "alice", Text: "disk full"})
+	open, _ := store.GetIncident("incident-1")
+	closed, _ := store.CloseIncident("uptime-1", 400)
+	again, _ := store.CloseIncident("uptime-1", 500)
+	incidents, _ := store.ListIncidents("uptime-1", 100, 200)
+	outside, _ := store.ListIncidents("uptime-1", 200, 300)
+
+	// Then
+	status, _ := store.GetUptimeStatus("uptime-1")
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.True(t, added, "Event was expected to be added")
+	assert.False(t, missing, "Event was not expected to be added without open incident")
+	assert.True(t, noted, "Note was expected to be added")
+	assert.True(t, closed, "Incident was expected to be closed")
+	assert.False(t, again, "Closed incident was not expected to be closed again")
+	assert.True(t, open.IsOpen(), "Incident was expected to be open before recovery")
+	assert.Len(t, open.Timeline, 3, "Unexpected timeline of open incident")
+	assert.Empty(t, status.IncidentID, "Closed incident was not expected to be linked to status")
+	assert.Len(t, incidents, 1, "Unexpected number of incidents")
+	assert.Empty(t, outside, "Incident opened outside of period was not expected to be listed")
+	incident := incidents[0]
+	assert.False(t, incident.IsOpen(), "Incident was not expected to be open")
+	assert.Equal(t, int64(300), incident.Duration, "Unexpected duration")
+	assert.Equal(t, storage.EVENT_RECOVERED, incident.Timeline[3].Type, "Unexpected last event")
+	assert.Equal(t, "disk full", incident.Notes[0].Text, "Unexpected note")
+}
+
+// Given silences are stored
+// When silences of uptime monitor are listed and removed
+// Then only its silences and silences matching tags only, which have not expired, are listed
+//      and removed silence is not listed anymore
+func TestSilences(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	for _, silence := range []storage.SilenceItem{
+		{ID: "b", Match: map[string]string{"team": "payments"}, Until: 300},
+		{ID: "a", UptimeID: "uptime-1", Until: 200},
+		{ID: "c", UptimeID: "uptime-2", Until: 100},
+	} {
+		assert.Nil(t, store.PutSilence(&silence), "Error was not expected to be returned")
+	}
+
+	// When
+	silences, err := store.ListSilences("uptime-1", 150)
+	other, _ := store.DeleteSilence("", "a")
+	deleted, _ := store.DeleteSilence("uptime-1", "a")
+	remaining, _ := store.ListSilences("uptime-1", 150)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Len(t, silences, 2, "Unexpected number of silences")
+	assert.Equal(t, "a", silences[0].ID, "Unexpected first silence")
+	assert.Equal(t, "payments", silences[1].Match["team"], "Unexpected second silence")
+	assert.False(t, other, "Silence of other uptime monitor was not expected to be removed")
+	assert.True(t, deleted, "Silence was expected to be removed")
+	assert.Len(t, remaining, 1, "Removed silence was not expected to be listed")
+}
+
+// Given expired silence is stored
+// When silences are listed
+//      and pruned
+// Then expired silence is kept by listing
+//      and removed by pruning
+func TestPruneSilences(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	for _, silence := range []storage.SilenceItem{
+		{ID: "a", UptimeID: "uptime-1", Until: 100},
+		{ID: "b", UptimeID: "uptime-1", Until: 300},
+	} {
+		assert.Nil(t, store.PutSilence(&silence), "Error was not expected to be returned")
+	}
+
+	// When
+	_, _ = store.ListSilences("uptime-1", 150)
+	listed, _ := store.ListSilences("uptime-1", 50)
+	err := store.PruneSilences("uptime-1", 150)
+	pruned, _ := store.ListSilences("uptime-1", 50)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Len(t, listed, 2, "Listing was not expected to remove expired silence")
+	assert.Len(t, pruned, 1, "Expired silence was expected to be pruned")
+	assert.Equal(t, "b", pruned[0].ID, "Unexpected remaining silence")
+}
+
+// Given delivery attempts are recorded in several batches
+// When delivery attempts are listed
+// Then attempts are listed in order of their recording
+//      and only the last DELIVERY_HISTORY attempts are kept
+func TestDeliveryAttempts(t *testing.T) {
+	// Given
+	store := newTestStorage(t)
+	defer store.Close()
+	for i := 1; i <= storage.DELIVERY_HISTORY; i++ {
+		err := store.RecordDeliveryAttempts("uptime-1", []storage.DeliveryAttemptItem{
+			{DeliveryID: "delivery", Attempt: i, Error: "timeout"},
+		})
+		assert.Nil(t, err, "Error was not expected to be returned")
+	}
+	err := store.RecordDeliveryAttempts("uptime-1", []storage.DeliveryAttemptItem{
+		{DeliveryID: "last", Attempt: 1, Error: "timeout"},
+		{DeliveryID: "last", Attempt: 2, StatusCode: 200},
+	})
+
+	// When
+	attempts, listErr := store.ListDeliveryAttempts("uptime-1")
+	other, _ := store.ListDeliveryAttempts("uptime-2")
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Nil(t, listErr, "Error was not expected to be returned")
+	assert.Len(t, attempts, storage.DELIVERY_HISTORY, "Only the last attempts were expected to be kept")
+	assert.Equal(t, 3, attempts[0].Attempt, "Oldest attempts were expected to be removed")
+	assert.Equal(t, "last", attempts[len(attempts)-1].DeliveryID, "Unexpected last attempt")
+	assert.Equal(t, 200, attempts[len(attempts)-1].StatusCode, "Unexpected status code of last attempt")
+	assert.Empty(t, other, "Attempts of other uptime monitor were not expected to be listed")
+}
@@ -0,0 +1,628 @@
+package storage
+
+import (
+	"monitor-uptime/internal/tags"
+	"time"
+)
+
+// Uptime statuses announced to subscribers, which are stored in uptime's monitor status
+const (
+	ANNOUNCED_OK       = "OK"
+	ANNOUNCED_FAIL     = "FAIL"
+	ANNOUNCED_DEGRADED = "DEGRADED"
+	ANNOUNCED_FLAPPING = "FLAPPING"
+)
+
+// States of uptime monitor, which are stored in uptime's monitor status
+// Notifications are sent only on transitions which change announced status: OK or FAILING to DOWN (FAIL),
+// any state to DEGRADED and RECOVERING to OK. While uptime monitor is FLAPPING, transitions are not announced.
+const (
+	STATE_UNKNOWN     = "UNKNOWN"                   // No run has been counted yet
+	STATE_OK          = "OK"                        // Uptime monitor is up
+	STATE_FAILING     = "FAILING"                   // Uptime monitor is failing, but fail threshold has not been crossed yet
+	STATE_DOWN        = "DOWN"                      // Fail threshold has been crossed and FAIL has been announced
+	STATE_RECOVERING  = "RECOVERING"                // Uptime monitor is up after FAIL or DEGRADED, but recovery threshold has not been reached yet
+	STATE_DEGRADED    = "DEGRADED"                  // Degraded threshold has been reached and DEGRADED has been announced
+	STATE_FLAPPING    = "FLAPPING"                  // Outcome of runs changes too often, it is FLAPPING until flap score drops
+	STATE_UNREACHABLE = "UNREACHABLE_DUE_TO_PARENT" // Uptime monitor fails while its parent is down, its runs are not counted
+)
+
+// Outcomes of runs recorded in history of uptime's monitor status
+const (
+	RUN_OK       = "O"
+	RUN_DEGRADED = "D"
+	RUN_FAIL     = "F"
+)
+
+// Maximal number of recent runs recorded in history of uptime's monitor status
+const FLAP_HISTORY = 50
+
+// Maximal number of recent delivery attempts of notifications recorded per uptime monitor
+const DELIVERY_HISTORY = 50
+
+// Kinds of notifications recorded in uptime's monitor status
+const (
+	NOTIFICATION_TRANSITION = "transition" // Notification of announced status transition
+	NOTIFICATION_REMINDER   = "reminder"   // Repeated notification of persisting status
+	NOTIFICATION_ESCALATION = "escalation" // Notification of escalation channels
+)
+
+// Types of events recorded in incident timeline
+const (
+	EVENT_FIRST_FAILURE     = "first_failure"     // First failed run of outage
+	EVENT_THRESHOLD_CROSSED = "threshold_crossed" // Fail threshold has been crossed and incident has been opened
+	EVENT_NOTIFIED          = "notified"          // Notification has been sent, message describes it
+	EVENT_ACKNOWLEDGED      = "acknowledged"      // Outage has been acknowledged
+	EVENT_UNREACHABLE       = "unreachable"       // Dependent uptime monitor is unreachable due to outage
+	EVENT_RECOVERED         = "recovered"         // Uptime monitor has recovered and incident has been closed
+)
+
+// Prefix of keys of silences, which are stored together with uptime statuses
+const SILENCE_PREFIX = "silence#"
+
+// Returns key of silences of uptime monitor, silences without uptime monitor (matching by tags only) share
+// tags.ANY_VALUE key
+func SilencesKey(uptimeID string) string {
+	if uptimeID == "" {
+		return SILENCE_PREFIX + tags.ANY_VALUE
+	}
+	return SILENCE_PREFIX + uptimeID
+}
+
+// Prefix of keys of delivery attempts, which are stored together with uptime statuses
+const DELIVERY_PREFIX = "delivery#"
+
+// Represents uptime monitor result that will be stored in storage
+// Item contains all collected data from single uptime monitor run
+type UptimeResultItem struct {
+	RequestID        string                `json:"requestId"` // Uniquely identifies single uptime monitor's run
+	UptimeID         string                `json:"uptimeId"`
+	RunAt            int64                 `json:"runAt"`          // Timestamp when the uptime monitor has been invoked
+	Type             string                `json:"type,omitempty"` // Type of uptime monitor, empty for HTTP
+	Host             string                `json:"host"`
+	StatusCode       int                   `json:"statusCode"`
+	TTFB             int64                 `json:"ttfb"`                          // Resulted Time To First Byte in milliseconds
+	DNSLookup        int64                 `json:"dnslookup"`                     // Resulted duration of DNS lookup in milliseconds
+	TLSHandshake     int64                 `json:"tlshandshake"`                  // Resulted duration of TLS handshake in milliseconds
+	ServerProcessing int64                 `json:"serverProcessing,omitempty"`    // Resulted duration between request has been sent and first response byte in milliseconds
+	ContentTransfer  int64                 `json:"contentTransfer,omitempty"`     // Resulted duration of response body transfer in milliseconds
+	Total            int64                 `json:"total,omitempty"`               // Resulted duration of whole request in milliseconds
+	ResponseSize     int64                 `json:"responseSize,omitempty"`        // Size of response body in bytes
+	ConnReused       bool                  `json:"connReused,omitempty"`          // Whether previously opened connection has been reused
+	PacketsSent      int                   `json:"packetsSent,omitempty"`         // Number of sent ICMP echo requests
+	PacketsRecv      int                   `json:"packetsRecv,omitempty"`         // Number of received ICMP echo replies
+	PacketLoss       float64               `json:"packetLoss,omitempty"`          // Percentage of lost ICMP packets
+	MinRTT           float64               `json:"minRtt,omitempty"`              // Resulted minimal round-trip time in milliseconds
+	AvgRTT           float64               `json:"avgRtt,omitempty"`              // Resulted average round-trip time in milliseconds
+	MaxRTT           float64               `json:"maxRtt,omitempty"`              // Resulted maximal round-trip time in milliseconds
+	StdDevRTT        float64               `json:"stdDevRtt,omitempty"`           // Resulted standard deviation of round-trip times in milliseconds
+	TCPConnect       int64                 `json:"tcpConnect,omitempty"`          // Resulted duration of TCP connect in milliseconds (HTTP and TCP monitors)
+	Response         string                `json:"response,omitempty"`            // Response (banner) received by TCP monitor
+	ResponseMatched  *bool                 `json:"responseMatched,omitempty"`     // Whether response of TCP monitor matched expectations
+	CertSubject      string                `json:"certSubject,omitempty"`         // Subject of TLS leaf certificate
+	CertIssuer       string                `json:"certIssuer,omitempty"`          // Issuer of TLS leaf certificate
+	CertSANs         []string              `json:"certSans,omitempty"`            // Subject alternative names of TLS leaf certificate
+	CertNotAfter     int64                 `json:"certNotAfter,omitempty"`        // Timestamp after which TLS leaf certificate is not valid
+	CertExpiryDays   *int                  `json:"certDaysUntilExpiry,omitempty"` // Number of days until TLS leaf certificate expires
+	CertError        string                `json:"certError,omitempty"`           // Error of TLS certificate chain verification
+	Assertions       []AssertionResultItem `json:"assertions,omitempty"`          // Results of assertions evaluated against HTTP response
+	Reason           string                `json:"reason,omitempty"`              // Reason why run failed, empty if host was up
+	Warning          string                `json:"warning,omitempty"`             // Reason why run was degraded, empty if host was not slow
+	ErrorClass       string                `json:"errorClass,omitempty"`          // Class of probe failure, empty if host has been probed
+	Error            string                `json:"error,omitempty"`               // Message of probe failure, empty if host has been probed
+	Maintenance      bool                  `json:"maintenance,omitempty"`         // Whether run has been executed during maintenance window, it is excluded from fail counting and SLA
+	Parent           string                `json:"parent,omitempty"`              // Failing parent uptime monitor which made host unreachable, run is not counted then
+}
+
+// Represents result of single assertion evaluated against HTTP response
+type AssertionResultItem struct {
+	Type    string `json:"type"`
+	Target  string `json:"target,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"` // Describes why assertion failed
+}
+
+// Represents uptime's monitor status, which tracks state of uptime monitor and consecutive runs towards thresholds
+type UptimeStatusItem struct {
+	UptimeID          string `json:"uptimeId"`
+	State             string `json:"state,omitempty"`   // Current state of uptime monitor, derived from counters for statuses stored before states were tracked
+	Since             int64  `json:"since,omitempty"`   // Timestamp when uptime monitor entered current state
+	Version           int64  `json:"version,omitempty"` // Version of status incremented by every update, used for conditional updates
+	Status            string `json:"status,omitempty"`  // Status announced to subscribers, empty if none has been announced
+	FailCounter       int    `json:"failCounter,omitempty"`
+	Threshold         int    `json:"threshold,omitempty"`
+	SuccessCounter    int    `json:"successCounter,omitempty"`
+	RecoveryThreshold int    `json:"recoveryThreshold,omitempty"`
+	DegradedCounter   int    `json:"degradedCounter,omitempty"`
+	DegradedThreshold int    `json:"degradedThreshold,omitempty"`
+	FailingSince      int64  `json:"failingSince,omitempty"`    // Timestamp of first failed run of current outage, kept until recovery (OK) once FAIL is announced
+	AnnouncedAt       int64  `json:"announcedAt,omitempty"`     // Timestamp when announced status has been notified
+	TransitionAt      int64  `json:"transitionAt,omitempty"`    // Timestamp of the last transition to announce, it is pending until announcedAt reaches it
+	TransitionFrom    string `json:"transitionFrom,omitempty"`  // Status announced before the last transition (OK, DEGRADED, FAIL or FLAPPING)
+	NotifiedAt        int64  `json:"notifiedAt,omitempty"`      // Timestamp of the last notification of announced status, including reminders
+	EscalatedAt       int64  `json:"escalatedAt,omitempty"`     // Timestamp when announced status has been escalated, 0 if it has not been escalated
+	AcknowledgedAt    int64  `json:"acknowledgedAt,omitempty"`  // Timestamp when announced status has been acknowledged, 0 if it has not been acknowledged
+	AcknowledgedBy    string `json:"acknowledgedBy,omitempty"`  // Who acknowledged announced status
+	AcknowledgeNote   string `json:"acknowledgeNote,omitempty"` // Note of acknowledgement, e.g. what is being done
+	Maintenance       string `json:"maintenance,omitempty"`     // Maintenance window whose start has been announced, empty if none
+	History           string `json:"history,omitempty"`         // Outcomes of recent runs (O, D or F), oldest first
+	FlapScore         int    `json:"flapScore,omitempty"`       // Percentage of outcome changes among recent runs
+	Parent            string `json:"parent,omitempty"`          // Failing parent uptime monitor while uptime monitor is UNREACHABLE_DUE_TO_PARENT
+	IncidentID        string `json:"incidentId,omitempty"`      // Open incident of uptime monitor, empty if there is none
+}
+
+// Represents silence suppressing notifications of uptime monitors until it expires
+// Silence matches uptime monitor by its ID (if set) and tags, e.g. all monitors of team during migration
+type SilenceItem struct {
+	ID        string            `json:"id"`
+	UptimeID  string            `json:"uptimeId,omitempty"` // Silenced uptime monitor, any if empty
+	Match     map[string]string `json:"match,omitempty"`    // Tags which uptime monitor must have, "*" matches any value
+	Until     int64             `json:"until"`              // Timestamp when silence expires
+	CreatedBy string            `json:"createdBy,omitempty"`
+	CreatedAt int64             `json:"createdAt"`
+	Comment   string            `json:"comment,omitempty"`
+}
+
+// Represents incident of uptime monitor, which opens when FAIL is announced and closes when uptime monitor recovers
+// Incident accumulates timeline of outage and free-form notes, e.g. its root cause, for post-mortems.
+type IncidentItem struct {
+	ID        string          `json:"id"`
+	UptimeID  string          `json:"uptimeId"`
+	StartedAt int64           `json:"startedAt"`          // Timestamp of the first failed run of outage
+	OpenedAt  int64           `json:"openedAt"`           // Timestamp when fail threshold has been crossed
+	ClosedAt  int64           `json:"closedAt,omitempty"` // Timestamp of recovery, 0 while incident is open
+	Duration  int64           `json:"duration,omitempty"` // Duration of outage in seconds from its first failed run until recovery
+	Timeline  []IncidentEvent `json:"timeline"`           // Events of outage ordered by time
+	Notes     []IncidentNote  `json:"notes,omitempty"`
+}
+
+// Represents single event of incident timeline
+type IncidentEvent struct {
+	Type    string `json:"type"`
+	At      int64  `json:"at"`
+	Message string `json:"message,omitempty"`
+}
+
+// Represents free-form note of incident
+type IncidentNote struct {
+	At   int64  `json:"at"`
+	By   string `json:"by,omitempty"`
+	Text string `json:"text"`
+}
+
+// Represents single attempt to deliver notification of uptime monitor, e.g. to generic webhook
+type DeliveryAttemptItem struct {
+	DeliveryID string `json:"deliveryId"` // ID of delivery shared by all its attempts
+	Channel    string `json:"channel"`    // Type of channel
+	Attempt    int    `json:"attempt"`    // Number of attempt starting with 1
+	At         int64  `json:"at"`
+	StatusCode int    `json:"statusCode,omitempty"` // Status code responded by webhook, 0 if it has not responded or channel does not report it
+	Duration   int64  `json:"duration"`             // Duration of attempt in milliseconds
+	Error      string `json:"error,omitempty"`      // Reason why attempt failed, empty if notification has been delivered
+}
+
+// Returns recorded delivery attempts extended by attempts, only the last DELIVERY_HISTORY attempts are kept
+func AppendDeliveryAttempts(recorded []DeliveryAttemptItem, attempts []DeliveryAttemptItem) []DeliveryAttemptItem {
+	all := append(append([]DeliveryAttemptItem(nil), recorded...), attempts...)
+	if len(all) > DELIVERY_HISTORY {
+		all = all[len(all)-DELIVERY_HISTORY:]
+	}
+	return all
+}
+
+// Represents persistence of uptime monitor results and statuses
+type Storage interface {
+	// Store uptime monitor result from single execution
+	StoreUptimeResult(uptime *UptimeResultItem) error
+	// List uptime monitor results of uptime monitor run between from and to timestamps (inclusive), ordered by run time
+	ListUptimeResults(uptimeID string, from int64, to int64) ([]UptimeResultItem, error)
+	// Get uptime status, nil is returned if it does not exist
+	GetUptimeStatus(uptimeID string) (*UptimeStatusItem, error)
+	// Record notification of kind sent at timestamp, nothing is recorded if uptime status does not exist
+	RecordNotification(uptimeID string, kind string, at int64) error
+	// Count failed run, returns true if FAIL status should be announced
+	UpdateUptimeStatus(uptimeID string, threshold int) (bool, error)
+	// Count degraded run, returns true if DEGRADED status should be announced
+	DegradeUptimeStatus(uptimeID string, degradedThreshold int) (bool, error)
+	// Count successful run, returns true if OK status should be announced
+	// If uptime status does not exist, then there is nothing to recover from, OK state is stored and false is returned
+	RecoverUptimeStatus(uptimeID string, recoveryThreshold int) (bool, error)
+	// Evaluate flap score of the last window runs, returns FLAPPING if uptime monitor started flapping,
+	// announced status (OK, DEGRADED or FAIL) if it is stable again, empty status otherwise
+	DetectFlapping(uptimeID string, window int, threshold int, stableThreshold int) (string, error)
+	// Mark uptime monitor UNREACHABLE_DUE_TO_PARENT because of failing parent, returns true if it has not been marked yet
+	MarkUnreachable(uptimeID string, parent string) (bool, error)
+	// Acknowledge announced status of uptime monitor, returns false if no status (FAIL or DEGRADED) has been announced
+	AcknowledgeUptimeStatus(uptimeID string, by string, note string, at int64) (bool, error)
+	// Set maintenance window whose start has been announced, empty window ends it, returns true if it has changed
+	SetMaintenance(uptimeID string, window string) (bool, error)
+	// Store incident opened for uptime monitor and link it to uptime status as its open incident
+	OpenIncident(incident *IncidentItem) error
+	// Append event to open incident of uptime monitor, returns false if uptime monitor has no open incident
+	AddIncidentEvent(uptimeID string, event IncidentEvent) (bool, error)
+	// Close open incident of uptime monitor at timestamp, returns false if uptime monitor has no open incident
+	CloseIncident(uptimeID string, at int64) (bool, error)
+	// Add note to incident, returns false if incident does not exist
+	AddIncidentNote(id string, note IncidentNote) (bool, error)
+	// Get incident, nil is returned if it does not exist
+	GetIncident(id string) (*IncidentItem, error)
+	// List incidents of uptime monitor opened between from and to timestamps (inclusive), ordered by opening time
+	ListIncidents(uptimeID string, from int64, to int64) ([]IncidentItem, error)
+	// Store silence, silence with the same ID is replaced
+	PutSilence(silence *SilenceItem) error
+	// List silences of uptime monitor and silences without uptime monitor, which have not expired at timestamp,
+	// ordered by ID. Only silences without uptime monitor are listed for empty uptime ID.
+	ListSilences(uptimeID string, at int64) ([]SilenceItem, error)
+	// Remove silence of uptime monitor (empty for silence without uptime monitor), returns true if it existed
+	DeleteSilence(uptimeID string, id string) (bool, error)
+	// Remove silences of uptime monitor (empty for silences without uptime monitor), which expired at timestamp
+	PruneSilences(uptimeID string, at int64) error
+	// Record delivery attempts of notification of uptime monitor, only the last DELIVERY_HISTORY attempts are kept
+	RecordDeliveryAttempts(uptimeID string, attempts []DeliveryAttemptItem) error
+	// List recorded delivery attempts of uptime monitor in order of their recording
+	ListDeliveryAttempts(uptimeID string) ([]DeliveryAttemptItem, error)
+}
+
+// Counts failed run
+// failCounter is incremented and successCounter and degradedCounter are reset, first failed run starts outage.
+// Uptime monitor is FAILING until failCounter crosses threshold, then it is DOWN.
+// Returns true if FAIL status should be announced, i.e. uptime monitor went DOWN and FAIL has not been announced yet.
+func (s *UptimeStatusItem) Fail(threshold int) bool {
+	s.normalize()
+	s.record(RUN_FAIL)
+	now := time.Now().Unix()
+	if s.FailingSince == 0 {
+		s.FailingSince = now
+	}
+	s.Threshold = threshold
+	s.FailCounter++
+	s.SuccessCounter = 0
+	s.DegradedCounter = 0
+	if s.FailCounter > s.Threshold {
+		s.setState(STATE_DOWN, now)
+		return s.announce(ANNOUNCED_FAIL)
+	}
+	s.setState(STATE_FAILING, now)
+	return false
+}
+
+// Counts degraded run
+// degradedCounter is incremented and failCounter and successCounter are reset. Outage is reset only if failures
+// ended without announcing FAIL, otherwise it lasts until uptime monitor recovers (OK).
+// Once degradedCounter reaches degradedThreshold uptime monitor is DEGRADED, until then it is OK,
+// or RECOVERING if FAIL has been announced.
+// Returns true if DEGRADED status should be announced, i.e. it has not been announced yet.
+func (s *UptimeStatusItem) Degrade(degradedThreshold int) bool {
+	s.normalize()
+	s.record(RUN_DEGRADED)
+	now := time.Now().Unix()
+	s.DegradedThreshold = degradedThreshold
+	s.DegradedCounter++
+	if s.FailCounter > 0 && s.Status != ANNOUNCED_FAIL {
+		s.FailingSince = 0
+	}
+	s.FailCounter = 0
+	s.SuccessCounter = 0
+	if s.DegradedCounter >= s.DegradedThreshold {
+		s.setState(STATE_DEGRADED, now)
+		return s.announce(ANNOUNCED_DEGRADED)
+	}
+	switch s.Status {
+	case ANNOUNCED_FAIL:
+		s.setState(STATE_RECOVERING, now)
+	case ANNOUNCED_DEGRADED:
+		s.setState(STATE_DEGRADED, now)
+	default:
+		s.setState(STATE_OK, now)
+	}
+	return false
+}
+
+// Counts successful run
+// If no status has been announced, then counters are reset and uptime monitor is OK silently.
+// Otherwise successCounter is incremented and degradedCounter is reset, uptime monitor is RECOVERING until
+// successCounter reaches recoveryThreshold, then counters are reset and uptime monitor is OK.
+// Returns true if OK status should be announced.
+func (s *UptimeStatusItem) Recover(recoveryThreshold int) bool {
+	s.normalize()
+	s.record(RUN_OK)
+	now := time.Now().Unix()
+	s.RecoveryThreshold = recoveryThreshold
+	if s.Status == "" {
+		s.reset()
+		s.setState(STATE_OK, now)
+		return false
+	}
+	s.SuccessCounter++
+	s.DegradedCounter = 0
+	if s.SuccessCounter >= s.RecoveryThreshold {
+		s.reset()
+		s.setState(STATE_OK, now)
+		return s.announce(ANNOUNCED_OK)
+	}
+	s.setState(STATE_RECOVERING, now)
+	return false
+}
+
+// Evaluates flap score, i.e. percentage of outcome changes among the last window runs
+// Flap score is evaluated only once window runs have been recorded. Uptime monitor starts FLAPPING once flap score
+// reaches threshold, and it is stable again once flap score drops below stableThreshold, then its state is derived
+// from counters. Returns status which should be announced: FLAPPING when uptime monitor starts flapping,
+// announced status (OK, DEGRADED or FAIL) when it is stable again, empty otherwise.
+func (s *UptimeStatusItem) DetectFlapping(window int, threshold int, stableThreshold int) string {
+	s.normalize()
+	if window > FLAP_HISTORY {
+		window = FLAP_HISTORY
+	}
+	s.FlapScore = 0
+	if window > 1 && len(s.History) >= window {
+		s.FlapScore = flapScore(s.History[len(s.History)-window:])
+	}
+
+	now := time.Now().Unix()
+	switch {
+	case s.State != STATE_FLAPPING && s.FlapScore > 0 && s.FlapScore >= threshold:
+		s.transition(s.AnnouncedStatus(), now)
+		s.State = STATE_FLAPPING
+		s.Since = now
+		return ANNOUNCED_FLAPPING
+	case s.State == STATE_FLAPPING && s.FlapScore < stableThreshold:
+		s.transition(ANNOUNCED_FLAPPING, now)
+		s.State = s.stableState()
+		s.Since = now
+		return s.AnnouncedStatus()
+	}
+	return ""
+}
+
+// Marks uptime monitor UNREACHABLE_DUE_TO_PARENT because of failing parent
+// Counters and announced status are kept, so counting continues once parent is up again.
+// Returns true if uptime monitor has not been unreachable due to parent yet, i.e. it should be folded into parent's outage.
+func (s *UptimeStatusItem) MarkUnreachable(parent string) bool {
+	s.normalize()
+	if s.State == STATE_UNREACHABLE && s.Parent == parent {
+		return false
+	}
+	s.State = STATE_UNREACHABLE
+	s.Since = time.Now().Unix()
+	s.Parent = parent
+	return true
+}
+
+// Returns uptime ID of monitor whose outage makes children of this parent unreachable, empty if parent is not down
+// Parent is down if FAIL has been announced for it, it stays down while recovering. Parent which is unreachable
+// due to its own parent is represented by its failing ancestor, so failures are folded into the root outage.
+// Parent without status has not run yet, it is not down.
+func (s *UptimeStatusItem) OutageRoot() string {
+	switch {
+	case s == nil:
+		return ""
+	case s.CurrentState() == STATE_UNREACHABLE && s.Parent != "":
+		return s.Parent
+	case s.AnnouncedStatus() == ANNOUNCED_FAIL:
+		return s.UptimeID
+	}
+	return ""
+}
+
+// Records notification of kind sent at timestamp
+// Notification of transition starts announced status, which is not escalated yet
+func (s *UptimeStatusItem) RecordNotification(kind string, at int64) {
+	switch kind {
+	case NOTIFICATION_TRANSITION:
+		s.AnnouncedAt = at
+		s.NotifiedAt = at
+		s.EscalatedAt = 0
+	case NOTIFICATION_REMINDER:
+		s.NotifiedAt = at
+	case NOTIFICATION_ESCALATION:
+		s.EscalatedAt = at
+	}
+}
+
+// Acknowledges announced status at timestamp, acknowledgement lasts until announced status changes
+// Returns false if no status (FAIL or DEGRADED) has been announced, there is nothing to acknowledge then
+func (s *UptimeStatusItem) Acknowledge(by string, note string, at int64) bool {
+	if !s.IsAnnounced() {
+		return false
+	}
+	s.AcknowledgedAt = at
+	s.AcknowledgedBy = by
+	s.AcknowledgeNote = note
+	return true
+}
+
+// Sets maintenance window whose start has been announced, empty window ends maintenance
+// Returns true if maintenance window has changed, i.e. its start or end should be announced
+func (s *UptimeStatusItem) SetMaintenance(window string) bool {
+	changed := s.Maintenance != window
+	s.Maintenance = window
+	return changed
+}
+
+// Returns true if announced status has been acknowledged
+func (s *UptimeStatusItem) IsAcknowledged() bool {
+	return s != nil && s.AcknowledgedAt != 0
+}
+
+// Returns current state of uptime monitor, UNKNOWN if uptime status does not exist
+// State of statuses stored before states were tracked is derived from announced status and counters
+func (s *UptimeStatusItem) CurrentState() string {
+	switch {
+	case s == nil:
+		return STATE_UNKNOWN
+	case s.State != "":
+		return s.State
+	}
+	switch s.AnnouncedStatus() {
+	case ANNOUNCED_FAIL:
+		if s.SuccessCounter > 0 {
+			return STATE_RECOVERING
+		}
+		return STATE_DOWN
+	case ANNOUNCED_DEGRADED:
+		return STATE_DEGRADED
+	}
+	if s.FailCounter > 0 {
+		return STATE_FAILING
+	}
+	return STATE_UNKNOWN
+}
+
+// Returns status announced to subscribers, OK if no status has been announced
+// Statuses stored before announced status was tracked are considered FAIL once threshold is crossed
+func (s *UptimeStatusItem) AnnouncedStatus() string {
+	if s == nil || !s.IsAnnounced() {
+		return ANNOUNCED_OK
+	}
+	if s.Status == "" {
+		return ANNOUNCED_FAIL
+	}
+	return s.Status
+}
+
+// Returns status whose announcement is pending, empty if there is none
+// Transition is pending until its notification is recorded, e.g. when notification failed or uptime monitor was
+// silenced, so it is announced by later runs. FLAPPING is pending while uptime monitor is still flapping.
+func (s *UptimeStatusItem) PendingAnnouncement() string {
+	if s == nil || s.TransitionAt == 0 || s.AnnouncedAt >= s.TransitionAt {
+		return ""
+	}
+	if s.CurrentState() == STATE_FLAPPING {
+		return ANNOUNCED_FLAPPING
+	}
+	return s.AnnouncedStatus()
+}
+
+// Returns true if any status has been announced to subscribers
+// Statuses stored before announced status was tracked rely on crossed threshold only
+func (s *UptimeStatusItem) IsAnnounced() bool {
+	return s.Status != "" || s.FailCounter > s.Threshold
+}
+
+// Stores state and announced status of statuses stored before they were tracked
+func (s *UptimeStatusItem) normalize() {
+	s.State = s.CurrentState()
+	if s.IsAnnounced() {
+		s.Status = s.AnnouncedStatus()
+	}
+}
+
+// Moves uptime monitor into state, since timestamp is changed only if state changes
+// FLAPPING uptime monitor stays FLAPPING until it is stable again.
+func (s *UptimeStatusItem) setState(state string, now int64) {
+	if s.State != state && s.State != STATE_FLAPPING {
+		s.State = state
+		s.Since = now
+		s.Parent = ""
+	}
+}
+
+// Stores announced status, returns true if it differs from previously announced status
+// OK status is stored as empty status, status is expected to be normalized. Change of announced status resets acknowledgement.
+// Status of FLAPPING uptime monitor is stored, but it is announced only once uptime monitor is stable again.
+func (s *UptimeStatusItem) announce(status string) bool {
+	if status == ANNOUNCED_OK {
+		status = ""
+	}
+	from := s.Status
+	if from == "" {
+		from = ANNOUNCED_OK
+	}
+	changed := s.Status != status
+	s.Status = status
+	if changed {
+		s.AcknowledgedAt = 0
+		s.AcknowledgedBy = ""
+		s.AcknowledgeNote = ""
+	}
+	if changed && s.State != STATE_FLAPPING {
+		s.transition(from, time.Now().Unix())
+		return true
+	}
+	return false
+}
+
+// Marks transition from announced status at timestamp, it is pending until its notification is recorded
+func (s *UptimeStatusItem) transition(from string, now int64) {
+	s.TransitionFrom = from
+	s.TransitionAt = now
+}
+
+// Returns state of uptime monitor which is stable again after flapping, it is derived from counters and announced status
+func (s *UptimeStatusItem) stableState() string {
+	switch {
+	case s.FailCounter > s.Threshold:
+		return STATE_DOWN
+	case s.FailCounter > 0:
+		return STATE_FAILING
+	case s.DegradedCounter > 0 && (s.DegradedCounter >= s.DegradedThreshold || s.Status == ANNOUNCED_DEGRADED):
+		return STATE_DEGRADED
+	case s.Status != "":
+		return STATE_RECOVERING
+	}
+	return STATE_OK
+}
+
+// Records outcome of run in history, only the last FLAP_HISTORY outcomes are kept
+func (s *UptimeStatusItem) record(outcome string) {
+	s.History += outcome
+	if len(s.History) > FLAP_HISTORY {
+		s.History = s.History[len(s.History)-FLAP_HISTORY:]
+	}
+}
+
+// Returns percentage of changes between consecutive outcomes of history
+func flapScore(history string) int {
+	changes := 0
+	for i := 1; i < len(history); i++ {
+		if history[i] != history[i-1] {
+			changes++
+		}
+	}
+	return changes * 100 / (len(history) - 1)
+}
+
+// Resets counters and outage
+func (s *UptimeStatusItem) reset() {
+	s.FailCounter = 0
+	s.SuccessCounter = 0
+	s.DegradedCounter = 0
+	s.FailingSince = 0
+}
+
+// Returns true if silence has not expired at timestamp and it matches uptime monitor with tags
+// Tag values are compared ignoring case, like by routes and maintenance windows.
+func (s *SilenceItem) Matches(uptimeID string, monitorTags map[string]string, at int64) bool {
+	if at >= s.Until || (s.UptimeID != "" && s.UptimeID != uptimeID) {
+		return false
+	}
+	return tags.Matches(s.Match, monitorTags)
+}
+
+// Creates incident of uptime monitor opened at timestamp, its timeline starts by the first failed run and crossed threshold
+func NewIncident(id string, uptimeID string, startedAt int64, openedAt int64) *IncidentItem {
+	return &IncidentItem{
+		ID:        id,
+		UptimeID:  uptimeID,
+		StartedAt: startedAt,
+		OpenedAt:  openedAt,
+		Timeline: []IncidentEvent{
+			{Type: EVENT_FIRST_FAILURE, At: startedAt},
+			{Type: EVENT_THRESHOLD_CROSSED, At: openedAt},
+		},
+	}
+}
+
+// Closes incident at timestamp of recovery, duration of outage is measured from its first failed run
+func (i *IncidentItem) Close(at int64) {
+	i.ClosedAt = at
+	i.Duration = at - i.StartedAt
+	i.Timeline = append(i.Timeline, IncidentEvent{Type: EVENT_RECOVERED, At: at})
+}
+
+// Returns true if incident has not been closed yet
+func (i *IncidentItem) IsOpen() bool {
+	return i.ClosedAt == 0
+}
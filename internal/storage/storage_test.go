@@ -0,0 +1,408 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/tags"
+	"testing"
+)
+
+// Given uptime status
+// When failed runs are counted
+// Then FAIL is announced only once threshold is crossed
+func TestUptimeStatusFail(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId"}
+
+	// When
+	announced := []bool{status.Fail(2), status.Fail(2), status.Fail(2), status.Fail(2)}
+
+	// Then
+	assert.Equal(t, []bool{false, false, true, false}, announced, "Unexpected announcements")
+	assert.Equal(t, ANNOUNCED_FAIL, status.Status, "Unexpected announced status")
+	assert.NotZero(t, status.FailingSince, "Outage was expected to start")
+}
+
+// Given uptime status
+// When FAIL is announced
+//      and its notification is recorded
+// Then FAIL is pending from OK until its notification is recorded
+func TestUptimeStatusPendingAnnouncement(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId"}
+
+	// When
+	status.Fail(0)
+	pending := status.PendingAnnouncement()
+	status.Fail(0)
+	stillPending := status.PendingAnnouncement()
+	status.RecordNotification(NOTIFICATION_TRANSITION, status.TransitionAt)
+
+	// Then
+	assert.Equal(t, ANNOUNCED_FAIL, pending, "FAIL was expected to be pending")
+	assert.Equal(t, ANNOUNCED_FAIL, stillPending, "FAIL was expected to be pending until it is notified")
+	assert.Equal(t, ANNOUNCED_OK, status.TransitionFrom, "Unexpected status announced before transition")
+	assert.Empty(t, status.PendingAnnouncement(), "Notified FAIL was not expected to be pending")
+	assert.Empty(t, (*UptimeStatusItem)(nil).PendingAnnouncement(), "Missing status was not expected to be pending")
+}
+
+// Given uptime status
+// When degraded runs are counted
+// Then DEGRADED is announced only once threshold is reached
+func TestUptimeStatusDegrade(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId", FailCounter: 1}
+
+	// When
+	announced := []bool{status.Degrade(2), status.Degrade(2), status.Degrade(2)}
+
+	// Then
+	assert.Equal(t, []bool{false, true, false}, announced, "Unexpected announcements")
+	assert.Equal(t, 0, status.FailCounter, "Fail counter was expected to be reset")
+	assert.Zero(t, status.FailingSince, "Outage was expected to be reset")
+}
+
+// Given uptime status with announced FAIL status
+// When it degrades
+//      and recovers
+// Then outage lasts while uptime monitor is DEGRADED
+//      and it is reset once OK is announced
+func TestUptimeStatusDegradeAfterFail(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId", State: STATE_DOWN, Status: ANNOUNCED_FAIL, FailCounter: 2, Threshold: 1, FailingSince: 100}
+
+	// When
+	degraded := status.Degrade(1)
+	degradedSince := status.FailingSince
+	status.Degrade(1)
+	stillDegradedSince := status.FailingSince
+	recovered := status.Recover(1)
+
+	// Then
+	assert.True(t, degraded, "DEGRADED was expected to be announced")
+	assert.Equal(t, int64(100), degradedSince, "Outage was expected to last after DEGRADED announcement")
+	assert.Equal(t, int64(100), stillDegradedSince, "Outage was expected to last while uptime monitor is DEGRADED")
+	assert.True(t, recovered, "OK was expected to be announced")
+	assert.Zero(t, status.FailingSince, "Outage was expected to be reset on recovery")
+}
+
+// Given uptime status without announced status
+// When successful run is counted
+// Then uptime monitor is OK silently
+func TestUptimeStatusRecoverNotAnnounced(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId", State: STATE_FAILING, FailCounter: 1, Threshold: 3}
+
+	// When
+	notify := status.Recover(2)
+
+	// Then
+	assert.False(t, notify, "OK was not expected to be announced")
+	assert.Equal(t, STATE_OK, status.State, "Unexpected state")
+	assert.Equal(t, 0, status.FailCounter, "Fail counter was expected to be reset")
+}
+
+// Given uptime status with announced FAIL status
+// When successful runs are counted
+// Then uptime monitor is RECOVERING
+//      and OK is announced once recovery threshold is reached
+func TestUptimeStatusRecoverAnnounced(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId", State: STATE_DOWN, Status: ANNOUNCED_FAIL}
+
+	// When
+	first := status.Recover(2)
+	firstState := status.State
+	second := status.Recover(2)
+
+	// Then
+	assert.False(t, first, "OK was not expected to be announced after first success")
+	assert.Equal(t, STATE_RECOVERING, firstState, "Uptime monitor was expected to be recovering")
+	assert.True(t, second, "OK was expected to be announced after second success")
+	assert.Equal(t, STATE_OK, status.State, "Uptime monitor was expected to be OK")
+	assert.Equal(t, ANNOUNCED_OK, status.AnnouncedStatus(), "Unexpected announced status")
+}
+
+// Given uptime monitor
+// When it fails, goes down, keeps failing, degrades, fails and recovers
+// Then FAIL, DEGRADED and OK are announced only on transitions
+//      and state and its since timestamp follow runs
+func TestUptimeStatusStateMachine(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId"}
+	var states []string
+	var announced []bool
+	record := func(notify bool) {
+		states = append(states, status.State)
+		announced = append(announced, notify)
+	}
+
+	// When
+	record(status.Fail(1))
+	record(status.Fail(1))
+	status.Since = 1
+	record(status.Fail(1))
+	downSince := status.Since
+	record(status.Degrade(1))
+	record(status.Fail(1))
+	record(status.Recover(2))
+	record(status.Recover(2))
+
+	// Then
+	assert.Equal(t, []string{STATE_FAILING, STATE_DOWN, STATE_DOWN, STATE_DEGRADED, STATE_FAILING, STATE_RECOVERING, STATE_OK},
+		states, "Unexpected states")
+	assert.Equal(t, []bool{false, true, false, true, false, false, true}, announced, "Unexpected announcements")
+	assert.Equal(t, int64(1), downSince, "Since was not expected to change while DOWN")
+	assert.NotEqual(t, int64(1), status.Since, "Since was expected to change with state")
+}
+
+// Given uptime monitor
+// When it alternates failed and successful runs
+//      and then it is up
+// Then it starts FLAPPING once flap score reaches threshold
+//      and transitions are not announced until it is stable again
+func TestUptimeStatusDetectFlapping(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId"}
+	var announced []bool
+	var flapping []string
+	run := func(notify bool) {
+		announced = append(announced, notify)
+		flapping = append(flapping, status.DetectFlapping(4, 50, 25))
+	}
+
+	// When
+	run(status.Fail(0))
+	run(status.Recover(1))
+	run(status.Fail(0))
+	run(status.Recover(1))
+	flappingScore := status.FlapScore
+	run(status.Fail(0))
+	flappingState := status.State
+	for i := 0; i < 4; i++ {
+		run(status.Recover(1))
+	}
+
+	// Then
+	assert.Equal(t, []bool{true, true, true, true, false, false, false, false, false}, announced, "Unexpected announcements")
+	assert.Equal(t, []string{"", "", "", ANNOUNCED_FLAPPING, "", "", "", "", ANNOUNCED_OK}, flapping, "Unexpected flapping announcements")
+	assert.Equal(t, 100, flappingScore, "Unexpected flap score")
+	assert.Equal(t, STATE_FLAPPING, flappingState, "Uptime monitor was expected to stay flapping")
+	assert.Equal(t, STATE_OK, status.State, "Uptime monitor was expected to be stable")
+	assert.Equal(t, 0, status.FlapScore, "Unexpected flap score of stable uptime monitor")
+	assert.Equal(t, "FOFOFOOOO", status.History, "Unexpected history")
+}
+
+// Given failing uptime monitor
+// When it is marked unreachable due to its parent twice
+//      and it fails again once parent is up
+// Then it is folded into parent's outage only once
+//      and counting continues from kept counters
+func TestUptimeStatusMarkUnreachable(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId"}
+	status.Fail(1)
+
+	// When
+	first := status.MarkUnreachable("gateway")
+	second := status.MarkUnreachable("gateway")
+	unreachableState := status.State
+	announced := status.Fail(1)
+
+	// Then
+	assert.True(t, first, "Uptime monitor was expected to be folded into parent's outage")
+	assert.False(t, second, "Uptime monitor was not expected to be folded again")
+	assert.Equal(t, STATE_UNREACHABLE, unreachableState, "Unexpected state")
+	assert.True(t, announced, "FAIL was expected to be announced once parent is up")
+	assert.Equal(t, STATE_DOWN, status.State, "Unexpected state")
+	assert.Empty(t, status.Parent, "Parent was expected to be reset")
+}
+
+// Given parent uptime monitors in various states
+// When root of outage making their children unreachable is evaluated
+// Then DOWN parent is the root
+//      and unreachable parent is represented by its failing ancestor
+//      and parent without status, parent which is only failing and recovered parent are not down
+func TestUptimeStatusOutageRoot(t *testing.T) {
+	// Given
+	down := &UptimeStatusItem{UptimeID: "gateway"}
+	down.Fail(0)
+	unreachable := &UptimeStatusItem{UptimeID: "database"}
+	unreachable.Fail(0)
+	unreachable.MarkUnreachable("gateway")
+	failing := &UptimeStatusItem{UptimeID: "cache"}
+	failing.Fail(1)
+	recovered := &UptimeStatusItem{UptimeID: "dns"}
+	recovered.Fail(0)
+	recovered.Recover(1)
+	var missing *UptimeStatusItem
+
+	// Then
+	assert.Equal(t, "gateway", down.OutageRoot(), "DOWN parent was expected to be the root")
+	assert.Equal(t, "gateway", unreachable.OutageRoot(), "Failing ancestor was expected to be the root")
+	assert.Empty(t, failing.OutageRoot(), "Parent was not expected to be down before FAIL is announced")
+	assert.Empty(t, recovered.OutageRoot(), "Recovered parent was not expected to be down")
+	assert.Empty(t, missing.OutageRoot(), "Parent without status was not expected to be down")
+}
+
+// Given uptime monitor unreachable due to its DOWN parent
+// When parent recovers
+//      and uptime monitor succeeds
+// Then uptime monitor is OK without parent
+//      and its outage is not announced
+func TestUptimeStatusRecoverAfterParent(t *testing.T) {
+	// Given
+	parent := &UptimeStatusItem{UptimeID: "gateway"}
+	parent.Fail(0)
+	child := &UptimeStatusItem{UptimeID: "anyUptimeId"}
+	child.Fail(1)
+	child.MarkUnreachable(parent.OutageRoot())
+
+	// When
+	parent.Recover(1)
+	announced := child.Recover(1)
+
+	// Then
+	assert.Empty(t, parent.OutageRoot(), "Parent was not expected to be down")
+	assert.False(t, announced, "OK was not expected to be announced")
+	assert.Equal(t, STATE_OK, child.State, "Unexpected state")
+	assert.Empty(t, child.Parent, "Parent was expected to be reset")
+}
+
+// Given statuses stored before states were tracked
+// When current state is read
+// Then it is derived from announced status and counters
+func TestUptimeStatusCurrentStateLegacy(t *testing.T) {
+	// Given
+	var missing *UptimeStatusItem
+	failing := &UptimeStatusItem{FailCounter: 1, Threshold: 2}
+	down := &UptimeStatusItem{FailCounter: 3, Threshold: 2}
+	recovering := &UptimeStatusItem{FailCounter: 3, Threshold: 2, SuccessCounter: 1}
+	degraded := &UptimeStatusItem{Status: ANNOUNCED_DEGRADED}
+
+	// Then
+	assert.Equal(t, STATE_UNKNOWN, missing.CurrentState(), "Missing status was expected to be UNKNOWN")
+	assert.Equal(t, STATE_FAILING, failing.CurrentState(), "Unexpected state")
+	assert.Equal(t, STATE_DOWN, down.CurrentState(), "Unexpected state")
+	assert.Equal(t, STATE_RECOVERING, recovering.CurrentState(), "Unexpected state")
+	assert.Equal(t, STATE_DEGRADED, degraded.CurrentState(), "Unexpected state")
+}
+
+// Given legacy status with crossed threshold, but without announced status
+// When failed run is counted
+// Then FAIL is not announced again
+func TestUptimeStatusFailLegacyAnnounced(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId", FailCounter: 3, Threshold: 2}
+
+	// When
+	notify := status.Fail(2)
+
+	// Then
+	assert.False(t, notify, "FAIL was not expected to be announced again")
+	assert.Equal(t, STATE_DOWN, status.State, "Unexpected state")
+	assert.Equal(t, ANNOUNCED_FAIL, status.Status, "Announced status was expected to be stored")
+}
+
+// Given uptime statuses in different stages
+// When announced status is read
+// Then OK is returned until any status is announced
+func TestUptimeStatusAnnouncedStatus(t *testing.T) {
+	// Given
+	var missing *UptimeStatusItem
+	failing := &UptimeStatusItem{FailCounter: 1, Threshold: 2}
+	legacy := &UptimeStatusItem{FailCounter: 3, Threshold: 2}
+	degraded := &UptimeStatusItem{Status: ANNOUNCED_DEGRADED}
+
+	// Then
+	assert.Equal(t, ANNOUNCED_OK, missing.AnnouncedStatus(), "Missing status was expected to be OK")
+	assert.Equal(t, ANNOUNCED_OK, failing.AnnouncedStatus(), "Not announced status was expected to be OK")
+	assert.Equal(t, ANNOUNCED_FAIL, legacy.AnnouncedStatus(), "Crossed threshold was expected to be FAIL")
+	assert.Equal(t, ANNOUNCED_DEGRADED, degraded.AnnouncedStatus(), "Unexpected announced status")
+}
+
+// Given uptime status with announced FAIL status
+// When transition, reminder and escalation notifications are recorded
+// Then their timestamps are stored
+//      and transition starts new announced status which is not escalated
+func TestUptimeStatusRecordNotification(t *testing.T) {
+	// Given
+	status := UptimeStatusItem{UptimeID: "anyUptimeId", Status: ANNOUNCED_FAIL}
+
+	// When
+	status.RecordNotification(NOTIFICATION_TRANSITION, 100)
+	status.RecordNotification(NOTIFICATION_REMINDER, 200)
+	status.RecordNotification(NOTIFICATION_ESCALATION, 300)
+	escalated := status
+	status.RecordNotification(NOTIFICATION_TRANSITION, 400)
+
+	// Then
+	assert.Equal(t, int64(100), escalated.AnnouncedAt, "Unexpected announcement timestamp")
+	assert.Equal(t, int64(200), escalated.NotifiedAt, "Unexpected notification timestamp")
+	assert.Equal(t, int64(300), escalated.EscalatedAt, "Unexpected escalation timestamp")
+	assert.Equal(t, int64(400), status.NotifiedAt, "Unexpected notification timestamp after transition")
+	assert.Zero(t, status.EscalatedAt, "Escalation was expected to be reset by transition")
+}
+
+// Given uptime status with announced FAIL status
+//       and uptime status without announced status
+// When they are acknowledged
+// Then only announced status is acknowledged until announced status changes
+func TestUptimeStatusAcknowledge(t *testing.T) {
+	// Given
+	announced := UptimeStatusItem{UptimeID: "anyUptimeId", State: STATE_DOWN, Status: ANNOUNCED_FAIL}
+	failing := UptimeStatusItem{UptimeID: "anyUptimeId", State: STATE_FAILING, FailCounter: 1, Threshold: 3}
+
+	// When
+	acknowledged := announced.Acknowledge("alice", "deploying fix", 100)
+	notAcknowledged := failing.Acknowledge("alice", "", 100)
+	stillAcknowledged := announced.IsAcknowledged()
+	announced.Recover(1)
+
+	// Then
+	assert.True(t, acknowledged, "Announced status was expected to be acknowledged")
+	assert.False(t, notAcknowledged, "Not announced status was not expected to be acknowledged")
+	assert.True(t, stillAcknowledged, "Acknowledgement was expected to last")
+	assert.False(t, announced.IsAcknowledged(), "Acknowledgement was expected to be reset by recovery")
+	assert.Empty(t, announced.AcknowledgedBy, "Acknowledgement was expected to be reset by recovery")
+}
+
+// Given silences of uptime monitor and of tags
+// When they are matched against uptime monitors
+// Then they match only uptime monitors with matching ID and tags before they expire
+//      and tag values are compared ignoring case
+func TestSilenceMatches(t *testing.T) {
+	// Given
+	monitor := SilenceItem{ID: "1", UptimeID: "uptime-1", Until: 200}
+	team := SilenceItem{ID: "2", Match: map[string]string{"team": "payments", "env": tags.ANY_VALUE}, Until: 200}
+	tags := map[string]string{"team": "payments", "env": "prod"}
+
+	// Then
+	assert.True(t, monitor.Matches("uptime-1", nil, 100), "Silence of uptime monitor was expected to match")
+	assert.False(t, monitor.Matches("uptime-2", nil, 100), "Silence of other uptime monitor was not expected to match")
+	assert.False(t, monitor.Matches("uptime-1", nil, 200), "Expired silence was not expected to match")
+	assert.True(t, team.Matches("uptime-2", tags, 100), "Silence of tags was expected to match")
+	assert.False(t, team.Matches("uptime-2", map[string]string{"team": "payments"}, 100), "Silence was not expected to match without tag")
+	assert.False(t, team.Matches("uptime-2", map[string]string{"team": "search", "env": "prod"}, 100), "Silence was not expected to match other team")
+	assert.True(t, team.Matches("uptime-2", map[string]string{"team": "Payments", "env": "prod"}, 100), "Silence was expected to match tag value ignoring case")
+}
+
+// Given incident opened once fail threshold has been crossed
+// When incident is closed at recovery
+// Then its timeline starts by the first failed run and ends by recovery
+//      and its duration is measured from the first failed run
+func TestIncidentClose(t *testing.T) {
+	// Given
+	incident := NewIncident("incident-1", "uptime-1", 100, 160)
+	open := incident.IsOpen()
+
+	// When
+	incident.Close(400)
+
+	// Then
+	assert.True(t, open, "New incident was expected to be open")
+	assert.False(t, incident.IsOpen(), "Closed incident was not expected to be open")
+	assert.Equal(t, int64(300), incident.Duration, "Unexpected duration")
+	assert.Equal(t, []string{EVENT_FIRST_FAILURE, EVENT_THRESHOLD_CROSSED, EVENT_RECOVERED},
+		[]string{incident.Timeline[0].Type, incident.Timeline[1].Type, incident.Timeline[2].Type}, "Unexpected timeline")
+	assert.Equal(t, int64(100), incident.Timeline[0].At, "Unexpected time of the first failure")
+}
@@ -0,0 +1,30 @@
+package tags
+
+import (
+	"os"
+	"strings"
+)
+
+// Tag matching any value, matcher matches only if tag is present
+const ANY_VALUE = "*"
+
+// Returns true if tags of uptime monitor contain all matched tags
+// Tag values are compared ignoring case, ANY_VALUE matches any value of present tag.
+func Matches(match map[string]string, tags map[string]string) bool {
+	for key, expected := range match {
+		value, ok := tags[key]
+		if !ok || (expected != ANY_VALUE && !strings.EqualFold(value, expected)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reads JSON configuration (e.g. of routing or maintenance windows) from file
+// Value starting with { is returned as inline JSON configuration instead
+func ReadConfig(pathOrJSON string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(pathOrJSON), "{") {
+		return []byte(pathOrJSON), nil
+	}
+	return os.ReadFile(pathOrJSON)
+}
@@ -0,0 +1,46 @@
+package tags
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Given matched tags with exact and any value
+// When they are matched against tags of uptime monitors
+// Then they match only tags which contain all of them
+//      and values are compared ignoring case
+func TestMatches(t *testing.T) {
+	// Given
+	match := map[string]string{"team": "payments", "env": ANY_VALUE}
+
+	// Then
+	assert.True(t, Matches(match, map[string]string{"team": "payments", "env": "prod", "tier": "1"}), "Tags were expected to match")
+	assert.True(t, Matches(match, map[string]string{"team": "Payments", "env": "prod"}), "Tag value was expected to match ignoring case")
+	assert.False(t, Matches(match, map[string]string{"team": "payments"}), "Tags were not expected to match without tag")
+	assert.False(t, Matches(match, map[string]string{"team": "search", "env": "prod"}), "Tags were not expected to match other value")
+	assert.True(t, Matches(nil, nil), "No matched tags were expected to match any tags")
+}
+
+// Given configuration in file
+// When configuration is read from file or inline JSON
+// Then content of file or inline JSON is returned
+//      and error is returned for missing file
+func TestReadConfig(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"routes": []}`), 0600), "Error was not expected to be returned")
+
+	// When
+	file, fileErr := ReadConfig(path)
+	inline, inlineErr := ReadConfig(` {"windows": []}`)
+	_, missingErr := ReadConfig(filepath.Join(t.TempDir(), "missing.json"))
+
+	// Then
+	assert.Nil(t, fileErr, "Error was not expected to be returned")
+	assert.Equal(t, `{"routes": []}`, string(file), "Unexpected content of file")
+	assert.Nil(t, inlineErr, "Error was not expected to be returned")
+	assert.Equal(t, ` {"windows": []}`, string(inline), "Unexpected inline configuration")
+	assert.NotNil(t, missingErr, "Error was expected to be returned")
+}
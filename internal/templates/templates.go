@@ -0,0 +1,202 @@
+package templates
+
+import (
+	"fmt"
+	htmlTemplate "html/template"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Names of templates rendered by notification channels
+const (
+	TITLE         = "title"         // Short title, e.g. Slack header or Discord embed title
+	SUMMARY       = "summary"       // One line summary, e.g. PagerDuty summary or Opsgenie message
+	TEXT          = "text"          // Message text, e.g. Discord embed description or Opsgenie description
+	EMAIL_SUBJECT = "email.subject" // Subject of email
+	EMAIL_TEXT    = "email.text"    // Plain-text body of email
+	EMAIL_HTML    = "email.html"    // HTML body of email, names ending by .html are rendered by html/template
+)
+
+// Default templates, which may be overridden per uptime monitor
+var Defaults = map[string]string{
+	TITLE:   `{{.Status}}: {{.Host}}`,
+	SUMMARY: `{{template "title" .}}{{if .Reason}} - {{.Reason}}{{end}}`,
+	TEXT: `{{if .Maintenance}}Uptime monitor {{.UptimeID}} {{if eq .Status "MAINTENANCE"}}entered{{else}}left{{end}} maintenance window {{.Maintenance}}.` +
+		`{{else if .Parent}}Uptime monitor {{.UptimeID}} is unreachable as its parent {{.Parent}} is down.` +
+		`{{else if .FlapScore}}Uptime monitor {{.UptimeID}} is flapping ({{.FlapScore}}% of recent runs changed status), ` +
+		`transitions are not announced until it is stable.` +
+		`{{else if .Reminder}}Uptime monitor {{.UptimeID}} is still {{.Status}}{{with .OutageDuration}} after {{duration .}}{{end}}.` +
+		`{{else}}Uptime monitor {{.UptimeID}} changed status {{.Transition}}{{with .OutageDuration}} after outage of {{duration .}}{{end}}.{{end}}` +
+		`{{if .Escalated}} Outage has been escalated as it has not been resolved.{{end}}`,
+	EMAIL_SUBJECT: `[{{.Status}}] {{.Host}}`,
+	EMAIL_TEXT: `{{template "text" .}}
+{{range .Fields}}
+{{.Name}}: {{.Value}}{{end}}
+{{if .RecentRuns}}
+Recent runs:{{range .RecentRuns}}
+- {{timestamp .RunAt}} status code {{.StatusCode}}, TTFB {{latency .TTFB}}{{if .Reason}}, {{.Reason}}{{end}}{{end}}
+{{end}}`,
+	EMAIL_HTML: `<html><body>
+<h2>{{template "title" .}}</h2>
+<p>{{template "text" .}}</p>
+<table>{{range .Fields}}
+<tr><th align="left">{{.Name}}</th><td>{{.Value}}</td></tr>{{end}}
+</table>
+{{if .RecentRuns}}<h3>Recent runs</h3>
+<table>
+<tr><th>Time</th><th>Status code</th><th>TTFB</th><th>Reason</th></tr>{{range .RecentRuns}}
+<tr><td>{{timestamp .RunAt}}</td><td>{{.StatusCode}}</td><td>{{latency .TTFB}}</td><td>{{.Reason}}</td></tr>{{end}}
+</table>{{end}}
+</body></html>`,
+}
+
+// Helper functions available to all templates
+var Funcs = map[string]interface{}{
+	"duration":  Duration,
+	"timestamp": Timestamp,
+	"latency":   Latency,
+	"upper": func(value interface{}) string {
+		return strings.ToUpper(fmt.Sprint(value))
+	},
+	"lower": func(value interface{}) string {
+		return strings.ToLower(fmt.Sprint(value))
+	},
+}
+
+// Represents set of parsed templates, default templates are overridden by uptime monitor's templates
+// Templates may include each other by name, e.g. {{template "title" .}}
+type Set struct {
+	overrides map[string]string
+	text      *template.Template
+	html      *htmlTemplate.Template
+}
+
+// Parses default templates overridden by provided ones
+// Returns error if any template cannot be parsed
+func New(overrides map[string]string) (*Set, error) {
+	text := template.New("").Funcs(Funcs)
+	html := htmlTemplate.New("").Funcs(Funcs)
+	for _, templates := range []map[string]string{Defaults, overrides} {
+		for name, content := range templates {
+			var err error
+			// All templates are parsed by html/template too, so HTML templates may include text templates
+			if _, err = html.New(name).Parse(content); err != nil {
+				return nil, err
+			}
+			if !isHTML(name) {
+				_, err = text.New(name).Parse(content)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Set{overrides: overrides, text: text, html: html}, nil
+}
+
+// Creates new set of templates overridden by provided templates, empty templates are ignored
+// Returns error if any template cannot be parsed
+func (s *Set) With(overrides map[string]string) (*Set, error) {
+	merged := map[string]string{}
+	for _, templates := range []map[string]string{s.overrides, overrides} {
+		for name, content := range templates {
+			if content != "" {
+				merged[name] = content
+			}
+		}
+	}
+	return New(merged)
+}
+
+// Default templates, which are always parsable
+var defaultSet, _ = New(nil)
+
+// Returns set of default templates
+func Default() *Set {
+	return defaultSet
+}
+
+// Renders template by its name with data, HTML templates escape rendered values
+// Returns error if template does not exist or cannot be rendered
+func (s *Set) Render(name string, data interface{}) (string, error) {
+	var out strings.Builder
+	var err error
+	if isHTML(name) {
+		err = s.html.ExecuteTemplate(&out, name, data)
+	} else {
+		err = s.text.ExecuteTemplate(&out, name, data)
+	}
+	return out.String(), err
+}
+
+// Returns true if template is rendered by html/template
+func isHTML(name string) bool {
+	return strings.HasSuffix(name, ".html")
+}
+
+// Humanizes duration, e.g. "1h 5m", "5m 30s" or "45s"
+// Duration may be provided as time.Duration or number of seconds
+func Duration(value interface{}) string {
+	var duration time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		duration = v
+	case int:
+		duration = time.Duration(v) * time.Second
+	case int64:
+		duration = time.Duration(v) * time.Second
+	default:
+		return ""
+	}
+
+	duration = duration.Round(time.Second)
+	days := int64(duration / (24 * time.Hour))
+	hours := int64(duration/time.Hour) % 24
+	minutes := int64(duration/time.Minute) % 60
+	seconds := int64(duration/time.Second) % 60
+	var parts []string
+	for _, part := range []struct {
+		value int64
+		unit  string
+	}{{days, "d"}, {hours, "h"}, {minutes, "m"}, {seconds, "s"}} {
+		if part.value > 0 && len(parts) < 2 {
+			parts = append(parts, strconv.FormatInt(part.value, 10)+part.unit)
+		} else if len(parts) > 0 {
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return "0s"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Formats timestamp in UTC, e.g. "2020-09-13 12:26:40 UTC"
+// Timestamp may be provided as time.Time or Unix timestamp
+func Timestamp(value interface{}) string {
+	var timestamp time.Time
+	switch v := value.(type) {
+	case time.Time:
+		timestamp = v
+	case int64:
+		timestamp = time.Unix(v, 0)
+	case int:
+		timestamp = time.Unix(int64(v), 0)
+	default:
+		return ""
+	}
+	if timestamp.IsZero() {
+		return ""
+	}
+	return timestamp.UTC().Format("2006-01-02 15:04:05 MST")
+}
+
+// Humanizes latency provided in milliseconds, e.g. "850 ms" or "1.25 s"
+func Latency(milliseconds int64) string {
+	if milliseconds < 1000 {
+		return strconv.FormatInt(milliseconds, 10) + " ms"
+	}
+	return strconv.FormatFloat(float64(milliseconds)/1000, 'f', -1, 64) + " s"
+}
@@ -0,0 +1,93 @@
+package templates
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// Represents sample data rendered by templates
+type sample struct {
+	Status string
+	Host   string
+	Reason string
+}
+
+// When durations are humanized
+// Then two most significant units are returned
+func TestDuration(t *testing.T) {
+	assert.Equal(t, "45s", Duration(45*time.Second), "Unexpected seconds")
+	assert.Equal(t, "5m 30s", Duration(int64(330)), "Unexpected minutes")
+	assert.Equal(t, "1h 5m", Duration(time.Hour+5*time.Minute+10*time.Second), "Unexpected hours")
+	assert.Equal(t, "2d", Duration(48*time.Hour+30*time.Second), "Unexpected days")
+	assert.Equal(t, "0s", Duration(time.Duration(0)), "Unexpected zero duration")
+}
+
+// When timestamps and latencies are formatted
+// Then they are humanized
+func TestTimestampAndLatency(t *testing.T) {
+	assert.Equal(t, "2020-09-13 12:26:40 UTC", Timestamp(int64(1600000000)), "Unexpected Unix timestamp")
+	assert.Equal(t, "2020-09-13 12:26:40 UTC", Timestamp(time.Unix(1600000000, 0)), "Unexpected time")
+	assert.Equal(t, "", Timestamp(time.Time{}), "Zero time was expected to be empty")
+	assert.Equal(t, "850 ms", Latency(850), "Unexpected milliseconds")
+	assert.Equal(t, "1.25 s", Latency(1250), "Unexpected seconds")
+}
+
+// Given title template is overridden
+// When summary is rendered
+// Then summary includes overridden title
+func TestRenderOverriddenTemplate(t *testing.T) {
+	// Given
+	set, err := New(map[string]string{TITLE: "{{lower .Status}} at {{.Host}}"})
+	assert.Nil(t, err, "Error was not expected to be returned")
+
+	// When
+	res, err := set.Render(SUMMARY, &sample{Status: "FAIL", Host: "example.com", Reason: "timeout"})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "fail at example.com - timeout", res, "Unexpected summary")
+}
+
+// Given HTML template including text template
+// When HTML template is rendered
+// Then rendered values are escaped
+func TestRenderHTMLEscapes(t *testing.T) {
+	// Given
+	set, _ := New(map[string]string{"custom.html": `<b>{{template "title" .}}</b>`})
+
+	// When
+	res, err := set.Render("custom.html", &sample{Status: "FAIL", Host: "<script>"})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	assert.Equal(t, "<b>FAIL: &lt;script&gt;</b>", res, "Unexpected HTML")
+}
+
+// Given set with overridden templates
+// When set is extended by other overrides
+// Then both overrides are applied and empty overrides are ignored
+func TestWith(t *testing.T) {
+	// Given
+	set, _ := New(map[string]string{TITLE: "custom title"})
+
+	// When
+	extended, err := set.With(map[string]string{TEXT: "custom text", SUMMARY: ""})
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	title, _ := extended.Render(TITLE, &sample{})
+	text, _ := extended.Render(TEXT, &sample{})
+	summary, _ := extended.Render(SUMMARY, &sample{})
+	assert.Equal(t, "custom title", title, "Unexpected title")
+	assert.Equal(t, "custom text", text, "Unexpected text")
+	assert.Equal(t, "custom title", summary, "Default summary was expected to be kept")
+}
+
+// When template cannot be parsed
+// Then error is returned
+func TestNewInvalidTemplate(t *testing.T) {
+	_, err := New(map[string]string{TITLE: "{{.Status"})
+
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
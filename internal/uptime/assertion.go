@@ -0,0 +1,152 @@
+package uptime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Represents type of assertion evaluated against HTTP response
+type AssertionType string
+
+const (
+	ASSERT_BODY_CONTAINS     = "body_contains"
+	ASSERT_BODY_NOT_CONTAINS = "body_not_contains"
+	ASSERT_BODY_REGEX        = "body_regex"
+	ASSERT_JSON_PATH_EQUALS  = "json_path_equals"
+	ASSERT_JSON_PATH_EXISTS  = "json_path_exists"
+	ASSERT_HEADER_EQUALS     = "header_equals"
+	ASSERT_MAX_BODY_SIZE     = "max_body_size"
+)
+
+// Represents single assertion evaluated against HTTP response
+type Assertion struct {
+	Type   AssertionType `json:"type"`
+	Target string        `json:"target,omitempty"` // JSON path (e.g. $.status or data.items[0].id) or header name
+	Value  string        `json:"value,omitempty"`  // Expected value, regular expression or maximal body size in bytes
+}
+
+// Represents result of single assertion
+type AssertionResult struct {
+	Assertion
+	Passed  bool
+	Message string // Describes why assertion failed, empty if passed
+}
+
+// Evaluates all assertions against result of uptime monitor run
+// Results are returned in the same order as assertions
+func EvaluateAssertions(assertions []Assertion, result *Result) []AssertionResult {
+	results := make([]AssertionResult, 0, len(assertions))
+	for _, assertion := range assertions {
+		message := evaluateAssertion(assertion, result)
+		results = append(results, AssertionResult{
+			Assertion: assertion,
+			Passed:    message == "",
+			Message:   message,
+		})
+	}
+	return results
+}
+
+// Evaluates single assertion against result of uptime monitor run
+// Returns message describing failure, empty if assertion passed
+func evaluateAssertion(assertion Assertion, result *Result) string {
+	switch assertion.Type {
+	case ASSERT_BODY_CONTAINS:
+		if !bytes.Contains(result.Body, []byte(assertion.Value)) {
+			return fmt.Sprintf("body does not contain %q", assertion.Value)
+		}
+	case ASSERT_BODY_NOT_CONTAINS:
+		if bytes.Contains(result.Body, []byte(assertion.Value)) {
+			return fmt.Sprintf("body contains %q", assertion.Value)
+		}
+	case ASSERT_BODY_REGEX:
+		pattern, err := regexp.Compile(assertion.Value)
+		if err != nil {
+			return fmt.Sprintf("invalid regular expression %q: %s", assertion.Value, err)
+		}
+		if !pattern.Match(result.Body) {
+			return fmt.Sprintf("body does not match %q", assertion.Value)
+		}
+	case ASSERT_JSON_PATH_EXISTS, ASSERT_JSON_PATH_EQUALS:
+		return evaluateJSONPath(assertion, result.Body)
+	case ASSERT_HEADER_EQUALS:
+		if actual := result.Header.Get(assertion.Target); actual != assertion.Value {
+			return fmt.Sprintf("header %s is %q, expected %q", assertion.Target, actual, assertion.Value)
+		}
+	case ASSERT_MAX_BODY_SIZE:
+		maxSize, err := strconv.ParseInt(assertion.Value, 10, 64)
+		if err != nil {
+			return fmt.Sprintf("invalid maximal body size %q", assertion.Value)
+		}
+		if result.BodySize > maxSize {
+			return fmt.Sprintf("body size %d exceeds %d bytes", result.BodySize, maxSize)
+		}
+	default:
+		return fmt.Sprintf("unsupported assertion type %q", assertion.Type)
+	}
+	return ""
+}
+
+// Evaluates JSON path assertion against body
+// Returns message describing failure, empty if assertion passed
+func evaluateJSONPath(assertion Assertion, body []byte) string {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return "body is not a valid JSON: " + err.Error()
+	}
+
+	value, ok := lookupJSONPath(document, assertion.Target)
+	if !ok {
+		return fmt.Sprintf("JSON path %s does not exist", assertion.Target)
+	}
+	if assertion.Type == ASSERT_JSON_PATH_EQUALS {
+		if actual := jsonValueString(value); actual != assertion.Value {
+			return fmt.Sprintf("JSON path %s is %q, expected %q", assertion.Target, actual, assertion.Value)
+		}
+	}
+	return ""
+}
+
+// Looks up value in decoded JSON document by simple path, e.g. $.data.items[0].id
+// Returns false if path does not exist
+func lookupJSONPath(document interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	if path == "" {
+		return document, true
+	}
+
+	current := document
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Converts decoded JSON value into string, strings are returned as is, other values in their JSON form
+func jsonValueString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
@@ -0,0 +1,86 @@
+package uptime
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// Creates uptime result with provided JSON body and content type header
+func jsonResult(body string) *Result {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	return &Result{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       []byte(body),
+		BodySize:   int64(len(body)),
+	}
+}
+
+// Given uptime result with JSON body,
+// When passing assertions are evaluated,
+// Then all assertions pass
+func TestEvaluateAssertionsPassed(t *testing.T) {
+	// Given
+	result := jsonResult(`{"status":"up","data":{"items":[{"id":42}]}}`)
+
+	// When
+	results := EvaluateAssertions([]Assertion{
+		{Type: ASSERT_BODY_CONTAINS, Value: `"up"`},
+		{Type: ASSERT_BODY_NOT_CONTAINS, Value: "error"},
+		{Type: ASSERT_BODY_REGEX, Value: `"id":\d+`},
+		{Type: ASSERT_JSON_PATH_EQUALS, Target: "$.status", Value: "up"},
+		{Type: ASSERT_JSON_PATH_EQUALS, Target: "$.data.items[0].id", Value: "42"},
+		{Type: ASSERT_JSON_PATH_EXISTS, Target: "data.items"},
+		{Type: ASSERT_HEADER_EQUALS, Target: "content-type", Value: "application/json"},
+		{Type: ASSERT_MAX_BODY_SIZE, Value: "1024"},
+	}, result)
+
+	// Then
+	assert.Len(t, results, 8, "Unexpected number of assertion results")
+	for _, assertionResult := range results {
+		assert.True(t, assertionResult.Passed, "Assertion %s was expected to pass", assertionResult.Type)
+	}
+}
+
+// Given uptime result with JSON body,
+// When failing assertions are evaluated,
+// Then all assertions fail with message
+func TestEvaluateAssertionsFailed(t *testing.T) {
+	// Given
+	result := jsonResult(`{"status":"down","errors":[]}`)
+
+	// When
+	results := EvaluateAssertions([]Assertion{
+		{Type: ASSERT_BODY_CONTAINS, Value: "up"},
+		{Type: ASSERT_BODY_NOT_CONTAINS, Value: "errors"},
+		{Type: ASSERT_BODY_REGEX, Value: `^OK$`},
+		{Type: ASSERT_JSON_PATH_EQUALS, Target: "$.status", Value: "up"},
+		{Type: ASSERT_JSON_PATH_EXISTS, Target: "$.errors[0]"},
+		{Type: ASSERT_HEADER_EQUALS, Target: "X-Version", Value: "1"},
+		{Type: ASSERT_MAX_BODY_SIZE, Value: "10"},
+		{Type: "unknown"},
+	}, result)
+
+	// Then
+	for _, assertionResult := range results {
+		assert.False(t, assertionResult.Passed, "Assertion %s was expected to fail", assertionResult.Type)
+		assert.NotEmpty(t, assertionResult.Message, "Failure message was expected")
+	}
+	assert.Equal(t, AssertionType(ASSERT_BODY_CONTAINS), results[0].Type, "Results were expected in order of assertions")
+}
+
+// Given uptime result with body which is not JSON,
+// When JSON path assertion is evaluated,
+// Then assertion fails
+func TestEvaluateAssertionsInvalidJSON(t *testing.T) {
+	// Given
+	result := jsonResult("<html>Error</html>")
+
+	// When
+	results := EvaluateAssertions([]Assertion{{Type: ASSERT_JSON_PATH_EXISTS, Target: "$.status"}}, result)
+
+	// Then
+	assert.False(t, results[0].Passed, "Assertion was expected to fail")
+}
@@ -0,0 +1,82 @@
+package uptime
+
+import (
+	"errors"
+	"github.com/sparrc/go-ping"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"net"
+	"time"
+)
+
+// Represents result of single ping monitor run
+type PingResult struct {
+	PacketsSent int
+	PacketsRecv int
+	PacketLoss  float64       // Percentage of lost packets
+	MinRTT      time.Duration // Minimal measured round-trip time
+	AvgRTT      time.Duration // Average measured round-trip time
+	MaxRTT      time.Duration // Maximal measured round-trip time
+	StdDevRTT   time.Duration // Standard deviation of measured round-trip times
+}
+
+// Sends count ICMP echo requests to host and collects ping monitor's metrics that are returned as result
+// If privileged is false, then unprivileged UDP mode is used (requires net.ipv4.ping_group_range on Linux)
+// If host cannot be resolved or ICMP echo requests cannot be sent to it at all (e.g. there is no route to host
+// or sockets are not permitted), then returned error is *ProbeError, so such run counts as failed.
+func GetPing(host string, count int, timeout int, privileged bool) (*PingResult, error) {
+	pinger, err := ping.NewPinger(host)
+	if err != nil {
+		return nil, &ProbeError{Class: ClassifyError(err), Err: err}
+	}
+
+	pinger.Count = count
+	pinger.Timeout = time.Duration(timeout) * time.Second
+	pinger.SetPrivileged(privileged)
+	pinger.Run()
+
+	stats := pinger.Statistics()
+	if stats.PacketsSent == 0 {
+		err = sendError(pinger.IPAddr(), privileged)
+		if err == nil {
+			err = errors.New("cannot send ICMP echo requests to " + host)
+		}
+		return nil, &ProbeError{Class: ClassifyError(err), Err: err}
+	}
+
+	return &PingResult{
+		PacketsSent: stats.PacketsSent,
+		PacketsRecv: stats.PacketsRecv,
+		PacketLoss:  stats.PacketLoss,
+		MinRTT:      stats.MinRtt.Round(time.Microsecond),
+		AvgRTT:      stats.AvgRtt.Round(time.Microsecond),
+		MaxRTT:      stats.MaxRtt.Round(time.Microsecond),
+		StdDevRTT:   stats.StdDevRtt.Round(time.Microsecond),
+	}, nil
+}
+
+// Returns error which prevented pinger from sending ICMP echo requests to address, since pinger does not report it
+// Nil is returned if single echo request can be sent now.
+func sendError(addr *net.IPAddr, privileged bool) error {
+	network, typ := "udp4", icmp.Type(ipv4.ICMPTypeEcho)
+	if addr.IP.To4() == nil {
+		network, typ = "udp6", ipv6.ICMPTypeEchoRequest
+	}
+	var dst net.Addr = &net.UDPAddr{IP: addr.IP, Zone: addr.Zone}
+	if privileged {
+		network = map[string]string{"udp4": "ip4:icmp", "udp6": "ip6:ipv6-icmp"}[network]
+		dst = addr
+	}
+	conn, err := icmp.ListenPacket(network, "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	msg, err := (&icmp.Message{Type: typ, Body: &icmp.Echo{ID: 1, Seq: 1}}).Marshal(nil)
+	if err != nil {
+		return err
+	}
+	_, err = conn.WriteTo(msg, dst)
+	return err
+}
@@ -0,0 +1,60 @@
+package uptime
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/icmp"
+	"testing"
+)
+
+// Skips test if unprivileged ICMP sockets are not permitted by the system
+func requireUnprivilegedPing(t *testing.T) {
+	conn, err := icmp.ListenPacket("udp4", "")
+	if err != nil {
+		t.Skip("Unprivileged ICMP sockets are not permitted: " + err.Error())
+	}
+	conn.Close()
+}
+
+// Given localhost is up,
+// When ping is retrieved in unprivileged mode,
+// Then all packets are received
+func TestGetPingLocalhost(t *testing.T) {
+	// Given
+	requireUnprivilegedPing(t)
+
+	// When
+	result, err := GetPing("127.0.0.1", 3, 5, false)
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.Equal(t, 3, result.PacketsSent, "Unexpected number of sent packets")
+	assert.Equal(t, 3, result.PacketsRecv, "Unexpected number of received packets")
+	assert.Equal(t, float64(0), result.PacketLoss, "Unexpected packet loss")
+	assert.LessOrEqual(t, int64(result.MinRTT), int64(result.MaxRTT), "Unexpected RTT values")
+}
+
+// When ping is retrieved
+//      and provided host cannot be resolved,
+// Then probe error is returned
+func TestGetPingNonExistingHost(t *testing.T) {
+	// When
+	_, err := GetPing("non-existing-host.invalid", 1, 1, false)
+
+	// Then
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+}
+
+// When ping is retrieved
+//      and ICMP echo requests cannot be sent to provided address,
+// Then probe error with cause is returned, so run counts as failed
+func TestGetPingUnroutableAddress(t *testing.T) {
+	// When
+	_, err := GetPing("255.255.255.255", 1, 1, false)
+
+	// Then
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+	assert.NotEqual(t, "cannot send ICMP echo requests to 255.255.255.255", probeErr.Err.Error(), "Cause of failure was expected")
+}
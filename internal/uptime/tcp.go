@@ -0,0 +1,118 @@
+package uptime
+
+import (
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Maximal size of response read from TCP connection
+const maxTCPResponseSize = 4096
+
+// Represents checks performed on established TCP connection
+type TCPCheck struct {
+	Payload      string // Payload sent right after connection is established, e.g. "PING\r\n"
+	ExpectPrefix string // Prefix which response (banner) is expected to start with
+	ExpectRegex  string // Regular expression which response (banner) is expected to match
+}
+
+// Represents result of single TCP monitor run
+type TCPResult struct {
+	DNSLookup time.Duration // Measured duration of DNS lookup
+	Connect   time.Duration // Measured duration of TCP connect
+	Response  string        // Received response (banner), empty if nothing was expected
+	Matched   bool          // Whether response matches expectations, true if nothing was expected
+}
+
+// Opens TCP connection to address (host:port) and collects TCP monitor's metrics that are returned as result
+// If check is provided, then its payload is sent and response is read and matched against expectations
+// If address or check is malformed error is returned instead.
+// If host cannot be probed (e.g. DNS or connection failure), then returned error is *ProbeError
+func GetTCP(address string, timeout int, check *TCPCheck) (*TCPResult, error) {
+	if check == nil {
+		check = &TCPCheck{}
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	var expectRegex *regexp.Regexp
+	if check.ExpectRegex != "" {
+		if expectRegex, err = regexp.Compile(check.ExpectRegex); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	dnsStartTime := time.Now()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, &ProbeError{Class: ClassifyError(err), Err: err}
+	}
+	dnsDuration := time.Since(dnsStartTime)
+
+	connStartTime := time.Now()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(addrs[0].IP.String(), port))
+	if err != nil {
+		return nil, &ProbeError{Class: ClassifyError(err), Err: err}
+	}
+	connDuration := time.Since(connStartTime)
+	defer conn.Close()
+
+	result := &TCPResult{
+		DNSLookup: dnsDuration.Round(time.Millisecond),
+		Connect:   connDuration.Round(time.Millisecond),
+		Matched:   true,
+	}
+	if check.Payload == "" && check.ExpectPrefix == "" && expectRegex == nil {
+		return result, nil
+	}
+
+	_ = conn.SetDeadline(deadline)
+	if check.Payload != "" {
+		if _, err = conn.Write([]byte(check.Payload)); err != nil {
+			return nil, &ProbeError{Class: ClassifyError(err), Err: err}
+		}
+	}
+
+	matches := func(response string) bool {
+		return strings.HasPrefix(response, check.ExpectPrefix) &&
+			(expectRegex == nil || expectRegex.MatchString(response))
+	}
+	response, err := readResponse(conn, matches)
+	if err != nil && response == "" {
+		return nil, &ProbeError{Class: ClassifyError(err), Err: err}
+	}
+
+	result.Response = response
+	result.Matched = matches(response)
+	return result, nil
+}
+
+// Reads response from connection until it matches, connection is closed or maximal response size is reached
+// Returns response read so far and error which stopped reading, if any
+func readResponse(conn net.Conn, matches func(string) bool) (string, error) {
+	buffer := make([]byte, maxTCPResponseSize)
+	size := 0
+	for size < len(buffer) {
+		n, err := conn.Read(buffer[size:])
+		size += n
+		if err == io.EOF {
+			return string(buffer[:size]), nil
+		}
+		if err != nil {
+			return string(buffer[:size]), err
+		}
+		if matches(string(buffer[:size])) {
+			break
+		}
+	}
+	return string(buffer[:size]), nil
+}
@@ -0,0 +1,114 @@
+package uptime
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+// Starts TCP server on localhost which sends banner to every connection and echoes received data
+func startTCPServer(t *testing.T, banner string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_, _ = conn.Write([]byte(banner))
+				buffer := make([]byte, 64)
+				n, _ := conn.Read(buffer)
+				_, _ = conn.Write(buffer[:n])
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+// Given TCP server is up,
+// When TCP uptime is retrieved without any check,
+// Then result is matched
+func TestGetTCPConnect(t *testing.T) {
+	// Given
+	listener := startTCPServer(t, "")
+	defer listener.Close()
+
+	// When
+	result, err := GetTCP(listener.Addr().String(), 4, nil)
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.True(t, result.Matched, "Result was expected to be matched")
+	assert.GreaterOrEqual(t, int64(result.Connect), int64(0), "Unexpected Connect value")
+}
+
+// Given TCP server is up
+//       and sends banner,
+// When TCP uptime is retrieved
+//      and banner is expected to have specific prefix,
+// Then result contains banner and is matched
+func TestGetTCPBannerPrefix(t *testing.T) {
+	// Given
+	listener := startTCPServer(t, "220 smtp.example.com ESMTP\r\n")
+	defer listener.Close()
+
+	// When
+	result, err := GetTCP(listener.Addr().String(), 4, &TCPCheck{ExpectPrefix: "220 "})
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.True(t, result.Matched, "Result was expected to be matched")
+	assert.Equal(t, "220 smtp.example.com ESMTP\r\n", result.Response, "Unexpected response")
+}
+
+// Given TCP server is up,
+// When TCP uptime is retrieved
+//      and sent payload is expected in response matching regular expression which does not match,
+// Then result is not matched
+func TestGetTCPPayloadRegexNotMatched(t *testing.T) {
+	// Given
+	listener := startTCPServer(t, "")
+	defer listener.Close()
+
+	// When
+	result, err := GetTCP(listener.Addr().String(), 2, &TCPCheck{Payload: "PING\r\n", ExpectRegex: "^\\+PONG"})
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.False(t, result.Matched, "Result was not expected to be matched")
+	assert.Equal(t, "PING\r\n", result.Response, "Unexpected response")
+}
+
+// Given TCP server is down,
+// When TCP uptime is retrieved,
+// Then probe error classified as refused connection is returned
+func TestGetTCPConnectionRefused(t *testing.T) {
+	// Given
+	listener := startTCPServer(t, "")
+	listener.Close()
+
+	// When
+	_, err := GetTCP(listener.Addr().String(), 4, nil)
+
+	// Then
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+	assert.Equal(t, ErrorClass(ERROR_CONNECT_REFUSED), probeErr.Class, "Unexpected error class")
+}
+
+// When TCP uptime is retrieved
+//      and provided address does not contain port,
+// Then error is returned
+func TestGetTCPMalformedAddress(t *testing.T) {
+	// When
+	_, err := GetTCP("localhost", 4, nil)
+
+	// Then
+	assert.NotNil(t, err, "Error was expected")
+}
@@ -1,37 +1,307 @@
 package uptime
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"syscall"
 	"time"
 )
 
 // Represents result of single uptime monitor run
 type Result struct {
-	StatusCode   int
-	TTFB         time.Duration // Measured Time To First Byte
-	DNSLookup    time.Duration // Measured duration of DNS lookup
-	TLSHandshake time.Duration // Measured duration of TLS handshake
+	StatusCode       int
+	TTFB             time.Duration // Measured Time To First Byte since connection has been requested (includes DNS, TCP and TLS)
+	DNSLookup        time.Duration // Measured duration of DNS lookup
+	TCPConnect       time.Duration // Measured duration of TCP connect
+	TLSHandshake     time.Duration // Measured duration of TLS handshake
+	ServerProcessing time.Duration // Measured duration between request has been written and first response byte
+	ContentTransfer  time.Duration // Measured duration of reading response body
+	Total            time.Duration // Measured duration of whole request including reading response body
+	ConnReused       bool          // Whether previously opened connection has been reused
+	Certificate      *Certificate  // Leaf certificate presented by host, nil if TLS is not used
+	Header           http.Header   // Response headers
+	Body             []byte        // Response body, truncated to maxBodySize
+	BodySize         int64         // Size of whole response body in bytes
 }
 
-// Creates single HTTP request and collects uptime monitor's metrics that are returned as result
-// In case of failure error is returned instead
+// Maximal size of response body kept in result
+const maxBodySize = 1 << 20
+
+// Root certificates used to verify certificate chains, system roots are used if nil
+var rootCAs *x509.CertPool
+
+// Error of request carrying resolved secrets which would be sent over connection without verified TLS
+var errUnverifiedTLS = errors.New("resolved secrets are sent only over verified TLS connection")
+
+// Represents leaf certificate presented by host during TLS handshake
+type Certificate struct {
+	Subject         string
+	Issuer          string
+	SANs            []string  // Subject alternative names (DNS names and IP addresses)
+	NotAfter        time.Time // Time after which certificate is not valid
+	DaysUntilExpiry int       // Number of whole days until certificate expires, negative if already expired
+	VerifyError     string    // Error of certificate chain verification, empty if chain is valid
+}
+
+// Represents type of authentication used by HTTP request
+type AuthType string
+
+const (
+	AUTH_BASIC   = "basic"
+	AUTH_BEARER  = "bearer"
+	AUTH_API_KEY = "api_key"
+)
+
+// Represents HTTP request sent by uptime monitor
+type RequestSpec struct {
+	Method  string            `json:"method,omitempty"`  // HTTP method, GET by default
+	Headers map[string]string `json:"headers,omitempty"` // HTTP headers, including Host and User-Agent overrides
+	Body    string            `json:"body,omitempty"`    // HTTP request body
+	Auth    *Auth             `json:"auth,omitempty"`    // Authentication of HTTP request
+	// Whether headers, body or authentication carry resolved secrets, such request is sent only over verified TLS
+	Sensitive bool `json:"-"`
+}
+
+// Represents authentication of HTTP request
+type Auth struct {
+	Type     AuthType `json:"type"`
+	Username string   `json:"username,omitempty"` // Username of basic authentication
+	Password string   `json:"password,omitempty"` // Password of basic authentication
+	Token    string   `json:"token,omitempty"`    // Bearer token or API key
+	Header   string   `json:"header,omitempty"`   // Header carrying API key, X-API-Key by default
+}
+
+// Creates HTTP request according to provided request spec
+// If spec is nil, then plain GET request is created
+func newRequest(host string, spec *RequestSpec) (*http.Request, error) {
+	if spec == nil {
+		spec = &RequestSpec{}
+	}
+	method := spec.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if spec.Body != "" {
+		body = strings.NewReader(spec.Body)
+	}
+
+	req, err := http.NewRequest(method, host, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range spec.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = value
+		} else {
+			req.Header.Set(name, value)
+		}
+	}
+
+	if auth := spec.Auth; auth != nil {
+		switch auth.Type {
+		case AUTH_BASIC:
+			req.SetBasicAuth(auth.Username, auth.Password)
+		case AUTH_BEARER:
+			req.Header.Set("Authorization", "Bearer "+auth.Token)
+		case AUTH_API_KEY:
+			header := auth.Header
+			if header == "" {
+				header = "X-API-Key"
+			}
+			req.Header.Set(header, auth.Token)
+		default:
+			return nil, errors.New("unsupported authentication type: " + string(auth.Type))
+		}
+	}
+	return req, nil
+}
+
+// Represents class of uptime probe failure
+type ErrorClass string
+
+const (
+	ERROR_DNS              = "dns_error"
+	ERROR_CONNECT_REFUSED  = "connect_refused"
+	ERROR_CONNECTION_RESET = "connection_reset"
+	ERROR_CONNECT          = "connect_error"
+	ERROR_TLS              = "tls_error"
+	ERROR_TIMEOUT          = "timeout"
+	ERROR_PROTOCOL         = "protocol_error"
+	ERROR_UNKNOWN          = "unknown_error"
+)
+
+// Represents failure of uptime probe, e.g. host is unreachable or does not respond in time
+// Such failure is a valid result of uptime monitor run, not an error of the monitor itself
+type ProbeError struct {
+	Class       ErrorClass
+	Err         error
+	Certificate *Certificate // Certificate presented by host in case of TLS failure, nil if it is not known
+}
+
+func (e *ProbeError) Error() string {
+	return string(e.Class) + ": " + e.Err.Error()
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// Classifies error returned by uptime probe
+// If error cannot be classified, then ERROR_UNKNOWN is returned
+func ClassifyError(err error) ErrorClass {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		return ERROR_DNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ERROR_TIMEOUT
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ERROR_CONNECT_REFUSED
+	case errors.Is(err, syscall.ECONNRESET):
+		return ERROR_CONNECTION_RESET
+	case errors.Is(err, errUnverifiedTLS),
+		errors.As(err, &unknownAuthorityErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &certInvalidErr),
+		errors.As(err, &recordHeaderErr),
+		strings.Contains(err.Error(), "tls:"):
+		return ERROR_TLS
+	case errors.As(err, &opErr) && opErr.Op == "dial",
+		errors.Is(err, syscall.ENETUNREACH),
+		errors.Is(err, syscall.EHOSTUNREACH):
+		return ERROR_CONNECT
+	case strings.Contains(err.Error(), "unsupported protocol scheme"),
+		strings.Contains(err.Error(), "malformed HTTP"):
+		return ERROR_PROTOCOL
+	}
+	return ERROR_UNKNOWN
+}
+
+// Creates certificate info from leaf certificate presented by host
+func newCertificate(leaf *x509.Certificate) *Certificate {
+	sans := append([]string{}, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return &Certificate{
+		Subject:         leaf.Subject.String(),
+		Issuer:          leaf.Issuer.String(),
+		SANs:            sans,
+		NotAfter:        leaf.NotAfter,
+		DaysUntilExpiry: int(math.Floor(time.Until(leaf.NotAfter).Hours() / 24)),
+	}
+}
+
+// Inspects certificate of host in separate diagnostic TLS handshake which skips verification
+// No request is sent over diagnostic connection. Chain is verified afterwards and its error is part of the certificate.
+// Returns nil if handshake fails or host does not present any certificate
+func inspectCertificate(hostUrl *url.URL, timeout time.Duration) *Certificate {
+	port := hostUrl.Port()
+	if port == "" {
+		port = "443"
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config:    &tls.Config{ServerName: hostUrl.Hostname(), InsecureSkipVerify: true},
+	}
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(hostUrl.Hostname(), port))
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := state.PeerCertificates[0]
+	certificate := newCertificate(leaf)
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: hostUrl.Hostname(), Intermediates: intermediates, Roots: rootCAs})
+	if err != nil {
+		certificate.VerifyError = err.Error()
+	}
+	return certificate
+}
+
+// Returns URL of request which failed, e.g. target of redirect, or fallback URL if it is not known
+func failedURL(err error, fallback *url.URL) *url.URL {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if failed, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			return failed
+		}
+	}
+	return fallback
+}
+
+// Creates single HTTP GET request and collects uptime monitor's metrics that are returned as result
+// See GetUptimeWithSpec
 func GetUptime(host string, timeout int) (*Result, error) {
-	var connStartTime, dnsStartTime, tlsStartTime time.Time
-	var firstByteDuration, dnsDuration, tlsDuration time.Duration
+	return GetUptimeWithSpec(host, timeout, nil)
+}
+
+// Creates single HTTP request according to provided spec and collects uptime monitor's metrics that are returned as result
+// Certificate chain is verified during TLS handshake of the request and of every redirect. If verification fails,
+// the request is not sent and details of the invalid certificate are collected by separate diagnostic handshake.
+// Sensitive spec is never sent over plain HTTP nor over TLS connection without verified chain, not even after redirect.
+// If request cannot be created error is returned instead.
+// If host cannot be probed (e.g. DNS, connection or TLS failure), then returned error is *ProbeError
+func GetUptimeWithSpec(host string, timeout int, spec *RequestSpec) (*Result, error) {
+	var connStartTime, dnsStartTime, tcpStartTime, tlsStartTime, wroteRequestTime, firstByteTime time.Time
+	var firstByteDuration, dnsDuration, tcpDuration, tlsDuration time.Duration
+	var connReused bool
+	var certificate *Certificate
 
-	req, err := http.NewRequest("GET", host, nil)
+	req, err := newRequest(host, spec)
 	if err != nil {
 		return nil, err
 	}
+	sensitive := spec != nil && spec.Sensitive
+	if sensitive && req.URL.Scheme != "https" {
+		return nil, &ProbeError{Class: ERROR_TLS, Err: errUnverifiedTLS}
+	}
 
 	trace := &httptrace.ClientTrace{
 		GetConn: func(_ string) {
 			connStartTime = time.Now()
 		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			connReused = info.Reused
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			wroteRequestTime = time.Now()
+		},
 		GotFirstResponseByte: func() {
-			firstByteDuration = time.Since(connStartTime)
+			firstByteTime = time.Now()
+			firstByteDuration = firstByteTime.Sub(connStartTime)
+		},
+		ConnectStart: func(_, _ string) {
+			tcpStartTime = time.Now()
+		},
+		ConnectDone: func(_, _ string, _ error) {
+			tcpDuration = time.Since(tcpStartTime)
 		},
 		DNSStart: func(_ httptrace.DNSStartInfo) {
 			dnsStartTime = time.Now()
@@ -48,19 +318,69 @@ func GetUptime(host string, timeout int) (*Result, error) {
 	}
 
 	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs: rootCAs,
+		// Called only once chain has been verified, certificate of the first handshake (monitored host) is kept
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if sensitive && len(state.VerifiedChains) == 0 {
+				return errUnverifiedTLS
+			}
+			if certificate == nil && len(state.PeerCertificates) > 0 {
+				certificate = newCertificate(state.PeerCertificates[0])
+			}
+			return nil
+		},
+	}
 	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
+		Timeout:   time.Duration(timeout) * time.Second,
+		Transport: transport,
+		CheckRedirect: func(redirect *http.Request, via []*http.Request) error {
+			if sensitive && redirect.URL.Scheme != "https" {
+				return errUnverifiedTLS
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
 	}
 
+	startTime := time.Now()
 	res, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		probeErr := &ProbeError{Class: ClassifyError(err), Err: err}
+		if probeErr.Class == ERROR_TLS {
+			probeErr.Certificate = inspectCertificate(failedURL(err, req.URL), client.Timeout)
+		}
+		return nil, probeErr
 	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBodySize))
+	if err != nil {
+		return nil, &ProbeError{Class: ClassifyError(err), Err: err}
+	}
+	remainingSize, err := io.Copy(io.Discard, res.Body)
+	if err != nil {
+		return nil, &ProbeError{Class: ClassifyError(err), Err: err}
+	}
+
+	endTime := time.Now()
 
 	return &Result{
-		StatusCode:   res.StatusCode,
-		TTFB:         firstByteDuration.Round(time.Millisecond),
-		DNSLookup:    dnsDuration.Round(time.Millisecond),
-		TLSHandshake: tlsDuration.Round(time.Millisecond),
+		StatusCode:       res.StatusCode,
+		TTFB:             firstByteDuration.Round(time.Millisecond),
+		DNSLookup:        dnsDuration.Round(time.Millisecond),
+		TCPConnect:       tcpDuration.Round(time.Millisecond),
+		TLSHandshake:     tlsDuration.Round(time.Millisecond),
+		ServerProcessing: firstByteTime.Sub(wroteRequestTime).Round(time.Millisecond),
+		ContentTransfer:  endTime.Sub(firstByteTime).Round(time.Millisecond),
+		Total:            endTime.Sub(startTime).Round(time.Millisecond),
+		ConnReused:       connReused,
+		Certificate:      certificate,
+		Header:           res.Header,
+		Body:             body,
+		BodySize:         int64(len(body)) + remainingSize,
 	}, nil
 }
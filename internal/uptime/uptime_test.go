@@ -1,9 +1,15 @@
 package uptime
 
 import (
+	"crypto/x509"
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -66,7 +72,56 @@ func TestGetUptimeTimeout(t *testing.T) {
 	_, err := GetUptime(hostHTTP.URL, 4)
 
 	// Then
-	assert.NotNil(t, err, "Error was expected")
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+	assert.Equal(t, ErrorClass(ERROR_TIMEOUT), probeErr.Class, "Unexpected error class")
+}
+
+// Given host is down (connection is refused),
+// When uptime is retrieved,
+// Then probe error classified as refused connection is returned
+func TestGetUptimeConnectionRefused(t *testing.T) {
+	// Given
+	hostHTTP := httptest.NewServer(http.NotFoundHandler())
+	hostHTTP.Close()
+
+	// When
+	_, err := GetUptime(hostHTTP.URL, 4)
+
+	// Then
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+	assert.Equal(t, ErrorClass(ERROR_CONNECT_REFUSED), probeErr.Class, "Unexpected error class")
+}
+
+// When DNS lookup error is classified
+// Then DNS error class is returned
+func TestClassifyErrorDNS(t *testing.T) {
+	// When
+	class := ClassifyError(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "non-existing-host"}})
+
+	// Then
+	assert.Equal(t, ErrorClass(ERROR_DNS), class, "Unexpected error class")
+}
+
+// When error of write to unreachable network is classified
+// Then connect error class is returned
+func TestClassifyErrorNetworkUnreachable(t *testing.T) {
+	// When
+	class := ClassifyError(&net.OpError{Op: "write", Err: &os.SyscallError{Syscall: "sendto", Err: syscall.ENETUNREACH}})
+
+	// Then
+	assert.Equal(t, ErrorClass(ERROR_CONNECT), class, "Unexpected error class")
+}
+
+// When unknown error is classified
+// Then unknown error class is returned
+func TestClassifyErrorUnknown(t *testing.T) {
+	// When
+	class := ClassifyError(errors.New("something went wrong"))
+
+	// Then
+	assert.Equal(t, ErrorClass(ERROR_UNKNOWN), class, "Unexpected error class")
 }
 
 // When uptime is retrieved
@@ -90,3 +145,326 @@ func TestGetUptimeMalformedHostURL(t *testing.T) {
 	// Then
 	assert.NotNil(t, err, "Error was expected")
 }
+
+// Given host is up
+//       and serves trusted certificate,
+// When uptime is retrieved,
+// Then uptime result contains certificate details
+//      and no certificate chain verification error
+func TestGetUptimeCertificate(t *testing.T) {
+	// Given
+	hostHTTPS := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer hostHTTPS.Close()
+	trustCertificate(t, hostHTTPS)
+
+	// When
+	result, err := GetUptime(hostHTTPS.URL, 10)
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.Equal(t, http.StatusOK, result.StatusCode, "Unexpected status code")
+	assert.NotNil(t, result.Certificate, "Certificate was expected")
+	assert.Contains(t, result.Certificate.Subject, "Acme Co", "Unexpected certificate subject")
+	assert.Contains(t, result.Certificate.SANs, "127.0.0.1", "Unexpected certificate SANs")
+	assert.True(t, result.Certificate.NotAfter.After(time.Now()), "Certificate was expected to be valid")
+	assert.Greater(t, result.Certificate.DaysUntilExpiry, 0, "Unexpected days until expiry")
+	assert.Empty(t, result.Certificate.VerifyError, "Certificate chain verification error was not expected")
+}
+
+// Given host is up
+//       and serves self-signed certificate,
+// When uptime is retrieved,
+// Then request is not sent
+//      and TLS probe error contains certificate details and chain verification error
+func TestGetUptimeInvalidCertificate(t *testing.T) {
+	// Given
+	requested := false
+	hostHTTPS := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = true
+		}))
+	defer hostHTTPS.Close()
+
+	// When
+	_, err := GetUptime(hostHTTPS.URL, 10)
+
+	// Then
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+	assert.Equal(t, ErrorClass(ERROR_TLS), probeErr.Class, "Unexpected error class")
+	assert.False(t, requested, "Request was not expected to be sent")
+	assert.NotNil(t, probeErr.Certificate, "Certificate was expected")
+	assert.Contains(t, probeErr.Certificate.Subject, "Acme Co", "Unexpected certificate subject")
+	assert.Greater(t, probeErr.Certificate.DaysUntilExpiry, 0, "Unexpected days until expiry")
+	assert.NotEmpty(t, probeErr.Certificate.VerifyError, "Certificate chain verification error was expected")
+}
+
+// Given host redirects to another host
+//       and the other host serves self-signed certificate,
+// When uptime is retrieved,
+// Then redirected request is not sent
+//      and TLS probe error contains certificate of the other host
+func TestGetUptimeRedirectToInvalidCertificate(t *testing.T) {
+	// Given
+	requested := false
+	targetHTTPS := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = true
+		}))
+	defer targetHTTPS.Close()
+	hostHTTP := httptest.NewServer(http.RedirectHandler(targetHTTPS.URL, http.StatusFound))
+	defer hostHTTP.Close()
+
+	// When
+	_, err := GetUptime(hostHTTP.URL, 10)
+
+	// Then
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+	assert.Equal(t, ErrorClass(ERROR_TLS), probeErr.Class, "Unexpected error class")
+	assert.False(t, requested, "Redirected request was not expected to be sent")
+	assert.NotNil(t, probeErr.Certificate, "Certificate was expected")
+	assert.NotEmpty(t, probeErr.Certificate.VerifyError, "Certificate chain verification error was expected")
+}
+
+// Trusts certificate of test TLS server for the rest of the test
+func trustCertificate(t *testing.T, server *httptest.Server) {
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	rootCAs = pool
+	t.Cleanup(func() {
+		rootCAs = nil
+	})
+}
+
+// Given host is up
+//       and does not use TLS,
+// When uptime is retrieved,
+// Then uptime result does not contain certificate
+func TestGetUptimeNoCertificate(t *testing.T) {
+	// Given
+	hostHTTP := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	defer hostHTTP.Close()
+
+	// When
+	result, err := GetUptime(hostHTTP.URL, 10)
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.Nil(t, result.Certificate, "Certificate was not expected")
+}
+
+// Given host is up,
+// When uptime is retrieved with request spec,
+// Then host receives request with requested method, headers, body and authentication
+func TestGetUptimeWithSpec(t *testing.T) {
+	// Given
+	var received *http.Request
+	var receivedBody []byte
+	hostHTTP := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			receivedBody, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		}))
+	defer hostHTTP.Close()
+
+	// When
+	result, err := GetUptimeWithSpec(hostHTTP.URL, 10, &RequestSpec{
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Host": "health.example.com", "User-Agent": "uptime-monitor"},
+		Body:    `{"ping":true}`,
+		Auth:    &Auth{Type: AUTH_BASIC, Username: "monitor", Password: "s3cr3t"},
+	})
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.Equal(t, http.StatusCreated, result.StatusCode, "Unexpected status code")
+	assert.Equal(t, http.MethodPost, received.Method, "Unexpected method")
+	assert.Equal(t, "health.example.com", received.Host, "Unexpected Host header")
+	assert.Equal(t, "uptime-monitor", received.UserAgent(), "Unexpected User-Agent header")
+	assert.Equal(t, `{"ping":true}`, string(receivedBody), "Unexpected body")
+	username, password, _ := received.BasicAuth()
+	assert.Equal(t, "monitor", username, "Unexpected username")
+	assert.Equal(t, "s3cr3t", password, "Unexpected password")
+}
+
+// Given host is up,
+// When uptime is retrieved with API key authentication,
+// Then host receives API key in requested header
+func TestGetUptimeWithSpecAPIKey(t *testing.T) {
+	// Given
+	var received *http.Request
+	hostHTTP := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+		}))
+	defer hostHTTP.Close()
+
+	// When
+	_, err := GetUptimeWithSpec(hostHTTP.URL, 10, &RequestSpec{
+		Auth: &Auth{Type: AUTH_API_KEY, Header: "X-Token", Token: "api-key"},
+	})
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.Equal(t, http.MethodGet, received.Method, "Unexpected method")
+	assert.Equal(t, "api-key", received.Header.Get("X-Token"), "Unexpected API key header")
+}
+
+// When uptime is retrieved
+//      and unsupported authentication is requested,
+// Then error is returned
+func TestGetUptimeWithSpecUnsupportedAuth(t *testing.T) {
+	// When
+	_, err := GetUptimeWithSpec("https://example.com", 10, &RequestSpec{Auth: &Auth{Type: "digest"}})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected")
+}
+
+// Given host is up
+//       and serves trusted certificate,
+// When uptime is retrieved with sensitive request spec,
+// Then host receives authentication
+func TestGetUptimeWithSensitiveSpec(t *testing.T) {
+	// Given
+	var authorization string
+	hostHTTPS := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+		}))
+	defer hostHTTPS.Close()
+	trustCertificate(t, hostHTTPS)
+
+	// When
+	_, err := GetUptimeWithSpec(hostHTTPS.URL, 10, &RequestSpec{
+		Auth:      &Auth{Type: AUTH_BEARER, Token: "s3cr3t"},
+		Sensitive: true,
+	})
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.Equal(t, "Bearer s3cr3t", authorization, "Unexpected Authorization header")
+}
+
+// Given host is up
+//       and serves self-signed certificate,
+// When uptime is retrieved with sensitive request spec,
+// Then host does not receive any request
+//      and TLS probe error is returned
+func TestGetUptimeWithSensitiveSpecInvalidCertificate(t *testing.T) {
+	// Given
+	requested := false
+	hostHTTPS := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = true
+		}))
+	defer hostHTTPS.Close()
+
+	// When
+	_, err := GetUptimeWithSpec(hostHTTPS.URL, 10, &RequestSpec{
+		Auth:      &Auth{Type: AUTH_BEARER, Token: "s3cr3t"},
+		Sensitive: true,
+	})
+
+	// Then
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+	assert.Equal(t, ErrorClass(ERROR_TLS), probeErr.Class, "Unexpected error class")
+	assert.False(t, requested, "Request was not expected to be sent")
+}
+
+// Given host is up
+//       and does not use TLS,
+// When uptime is retrieved with sensitive request spec,
+// Then host does not receive any request
+//      and TLS probe error is returned
+func TestGetUptimeWithSensitiveSpecPlainHTTP(t *testing.T) {
+	// Given
+	requested := false
+	hostHTTP := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = true
+		}))
+	defer hostHTTP.Close()
+
+	// When
+	_, err := GetUptimeWithSpec(hostHTTP.URL, 10, &RequestSpec{
+		Auth:      &Auth{Type: AUTH_BASIC, Username: "monitor", Password: "s3cr3t"},
+		Sensitive: true,
+	})
+
+	// Then
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+	assert.Equal(t, ErrorClass(ERROR_TLS), probeErr.Class, "Unexpected error class")
+	assert.False(t, requested, "Request was not expected to be sent")
+}
+
+// Given host serves trusted certificate
+//       and redirects to another host without TLS,
+// When uptime is retrieved with sensitive request spec,
+// Then the other host does not receive any request
+//      and TLS probe error is returned
+func TestGetUptimeWithSensitiveSpecRedirectToPlainHTTP(t *testing.T) {
+	// Given
+	requested := false
+	targetHTTP := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = true
+		}))
+	defer targetHTTP.Close()
+	hostHTTPS := httptest.NewTLSServer(http.RedirectHandler(targetHTTP.URL, http.StatusTemporaryRedirect))
+	defer hostHTTPS.Close()
+	trustCertificate(t, hostHTTPS)
+
+	// When
+	_, err := GetUptimeWithSpec(hostHTTPS.URL, 10, &RequestSpec{
+		Headers:   map[string]string{"X-API-Key": "s3cr3t"},
+		Sensitive: true,
+	})
+
+	// Then
+	var probeErr *ProbeError
+	assert.True(t, errors.As(err, &probeErr), "Probe error was expected")
+	assert.Equal(t, ErrorClass(ERROR_TLS), probeErr.Class, "Unexpected error class")
+	assert.False(t, requested, "Redirected request was not expected to be sent")
+}
+
+// Given host is up
+//       and it is slow to process request and to transfer body,
+// When uptime is retrieved,
+// Then uptime result contains timing of all request phases
+//      and size of response body
+func TestGetUptimeTimingBreakdown(t *testing.T) {
+	// Given
+	hostHTTP := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("first part,"))
+			w.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte("second part"))
+		}))
+	defer hostHTTP.Close()
+
+	// When
+	result, err := GetUptime(hostHTTP.URL, 10)
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.GreaterOrEqual(t, int64(result.TCPConnect), int64(0), "Unexpected TCPConnect value")
+	assert.GreaterOrEqual(t, int64(result.ServerProcessing), int64(200*time.Millisecond), "Unexpected ServerProcessing value")
+	assert.GreaterOrEqual(t, int64(result.ContentTransfer), int64(200*time.Millisecond), "Unexpected ContentTransfer value")
+	assert.GreaterOrEqual(t, int64(result.Total), int64(400*time.Millisecond), "Unexpected Total value")
+	assert.Equal(t, int64(len("first part,second part")), result.BodySize, "Unexpected body size")
+	assert.False(t, result.ConnReused, "Connection was not expected to be reused")
+}
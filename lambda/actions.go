@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/google/uuid"
+	"monitor-uptime/internal/storage"
+	"strings"
+	"time"
+)
+
+// Actions of uptime monitor request
+const (
+	ACTION_MONITOR     = ""            // Run uptime monitor
+	ACTION_ACKNOWLEDGE = "acknowledge" // Acknowledge announced status of uptime monitor
+	ACTION_SILENCE     = "silence"     // Create silence
+	ACTION_UNSILENCE   = "unsilence"   // Remove silence
+	ACTION_SILENCES    = "silences"    // List active silences of uptime monitor and silences matching tags only
+	ACTION_INCIDENTS   = "incidents"   // List incidents of uptime monitor
+	ACTION_INCIDENT    = "incident"    // Get incident
+	ACTION_NOTE        = "note"        // Add note to incident
+	ACTION_DELIVERIES  = "deliveries"  // List recorded delivery attempts of notifications of uptime monitor
+)
+
+// Handles request acknowledging outage, managing silences or reviewing incidents
+// Returns error if action is not supported, its request is not valid or storage fails
+func handleAction(req *UptimeMonitorRequest, sessionOptions *session.Options) (UptimeMonitorResponse, error) {
+	store, err := newStorage(sessionOptions)
+	if err != nil {
+		return UptimeMonitorResponse{}, err
+	}
+	now := time.Now()
+
+	switch req.Action {
+	case ACTION_ACKNOWLEDGE:
+		if req.UptimeID == "" {
+			return UptimeMonitorResponse{}, errors.New("uptime ID of acknowledged monitor is required")
+		}
+		acknowledged, err := store.AcknowledgeUptimeStatus(req.UptimeID, req.By, req.Note, now.Unix())
+		if err != nil || !acknowledged {
+			return UptimeMonitorResponse{}, err
+		}
+		message := req.By
+		if req.Note != "" {
+			message = strings.TrimPrefix(req.By+": ", ": ") + req.Note
+		}
+		err = recordIncidentEvent(req.UptimeID, storage.EVENT_ACKNOWLEDGED, message, now.Unix(), store)
+		return UptimeMonitorResponse{Acknowledged: true}, err
+	case ACTION_SILENCE:
+		silence, err := newSilence(req, now)
+		if err != nil {
+			return UptimeMonitorResponse{}, err
+		}
+		if err = store.PruneSilences(silence.UptimeID, now.Unix()); err != nil {
+			return UptimeMonitorResponse{}, err
+		}
+		if err = store.PutSilence(silence); err != nil {
+			return UptimeMonitorResponse{}, err
+		}
+		return UptimeMonitorResponse{Silences: []storage.SilenceItem{*silence}}, nil
+	case ACTION_UNSILENCE:
+		if req.Silence == nil || req.Silence.ID == "" {
+			return UptimeMonitorResponse{}, errors.New("ID of removed silence is required")
+		}
+		uptimeID := req.Silence.UptimeID
+		if uptimeID == "" {
+			uptimeID = req.UptimeID
+		}
+		deleted, err := store.DeleteSilence(uptimeID, req.Silence.ID)
+		if err == nil && !deleted {
+			err = errors.New("silence not found: " + req.Silence.ID)
+		}
+		return UptimeMonitorResponse{}, err
+	case ACTION_SILENCES:
+		silences, err := store.ListSilences(req.UptimeID, now.Unix())
+		return UptimeMonitorResponse{Silences: silences}, err
+	case ACTION_INCIDENTS:
+		if req.UptimeID == "" {
+			return UptimeMonitorResponse{}, errors.New("uptime ID of monitor whose incidents are listed is required")
+		}
+		to := req.To
+		if to == 0 {
+			to = now.Unix()
+		}
+		from := req.From
+		if from == 0 {
+			from = time.Unix(to, 0).AddDate(0, 0, -INCIDENTS_DAYS).Unix()
+		}
+		incidents, err := store.ListIncidents(req.UptimeID, from, to)
+		return UptimeMonitorResponse{Incidents: incidents}, err
+	case ACTION_DELIVERIES:
+		if req.UptimeID == "" {
+			return UptimeMonitorResponse{}, errors.New("uptime ID of monitor whose deliveries are listed is required")
+		}
+		deliveries, err := store.ListDeliveryAttempts(req.UptimeID)
+		return UptimeMonitorResponse{Deliveries: deliveries}, err
+	case ACTION_INCIDENT:
+		if req.IncidentID == "" {
+			return UptimeMonitorResponse{}, errors.New("incident ID is required")
+		}
+		incident, err := store.GetIncident(req.IncidentID)
+		if err != nil || incident == nil {
+			return UptimeMonitorResponse{}, err
+		}
+		return UptimeMonitorResponse{Incidents: []storage.IncidentItem{*incident}}, nil
+	case ACTION_NOTE:
+		if req.IncidentID == "" || req.Note == "" {
+			return UptimeMonitorResponse{}, errors.New("incident ID and note are required")
+		}
+		added, err := store.AddIncidentNote(req.IncidentID, storage.IncidentNote{At: now.Unix(), By: req.By, Text: req.Note})
+		if err != nil {
+			return UptimeMonitorResponse{}, err
+		}
+		if !added {
+			return UptimeMonitorResponse{}, errors.New("incident does not exist: " + req.IncidentID)
+		}
+		return UptimeMonitorResponse{}, nil
+	}
+	return UptimeMonitorResponse{}, errors.New("unsupported action: " + req.Action)
+}
+
+// Creates silence of request, its ID is generated and it lasts for request's duration unless silence sets them
+// Silence without matched tags silences uptime monitor of request
+// Returns error if silence does not expire in the future or it does not match any uptime monitor
+func newSilence(req *UptimeMonitorRequest, now time.Time) (*storage.SilenceItem, error) {
+	var silence storage.SilenceItem
+	if req.Silence != nil {
+		silence = *req.Silence
+	}
+	if silence.ID == "" {
+		silence.ID = uuid.New().String()
+	}
+	if silence.UptimeID == "" && len(silence.Match) == 0 {
+		silence.UptimeID = req.UptimeID
+	}
+	if silence.Until == 0 && req.Duration > 0 {
+		silence.Until = now.Add(time.Duration(req.Duration) * time.Minute).Unix()
+	}
+	if silence.Comment == "" {
+		silence.Comment = req.Note
+	}
+	silence.CreatedBy = req.By
+	silence.CreatedAt = now.Unix()
+
+	if silence.Until <= now.Unix() {
+		return nil, errors.New("silence must expire in the future, set its until or duration")
+	}
+	if silence.UptimeID == "" && len(silence.Match) == 0 {
+		return nil, errors.New("silence must match uptime ID or tags")
+	}
+	return &silence, nil
+}
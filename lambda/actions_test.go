@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/storage"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Given uptime monitor with announced FAIL
+// When outage is acknowledged
+// Then acknowledgement is stored in uptime status
+func TestHandleActionAcknowledge(t *testing.T) {
+	// Given
+	host := newTestHost(t, http.StatusInternalServerError)
+	webhook := newTestWebhook(t)
+	req := newTestRequest(t, "acknowledged-uptime", host, webhook)
+	runMonitor(t, req)
+	runMonitor(t, req)
+	store, _ := newStorage(nil)
+
+	// When
+	res := runMonitor(t, UptimeMonitorRequest{Action: ACTION_ACKNOWLEDGE, UptimeID: req.UptimeID, By: "alice", Note: "rolling back"})
+
+	// Then
+	status, _ := store.GetUptimeStatus(req.UptimeID)
+	assert.True(t, res.Acknowledged, "Outage was expected to be acknowledged")
+	assert.Equal(t, "alice", status.AcknowledgedBy, "Unexpected acknowledging user")
+	assert.Equal(t, "rolling back", status.AcknowledgeNote, "Unexpected note of acknowledgement")
+}
+
+// Given uptime monitor without announced status
+// When outage is acknowledged
+// Then nothing is acknowledged
+func TestHandleActionAcknowledgeNotAnnounced(t *testing.T) {
+	// Given
+	t.Setenv("STORAGE", STORAGE_MEMORY)
+
+	// When
+	res := runMonitor(t, UptimeMonitorRequest{Action: ACTION_ACKNOWLEDGE, UptimeID: "not-announced-uptime", By: "alice"})
+
+	// Then
+	assert.False(t, res.Acknowledged, "Outage was not expected to be acknowledged")
+}
+
+// Given silenced uptime monitor
+// When it fails
+//      and its silences are listed and removed
+// Then failure is not announced
+//      and silence is listed until it is removed
+func TestHandleActionSilence(t *testing.T) {
+	// Given
+	host := newTestHost(t, http.StatusInternalServerError)
+	webhook := newTestWebhook(t)
+	req := newTestRequest(t, "silenced-uptime", host, webhook)
+	silenced := runMonitor(t, UptimeMonitorRequest{Action: ACTION_SILENCE, UptimeID: req.UptimeID, Duration: 60, By: "alice"})
+
+	// When
+	runMonitor(t, req)
+	runMonitor(t, req)
+	listed := runMonitor(t, UptimeMonitorRequest{Action: ACTION_SILENCES, UptimeID: req.UptimeID})
+	runMonitor(t, UptimeMonitorRequest{Action: ACTION_UNSILENCE, UptimeID: req.UptimeID, Silence: &storage.SilenceItem{ID: silenced.Silences[0].ID}})
+	remaining := runMonitor(t, UptimeMonitorRequest{Action: ACTION_SILENCES, UptimeID: req.UptimeID})
+
+	// Then
+	assert.Empty(t, webhook.statuses(), "Failure of silenced uptime monitor was not expected to be announced")
+	assert.Len(t, listed.Silences, 1, "Silence was expected to be listed")
+	assert.Equal(t, "alice", listed.Silences[0].CreatedBy, "Unexpected author of silence")
+	assert.Empty(t, remaining.Silences, "Removed silence was not expected to be listed")
+}
+
+// Given uptime monitor without silences
+// When silence is removed
+// Then error is returned
+func TestHandleActionUnsilenceNotFound(t *testing.T) {
+	// Given
+	t.Setenv("STORAGE", STORAGE_MEMORY)
+
+	// When
+	_, err := HandleRequest(context.Background(), UptimeMonitorRequest{Action: ACTION_UNSILENCE, UptimeID: "unsilenced-uptime", Silence: &storage.SilenceItem{ID: "mistyped"}})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given uptime monitor with expired silence
+// When another silence of uptime monitor is created
+// Then expired silence is pruned
+func TestHandleActionSilencePrunesExpired(t *testing.T) {
+	// Given
+	t.Setenv("STORAGE", STORAGE_MEMORY)
+	store, _ := newStorage(nil)
+	expired := storage.SilenceItem{ID: "expired-silence", UptimeID: "pruned-uptime", Until: time.Now().Add(-time.Minute).Unix()}
+	assert.Nil(t, store.PutSilence(&expired), "Error was not expected to be returned")
+
+	// When
+	runMonitor(t, UptimeMonitorRequest{Action: ACTION_SILENCE, UptimeID: "pruned-uptime", Duration: 60})
+
+	// Then
+	silences, _ := store.ListSilences("pruned-uptime", 0)
+	assert.Len(t, silences, 1, "Expired silence was expected to be pruned")
+	assert.NotEqual(t, "expired-silence", silences[0].ID, "Unexpected remaining silence")
+}
+
+// Given silence requests
+// When silences are created
+// Then silence defaults to uptime monitor and duration of request
+//      and silence which does not expire in the future or matches nothing is rejected
+func TestNewSilence(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		name     string
+		req      UptimeMonitorRequest
+		uptimeID string
+		until    int64
+		valid    bool
+	}{
+		{"uptime monitor of request", UptimeMonitorRequest{UptimeID: "uptime-1", Duration: 10}, "uptime-1", 1600, true},
+		{"tags only", UptimeMonitorRequest{UptimeID: "uptime-1", Duration: 10, Silence: &storage.SilenceItem{Match: map[string]string{"team": "payments"}}}, "", 1600, true},
+		{"until", UptimeMonitorRequest{UptimeID: "uptime-1", Silence: &storage.SilenceItem{Until: 2000}}, "uptime-1", 2000, true},
+		{"expired", UptimeMonitorRequest{UptimeID: "uptime-1", Silence: &storage.SilenceItem{Until: 1000}}, "", 0, false},
+		{"without expiration", UptimeMonitorRequest{UptimeID: "uptime-1"}, "", 0, false},
+		{"matching nothing", UptimeMonitorRequest{Duration: 10}, "", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// When
+			silence, err := newSilence(&test.req, now)
+
+			// Then
+			if !test.valid {
+				assert.NotNil(t, err, "Error was expected to be returned")
+				return
+			}
+			assert.Nil(t, err, "Error was not expected to be returned")
+			assert.NotEmpty(t, silence.ID, "Silence ID was expected to be generated")
+			assert.Equal(t, test.uptimeID, silence.UptimeID, "Unexpected silenced uptime monitor")
+			assert.Equal(t, test.until, silence.Until, "Unexpected expiration")
+			assert.Equal(t, now.Unix(), silence.CreatedAt, "Unexpected creation time")
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/google/uuid"
+	"log"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/storage"
+	"time"
+)
+
+// Number of days back from which incidents are listed, unless request sets its start
+const INCIDENTS_DAYS = 30
+
+// Opens or closes incident of request's uptime monitor by announced status
+// Incident is opened once FAIL is announced, it starts by the first failed run of outage. FAIL replaced by FLAPPING
+// in the same run is stored as announced status, so FLAPPING opens incident from stored FAIL status too.
+// Incident is closed once recovery (OK or DEGRADED) is announced. Incidents are tracked regardless of silences.
+func trackIncident(statusReq *UptimeMonitorRequest, status sns.UptimeStatus, store storage.Storage) error {
+	now := time.Now().Unix()
+	switch status {
+	case sns.STATUS_FAIL, sns.STATUS_FLAPPING:
+		current, err := store.GetUptimeStatus(statusReq.UptimeID)
+		if err != nil || current.AnnouncedStatus() != storage.ANNOUNCED_FAIL || current.IncidentID != "" {
+			return err
+		}
+		startedAt := current.FailingSince
+		if startedAt == 0 {
+			startedAt = now
+		}
+		incident := storage.NewIncident(uuid.New().String(), statusReq.UptimeID, startedAt, now)
+		log.Printf("incident %s of uptime %s opened", incident.ID, statusReq.UptimeID)
+		return store.OpenIncident(incident)
+	case sns.STATUS_OK, sns.STATUS_DEGRADED:
+		_, err := store.CloseIncident(statusReq.UptimeID, now)
+		return err
+	}
+	return nil
+}
+
+// Appends event to open incident of uptime monitor, nothing is appended if it has no open incident
+func recordIncidentEvent(uptimeID string, eventType string, message string, at int64, store storage.Storage) error {
+	_, err := store.AddIncidentEvent(uptimeID, storage.IncidentEvent{Type: eventType, At: at, Message: message})
+	return err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/memory"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/storage"
+	"testing"
+	"time"
+)
+
+// Given uptime monitor whose FAIL has been announced
+// When FAIL is tracked twice
+//      and event is recorded
+//      and recovery is tracked
+// Then single incident is opened from the first failed run
+//      and event is appended to its timeline
+//      and incident is closed
+func TestTrackIncident(t *testing.T) {
+	// Given
+	store := memory.NewStorage()
+	statusReq := &UptimeMonitorRequest{UptimeID: "anyUptimeId"}
+	_, _ = store.UpdateUptimeStatus(statusReq.UptimeID, 0)
+	failing, _ := store.GetUptimeStatus(statusReq.UptimeID)
+
+	// When
+	openErr := trackIncident(statusReq, sns.STATUS_FAIL, store)
+	opened, _ := store.GetUptimeStatus(statusReq.UptimeID)
+	alreadyOpenErr := trackIncident(statusReq, sns.STATUS_FAIL, store)
+	eventErr := recordIncidentEvent(statusReq.UptimeID, storage.EVENT_NOTIFIED, "FAIL announced", time.Now().Unix(), store)
+	_, _ = store.RecoverUptimeStatus(statusReq.UptimeID, 1)
+	closeErr := trackIncident(statusReq, sns.STATUS_OK, store)
+
+	// Then
+	assert.Nil(t, openErr, "Error was not expected to be returned")
+	assert.Nil(t, alreadyOpenErr, "Error was not expected to be returned")
+	assert.Nil(t, eventErr, "Error was not expected to be returned")
+	assert.Nil(t, closeErr, "Error was not expected to be returned")
+	incidents, _ := store.ListIncidents(statusReq.UptimeID, 0, time.Now().Unix())
+	assert.Len(t, incidents, 1, "Single incident was expected to be opened")
+	assert.Equal(t, opened.IncidentID, incidents[0].ID, "Incident was expected to be linked to uptime status")
+	assert.Equal(t, failing.FailingSince, incidents[0].StartedAt, "Incident was expected to start by the first failed run")
+	assert.Equal(t, storage.EVENT_NOTIFIED, incidents[0].Timeline[2].Type, "Event was expected to be appended")
+	assert.False(t, incidents[0].IsOpen(), "Incident was expected to be closed")
+	closed, _ := store.GetUptimeStatus(statusReq.UptimeID)
+	assert.Empty(t, closed.IncidentID, "Uptime status was not expected to have open incident")
+}
+
+// Given uptime monitor whose FAIL has been stored as announced status
+//       and FLAPPING has been detected in the same run
+// When FLAPPING is tracked
+//      and monitor recovers and fails again while flapping
+// Then incident is opened from stored FAIL status
+//      and another incident is opened after the first one has been closed
+func TestTrackIncidentFlapping(t *testing.T) {
+	// Given
+	store := memory.NewStorage()
+	statusReq := &UptimeMonitorRequest{UptimeID: "anyUptimeId"}
+	_, _ = store.UpdateUptimeStatus(statusReq.UptimeID, 0)
+
+	// When
+	firstErr := trackIncident(statusReq, sns.STATUS_FLAPPING, store)
+	_, _ = store.RecoverUptimeStatus(statusReq.UptimeID, 1)
+	closeErr := trackIncident(statusReq, sns.STATUS_OK, store)
+	_, _ = store.UpdateUptimeStatus(statusReq.UptimeID, 0)
+	reopenErr := trackIncident(statusReq, sns.STATUS_FLAPPING, store)
+
+	// Then
+	assert.Nil(t, firstErr, "Error was not expected to be returned")
+	assert.Nil(t, closeErr, "Error was not expected to be returned")
+	assert.Nil(t, reopenErr, "Error was not expected to be returned")
+	incidents, _ := store.ListIncidents(statusReq.UptimeID, 0, time.Now().Unix())
+	assert.Len(t, incidents, 2, "Incident was expected to be reopened")
+	open := 0
+	for _, incident := range incidents {
+		if incident.IsOpen() {
+			open++
+		}
+	}
+	assert.Equal(t, 1, open, "Only the second incident was expected to be open")
+}
+
+// Given uptime monitor without announced FAIL
+// When FLAPPING is tracked
+// Then no incident is opened
+func TestTrackIncidentFlappingWithoutFail(t *testing.T) {
+	// Given
+	store := memory.NewStorage()
+	statusReq := &UptimeMonitorRequest{UptimeID: "anyUptimeId"}
+	_, _ = store.RecoverUptimeStatus(statusReq.UptimeID, 1)
+
+	// When
+	err := trackIncident(statusReq, sns.STATUS_FLAPPING, store)
+
+	// Then
+	assert.Nil(t, err, "Error was not expected to be returned")
+	incidents, _ := store.ListIncidents(statusReq.UptimeID, 0, time.Now().Unix())
+	assert.Empty(t, incidents, "Incident was not expected to be opened")
+}
@@ -4,33 +4,113 @@ import (
 	"context"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws/session"
-	dynamodbAPI "github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
-	snsAPI "github.com/aws/aws-sdk-go/service/sns"
-	"github.com/google/uuid"
-	"monitor-uptime/internal/dynamodb"
+	"monitor-uptime/internal/escalation"
+	"monitor-uptime/internal/maintenance"
+	"monitor-uptime/internal/notifier"
 	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/storage"
 	"monitor-uptime/internal/uptime"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 )
 
+// Types of uptime monitor
+const (
+	MONITOR_HTTP = "http"
+	MONITOR_PING = "ping"
+	MONITOR_TCP  = "tcp"
+)
+
 // Represents uptime monitor service request
 type UptimeMonitorRequest struct {
-	UptimeID    string `json:"uptimeId"`    // Uptime ID that invoked service
-	Host        string `json:"host"`        // Host for which uptime will be invoked
-	StatusCodes []int  `json:"statusCodes"` // Expected status code
+	UptimeID          string               `json:"uptimeId"`          // Uptime ID that invoked service
+	Type              string               `json:"type"`              // Type of monitor, either http (default), ping or tcp
+	Host              string               `json:"host"`              // Host for which uptime will be invoked, host:port for tcp monitor
+	StatusCodes       []int                `json:"statusCodes"`       // Expected status code
+	FailThreshold     int                  `json:"failThreshold"`     // Number of consecutive failures tolerated, FAIL is announced by the next one (threshold + 1)
+	RecoveryThreshold int                  `json:"recoveryThreshold"` // Number of consecutive successes needed, OK is announced by the last of them (unlike failThreshold)
+	DegradedThreshold int                  `json:"degradedThreshold"` // Number of consecutive slow runs needed, DEGRADED is announced by the last of them (unlike failThreshold)
+	FlapWindow        int                  `json:"flapWindow"`        // Number of recent runs whose outcome changes are counted by flap detection, 0 means no detection
+	FlapThreshold     int                  `json:"flapThreshold"`     // Flap score (percentage of outcome changes) at which uptime monitor starts flapping
+	FlapStable        int                  `json:"flapStable"`        // Flap score below which flapping uptime monitor is stable again
+	WarnTTFB          int64                `json:"warnTtfb"`          // Run is degraded if TTFB exceeds this number of milliseconds, 0 means no limit
+	WarnTotal         int64                `json:"warnTotal"`         // Run is degraded if total request time exceeds this number of milliseconds, 0 means no limit
+	PingCount         int                  `json:"pingCount"`         // Number of ICMP echo requests sent by ping monitor
+	MaxPacketLoss     float64              `json:"maxPacketLoss"`     // Maximal tolerated packet loss percentage of ping monitor
+	MaxAvgRTT         int64                `json:"maxAvgRtt"`         // Maximal tolerated average round-trip time of ping monitor in milliseconds, 0 means no limit
+	Payload           string               `json:"payload"`           // Payload sent by tcp monitor once connection is established
+	ExpectPrefix      string               `json:"expectPrefix"`      // Prefix which response of tcp monitor is expected to start with
+	ExpectRegex       string               `json:"expectRegex"`       // Regular expression which response of tcp monitor is expected to match
+	CertExpiryDays    int                  `json:"certExpiryDays"`    // Run fails if TLS certificate expires within this number of days, 0 means no check
+	CertWarnDays      int                  `json:"certWarnDays"`      // Run is degraded if TLS certificate expires within this number of days, 0 means no check
+	Assertions        []uptime.Assertion   `json:"assertions"`        // Assertions evaluated against HTTP response
+	Request           *uptime.RequestSpec  `json:"request"`           // HTTP request (method, headers, body, auth) sent by http monitor, secrets may be references (env:, secretsmanager:, ssm:)
+	Channels          []notifier.Channel   `json:"channels"`          // Channels to which status transitions are announced in addition to routed ones, SNS_TOPIC is used if there are none
+	Tags              map[string]string    `json:"tags"`              // Tags of monitor (e.g. team, env, severity) used by routing rules
+	Templates         map[string]string    `json:"templates"`         // Templates overriding default notification templates by their names (title, summary, text, email.subject, email.text, email.html)
+	Escalation        *escalation.Policy   `json:"escalation"`        // Reminders and escalation of persisting outage, FAIL is announced only once if not set
+	Action            string               `json:"action"`            // Action of request, either empty (run monitor), acknowledge, silence, unsilence, silences, incidents, incident, note or deliveries
+	By                string               `json:"by"`                // Who acknowledges outage, creates silence or adds note to incident
+	Note              string               `json:"note"`              // Note of acknowledgement or incident, or comment of silence
+	Silence           *storage.SilenceItem `json:"silence"`           // Silence created by silence action, its ID and uptime ID (or request's one) are removed by unsilence action
+	Duration          int                  `json:"duration"`          // Minutes for which silence lasts, unless silence sets its expiration
+	Maintenance       []maintenance.Window `json:"maintenance"`       // Maintenance windows of monitor in addition to windows of MAINTENANCE_CONFIG
+	Parents           []string             `json:"parents"`           // Uptime IDs of parent monitors, failures while any parent is down are folded into its outage
+	IncidentID        string               `json:"incidentId"`        // Incident returned by incident action or annotated by note action
+	From              int64                `json:"from"`              // Timestamp from which incidents are listed, 30 days before to by default
+	To                int64                `json:"to"`                // Timestamp until which incidents are listed, now by default
 }
 
 // Represents uptime monitor service response
 type UptimeMonitorResponse struct {
-	Host         string `json:"host"`
-	StatusCode   int    `json:"statusCode"`   // Resulted status code
-	TTFB         int64  `json:"ttfb"`         // Measured Time To First Byte in milliseconds
-	DNSLookup    int64  `json:"dnslookup"`    // Measured duration of DNS lookup in milliseconds
-	TLSHandshake int64  `json:"tlshandshake"` // Measured duration of TLS handshake in milliseconds
+	Host             string                        `json:"host"`
+	StatusCode       int                           `json:"statusCode"`                    // Resulted status code
+	TTFB             int64                         `json:"ttfb"`                          // Measured Time To First Byte in milliseconds
+	DNSLookup        int64                         `json:"dnslookup"`                     // Measured duration of DNS lookup in milliseconds
+	TLSHandshake     int64                         `json:"tlshandshake"`                  // Measured duration of TLS handshake in milliseconds
+	ServerProcessing int64                         `json:"serverProcessing,omitempty"`    // Measured duration between request has been sent and first response byte in milliseconds
+	ContentTransfer  int64                         `json:"contentTransfer,omitempty"`     // Measured duration of response body transfer in milliseconds
+	Total            int64                         `json:"total,omitempty"`               // Measured duration of whole request in milliseconds
+	ResponseSize     int64                         `json:"responseSize,omitempty"`        // Size of response body in bytes
+	ConnReused       bool                          `json:"connReused,omitempty"`          // Whether previously opened connection has been reused
+	PacketsSent      int                           `json:"packetsSent,omitempty"`         // Number of sent ICMP echo requests
+	PacketsRecv      int                           `json:"packetsRecv,omitempty"`         // Number of received ICMP echo replies
+	PacketLoss       float64                       `json:"packetLoss,omitempty"`          // Percentage of lost ICMP packets
+	MinRTT           float64                       `json:"minRtt,omitempty"`              // Minimal round-trip time in milliseconds
+	AvgRTT           float64                       `json:"avgRtt,omitempty"`              // Average round-trip time in milliseconds
+	MaxRTT           float64                       `json:"maxRtt,omitempty"`              // Maximal round-trip time in milliseconds
+	StdDevRTT        float64                       `json:"stdDevRtt,omitempty"`           // Standard deviation of round-trip times in milliseconds
+	TCPConnect       int64                         `json:"tcpConnect,omitempty"`          // Measured duration of TCP connect in milliseconds (http and tcp monitors)
+	Response         string                        `json:"response,omitempty"`            // Response (banner) received by tcp monitor
+	ResponseMatched  *bool                         `json:"responseMatched,omitempty"`     // Whether response of tcp monitor matches expectations
+	CertSubject      string                        `json:"certSubject,omitempty"`         // Subject of TLS leaf certificate
+	CertIssuer       string                        `json:"certIssuer,omitempty"`          // Issuer of TLS leaf certificate
+	CertSANs         []string                      `json:"certSans,omitempty"`            // Subject alternative names of TLS leaf certificate
+	CertNotAfter     int64                         `json:"certNotAfter,omitempty"`        // Timestamp after which TLS leaf certificate is not valid
+	CertExpiryDays   *int                          `json:"certDaysUntilExpiry,omitempty"` // Number of days until TLS leaf certificate expires
+	CertError        string                        `json:"certError,omitempty"`           // Error of TLS certificate chain verification
+	Assertions       []AssertionResponse           `json:"assertions,omitempty"`          // Results of assertions evaluated against HTTP response
+	Reason           string                        `json:"reason,omitempty"`              // Reason why run failed, empty if host is up
+	Warning          string                        `json:"warning,omitempty"`             // Reason why run is degraded, empty if host is not slow
+	ErrorClass       string                        `json:"errorClass,omitempty"`          // Class of probe failure (e.g. dns_error, timeout), if host could not be probed
+	Error            string                        `json:"error,omitempty"`               // Message of probe failure, if host could not be probed
+	Acknowledged     bool                          `json:"acknowledged,omitempty"`        // Whether outage has been acknowledged by acknowledge action
+	Silences         []storage.SilenceItem         `json:"silences,omitempty"`            // Silence created by silence action or active silences listed by silences action
+	Maintenance      string                        `json:"maintenance,omitempty"`         // Maintenance window during which run has been executed, run is not counted then
+	FlapScore        int                           `json:"flapScore,omitempty"`           // Flap score of uptime monitor which started flapping
+	Parent           string                        `json:"parent,omitempty"`              // Failing parent monitor which made host unreachable, run is not counted then
+	Incidents        []storage.IncidentItem        `json:"incidents,omitempty"`           // Incidents listed by incidents action or incident returned by incident action
+	Deliveries       []storage.DeliveryAttemptItem `json:"deliveries,omitempty"`          // Delivery attempts listed by deliveries action
+}
+
+// Represents result of single assertion evaluated against HTTP response
+type AssertionResponse struct {
+	Type    string `json:"type"`
+	Target  string `json:"target,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"` // Describes why assertion failed
 }
 
 // Get environment variable as string
@@ -51,7 +131,17 @@ func getEnvStringWithDefault(key string, defaultValue string) string {
 	return defaultValue
 }
 
-// Get environment variable as string
+// Get environment variable as boolean
+// If environment variable is not set, then default value is returned instead
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		boolValue, _ := strconv.ParseBool(value)
+		return boolValue
+	}
+	return defaultValue
+}
+
+// Get environment variable as integer
 // If environment variable is not set, then default value is returned instead
 func getEnvInt(key string, defaultValue int) int {
 	if value, ok := os.LookupEnv(key); ok {
@@ -61,127 +151,110 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
-// Get uptime monitor response for provided host
-// In case of failure error is returned
-func response(host string) (*UptimeMonitorResponse, error) {
-	hostUrl := sanityHTTPProtocol(host)
-	response, err := uptime.GetUptime(hostUrl, getEnvInt("TIMEOUT", 4))
-	if err != nil {
-		return nil, err
+// Get positive threshold value
+// If threshold is not provided (non-positive), then value of environment variable or default value is used instead
+func threshold(value int, key string, defaultValue int) int {
+	if value > 0 {
+		return value
 	}
-
-	return &UptimeMonitorResponse{
-		Host:         hostUrl,
-		StatusCode:   response.StatusCode,
-		TTFB:         response.TTFB.Milliseconds(),
-		DNSLookup:    response.DNSLookup.Milliseconds(),
-		TLSHandshake: response.TLSHandshake.Milliseconds(),
-	}, nil
+	return getEnvInt(key, defaultValue)
 }
 
-// Makes sure that host always contains protocol part
-// If not provided explicitly HTTPS is added by default
-func sanityHTTPProtocol(host string) string {
-	if !(strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://")) {
-		return "https://" + host
+// Handles uptime monitor lambda request
+// Get uptime response with measured metrics and stored it into storage (DynamoDB by default)
+// If host cannot be probed or result does not match expectations (e.g. status code, assertions) provided in request,
+// then run is counted as failed and notification is sent to monitor's channels once threshold is crossed.
+// Transition which has not been delivered (e.g. notification failed or monitor was silenced) is announced by next runs,
+// failed notifications are logged and recorded as delivery attempts rather than returned, as the run is stored already.
+// Monitor whose runs change status too often is flapping, single FLAPPING notification is sent instead of transitions
+// until it is stable again.
+// While outage persists, reminders and escalation are sent according to monitor's escalation policy.
+// Announced outage is recorded as incident with timeline of its notifications until recovery is announced.
+// During maintenance window run is stored flagged as maintenance, but it is not counted and nothing is notified
+// except announced start and end of window.
+// Failure while any parent monitor is down marks monitor UNREACHABLE_DUE_TO_PARENT and it is folded into parent's outage.
+// Requests with action acknowledge outages, manage silences and review incidents instead of running monitor.
+// In case of failure error is returned
+func HandleRequest(ctx context.Context, req UptimeMonitorRequest) (UptimeMonitorResponse, error) {
+	sessionOptions := session.Options{SharedConfigState: session.SharedConfigEnable}
+	if req.Action != ACTION_MONITOR {
+		return handleAction(&req, &sessionOptions)
 	}
-	return host
-}
-
-// Stores uptime into Dynamo DB
-func storeUptime(statusReq *UptimeMonitorRequest, response *UptimeMonitorResponse, db dynamodbiface.DynamoDBAPI) error {
-	tableName := getEnvString("DYNAMO_TABLE_EXECUTIONS")
-	if tableName == nil {
-		return nil
-	}
-	return dynamodb.StoreUptimeResult(&dynamodb.UptimeResultItem{
-		RequestID:    uuid.New().String(),
-		UptimeID:     statusReq.UptimeID,
-		RunAt:        time.Now().Unix(),
-		Host:         statusReq.Host,
-		StatusCode:   response.StatusCode,
-		TTFB:         response.TTFB,
-		DNSLookup:    response.DNSLookup,
-		TLSHandshake: response.TLSHandshake,
-	}, *tableName, db)
-}
-
-// Updates uptime status in Dynamo DB
-// If status has been changed (e.g. cross threshold or uptime went from Fail to OK), then new status is returned
-func updateUptimeStatus(statusReq *UptimeMonitorRequest,
-	response *UptimeMonitorResponse,
-	db dynamodbiface.DynamoDBAPI) (*sns.UptimeStatus, error) {
-	var err error
-	var notify bool
-	var status sns.UptimeStatus
-
-	dbStatus := getEnvStringWithDefault("DYNAMO_TABLE_STATUS", "uptimeStatus")
-
-	if hasExpectedStatusCode(response.StatusCode, statusReq.StatusCodes) {
-		status = sns.STATUS_OK
-		notify, err = dynamodb.ClearUptimeStatus(statusReq.UptimeID, dbStatus, db)
-	} else {
-		status = sns.STATUS_FAIL
-		notify, err = dynamodb.UpdateUptimeStatus(statusReq.UptimeID, "3", dbStatus, db)
-	}
-
-	if err == nil {
-		if notify {
-			return &status, nil
-		} else {
-			return nil, nil
-		}
-	} else {
-		return nil, err
+	res, err := response(&req, &sessionOptions)
+	if err != nil {
+		return UptimeMonitorResponse{}, err
 	}
-}
-
-// Notify uptime monitor status via SNS
-func notifyUptimeStatus(uptimeId string, status sns.UptimeStatus, sessionOptions *session.Options) error {
-	snsTopicName := getEnvString("SNS_TOPIC")
-
-	if snsTopicName != nil {
-		snsClient := snsAPI.New(session.Must(session.NewSessionWithOptions(*sessionOptions)))
-		return sns.PublishUptimeStatus(&sns.UptimeNotification{Status: status}, uptimeId, *snsTopicName, snsClient)
+	res.Reason = failureReason(&req, res)
+	if res.Reason == "" {
+		res.Warning = degradedReason(&req, res)
 	}
-	return nil
-}
 
-// Checks whether resulted status code matches to requested expectations
-func hasExpectedStatusCode(actualStatusCode int, expectedStatusCodes []int) bool {
-	for _, expectedStatusCode := range expectedStatusCodes {
-		if expectedStatusCode == actualStatusCode {
-			return true
-		}
+	window, err := activeMaintenance(&req, time.Now())
+	if err != nil {
+		return UptimeMonitorResponse{}, err
+	}
+	if window != nil {
+		res.Maintenance = window.Name
 	}
-	return false
-}
 
-// Handles uptime monitor lambda request
-// Get uptime response with measured metrics and stored it into DynamoDB
-// If resulted status code is not in expected status code provided in request, then send notification to SNS topic
-// In case of failure error is returned
-func HandleRequest(ctx context.Context, req UptimeMonitorRequest) (UptimeMonitorResponse, error) {
-	res, err := response(req.Host)
+	store, err := newStorage(&sessionOptions)
 	if err != nil {
 		return UptimeMonitorResponse{}, err
 	}
-
-	sessionOptions := session.Options{SharedConfigState: session.SharedConfigEnable}
-	db := dynamodbAPI.New(session.Must(session.NewSessionWithOptions(sessionOptions)))
-	if err = storeUptime(&req, res, db); err != nil {
+	if res.Reason != "" && window == nil {
+		if res.Parent, err = failingParent(&req, store); err != nil {
+			return UptimeMonitorResponse{}, err
+		}
+	}
+	if err = storeUptime(&req, res, store); err != nil {
 		return UptimeMonitorResponse{}, err
 	}
 
+	previous, err := store.GetUptimeStatus(req.UptimeID)
+	if err != nil {
+		return UptimeMonitorResponse{}, err
+	}
+	if window != nil {
+		if err = enterMaintenance(&req, res, previous, window, store, &sessionOptions); err != nil {
+			return UptimeMonitorResponse{}, err
+		}
+		return *res, nil
+	}
+	if previous != nil && previous.Maintenance != "" {
+		if err = leaveMaintenance(&req, res, previous, store, &sessionOptions); err != nil {
+			return UptimeMonitorResponse{}, err
+		}
+	}
+	if res.Parent != "" {
+		if err = foldIntoParent(&req, res, previous, store, &sessionOptions); err != nil {
+			return UptimeMonitorResponse{}, err
+		}
+		return *res, nil
+	}
 	var status *sns.UptimeStatus
-	status, err = updateUptimeStatus(&req, res, db)
+	status, err = updateUptimeStatus(&req, res, store)
 	if err != nil {
 		return UptimeMonitorResponse{}, err
 	}
+	flapping, err := detectFlapping(&req, res, previous, store)
+	if err != nil {
+		return UptimeMonitorResponse{}, err
+	}
+	if flapping != nil {
+		status = flapping
+	}
 	if status != nil {
-		if err = notifyUptimeStatus(req.UptimeID, *status, &sessionOptions); err != nil {
+		if err = trackIncident(&req, *status, store); err != nil {
 			return UptimeMonitorResponse{}, err
 		}
+		err = announceUptimeStatus(&req, res, previous, *status, store, &sessionOptions)
+	} else if pending := previous.PendingAnnouncement(); pending != "" {
+		err = announceUptimeStatus(&req, res, previous, sns.UptimeStatus(pending), store, &sessionOptions)
+	} else if res.Reason != "" {
+		err = escalateUptimeStatus(&req, res, previous, store, &sessionOptions)
+	}
+	if err != nil {
+		return UptimeMonitorResponse{}, err
 	}
 
 	return *res, nil
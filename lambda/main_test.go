@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/notifier"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Represents host probed by uptime monitor, it responds with status code which can be changed between runs
+type testHost struct {
+	*httptest.Server
+	statusCode int32
+}
+
+// Starts host responding with status code, host is closed once test finishes
+func newTestHost(t *testing.T, statusCode int) *testHost {
+	host := &testHost{statusCode: int32(statusCode)}
+	host.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&host.statusCode)))
+	}))
+	t.Cleanup(host.Close)
+	return host
+}
+
+// Changes status code responded by host
+func (h *testHost) respond(statusCode int) {
+	atomic.StoreInt32(&h.statusCode, int32(statusCode))
+}
+
+// Represents webhook receiving notifications of uptime monitor, it can be made failing between runs
+type testWebhook struct {
+	*httptest.Server
+	mutex      sync.Mutex
+	payloads   []notifier.WebhookPayload
+	statusCode int32 // Status code responded instead of receiving payload, 0 if payloads are received
+}
+
+// Starts webhook collecting received payloads, webhook is closed once test finishes
+func newTestWebhook(t *testing.T) *testWebhook {
+	webhook := &testWebhook{}
+	webhook.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if statusCode := atomic.LoadInt32(&webhook.statusCode); statusCode != 0 {
+			w.WriteHeader(int(statusCode))
+			return
+		}
+		var payload notifier.WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		webhook.mutex.Lock()
+		defer webhook.mutex.Unlock()
+		webhook.payloads = append(webhook.payloads, payload)
+	}))
+	t.Cleanup(webhook.Close)
+	return webhook
+}
+
+// Changes status code responded by webhook instead of receiving payloads, 0 makes it receive them again
+func (w *testWebhook) respond(statusCode int) {
+	atomic.StoreInt32(&w.statusCode, int32(statusCode))
+}
+
+// Returns statuses of received notifications in order of their delivery
+func (w *testWebhook) statuses() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var statuses []string
+	for _, payload := range w.payloads {
+		statuses = append(statuses, string(payload.Status))
+	}
+	return statuses
+}
+
+// Returns channel of webhook
+func (w *testWebhook) channel() notifier.Channel {
+	return notifier.Channel{Type: notifier.CHANNEL_WEBHOOK, WebhookURL: w.URL}
+}
+
+// Creates request of uptime monitor probing host and notifying webhook
+// Monitor uses shared in-memory storage, so its uptime ID is expected to be unique among tests.
+func newTestRequest(t *testing.T, uptimeID string, host *testHost, webhook *testWebhook) UptimeMonitorRequest {
+	t.Setenv("STORAGE", STORAGE_MEMORY)
+	return UptimeMonitorRequest{
+		UptimeID:          uptimeID,
+		Host:              host.URL,
+		StatusCodes:       []int{http.StatusOK},
+		FailThreshold:     1,
+		RecoveryThreshold: 1,
+		Channels:          []notifier.Channel{webhook.channel()},
+	}
+}
+
+// Handles request of uptime monitor run, test fails if it returns error
+func runMonitor(t *testing.T, req UptimeMonitorRequest) UptimeMonitorResponse {
+	res, err := HandleRequest(context.Background(), req)
+	assert.Nil(t, err, "Error was not expected to be returned")
+	return res
+}
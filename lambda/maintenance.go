@@ -0,0 +1,112 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws/session"
+	"monitor-uptime/internal/maintenance"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/storage"
+	"sync"
+	"time"
+)
+
+// Maintenance configuration loaded once from MAINTENANCE_CONFIG environment variable
+var (
+	maintenanceOnce   sync.Once
+	maintenanceConfig *maintenance.Config
+	maintenanceErr    error
+)
+
+// Get maintenance configuration, nil is returned if MAINTENANCE_CONFIG environment variable is not set
+// MAINTENANCE_CONFIG is path to JSON file or inline JSON, it is loaded by the first call only
+func getMaintenanceConfig() (*maintenance.Config, error) {
+	maintenanceOnce.Do(func() {
+		if value := getEnvString("MAINTENANCE_CONFIG"); value != nil {
+			maintenanceConfig, maintenanceErr = maintenance.Load(*value)
+		}
+	})
+	return maintenanceConfig, maintenanceErr
+}
+
+// Returns maintenance window of request's uptime monitor active at time, nil if there is none
+// Windows of request take precedence over windows matched by maintenance configuration (if set).
+// Returns error if any window of request is not valid.
+func activeMaintenance(statusReq *UptimeMonitorRequest, at time.Time) (*maintenance.Window, error) {
+	for _, window := range statusReq.Maintenance {
+		if err := window.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if window := maintenance.Active(statusReq.Maintenance, at); window != nil {
+		return window, nil
+	}
+	config, err := getMaintenanceConfig()
+	if err != nil || config == nil {
+		return nil, err
+	}
+	return config.Active(statusReq.UptimeID, statusReq.Tags, at), nil
+}
+
+// Announces start of maintenance window as MAINTENANCE status, if window should be announced
+// Start is announced only once per window, window is stored in uptime status so its end is announced later.
+func enterMaintenance(statusReq *UptimeMonitorRequest,
+	response *UptimeMonitorResponse,
+	previous *storage.UptimeStatusItem,
+	window *maintenance.Window,
+	store storage.Storage,
+	sessionOptions *session.Options) error {
+	if !window.Announce {
+		return nil
+	}
+	changed, err := store.SetMaintenance(statusReq.UptimeID, window.Name)
+	if err != nil || !changed {
+		return err
+	}
+	return announceMaintenance(statusReq, response, previous, window.Name, sns.STATUS_MAINTENANCE, store, sessionOptions)
+}
+
+// Announces end of maintenance window whose start has been announced
+// Uptime monitor returns to its announced status, which is announced as transition from MAINTENANCE status.
+func leaveMaintenance(statusReq *UptimeMonitorRequest,
+	response *UptimeMonitorResponse,
+	previous *storage.UptimeStatusItem,
+	store storage.Storage,
+	sessionOptions *session.Options) error {
+	changed, err := store.SetMaintenance(statusReq.UptimeID, "")
+	if err != nil || !changed {
+		return err
+	}
+	status := sns.UptimeStatus(previous.AnnouncedStatus())
+	return announceMaintenance(statusReq, response, previous, previous.Maintenance, status, store, sessionOptions)
+}
+
+// Announces start (MAINTENANCE status) or end (announced status) of maintenance window to channels of request
+// Announcement is not sent while uptime monitor is silenced, nor it is recorded as notification of announced status.
+func announceMaintenance(statusReq *UptimeMonitorRequest,
+	response *UptimeMonitorResponse,
+	previous *storage.UptimeStatusItem,
+	window string,
+	status sns.UptimeStatus,
+	store storage.Storage,
+	sessionOptions *session.Options) error {
+	channels, err := notificationChannels(statusReq, status)
+	if err != nil {
+		return err
+	}
+	notification, err := newNotification(statusReq, response, previous, status, channels, store)
+	if err != nil {
+		return err
+	}
+	notification.Maintenance = window
+	notification.Reason = ""
+	notification.ErrorClass = ""
+	notification.FailCounter = 0
+	notification.OutageStart = time.Time{}
+	if status != sns.STATUS_MAINTENANCE {
+		notification.PreviousStatus = sns.STATUS_MAINTENANCE
+	}
+	if silenced, err := isSilenced(statusReq, notification, store); silenced || err != nil {
+		return err
+	}
+	_, err = notifyUptimeStatus(channels, notification, store, sessionOptions)
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/maintenance"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Given failing uptime monitor with announced maintenance window
+// When it runs during maintenance window
+//      and after maintenance window ends
+// Then start and end of maintenance window are announced once
+//      and failures during maintenance window are neither counted nor announced
+//      and failures after maintenance window are counted and announced
+func TestHandleRequestMaintenance(t *testing.T) {
+	// Given
+	host := newTestHost(t, http.StatusInternalServerError)
+	webhook := newTestWebhook(t)
+	req := newTestRequest(t, "maintenance-uptime", host, webhook)
+	now := time.Now().UTC()
+	req.Maintenance = []maintenance.Window{{
+		Name:     "migration",
+		Start:    now.Add(-time.Hour).Format(time.RFC3339),
+		End:      now.Add(time.Hour).Format(time.RFC3339),
+		Announce: true,
+	}}
+	store, _ := newStorage(nil)
+
+	// When
+	var during []UptimeMonitorResponse
+	for i := 0; i < 3; i++ {
+		during = append(during, runMonitor(t, req))
+	}
+	duringStatus, _ := store.GetUptimeStatus(req.UptimeID)
+	duringNotifications := webhook.statuses()
+	req.Maintenance = nil
+	runMonitor(t, req)
+	afterStatus, _ := store.GetUptimeStatus(req.UptimeID)
+	runMonitor(t, req)
+
+	// Then
+	for _, res := range during {
+		assert.Equal(t, "migration", res.Maintenance, "Run was expected to be flagged as maintenance")
+	}
+	assert.Equal(t, 0, duringStatus.FailCounter, "Failures during maintenance window were not expected to be counted")
+	assert.Equal(t, "migration", duringStatus.Maintenance, "Announced maintenance window was expected to be stored")
+	assert.Equal(t, []string{"MAINTENANCE"}, duringNotifications, "Only start of maintenance window was expected to be announced")
+	assert.Equal(t, 1, afterStatus.FailCounter, "Failure after maintenance window was expected to be counted")
+	assert.Empty(t, afterStatus.Maintenance, "Maintenance window was expected to end")
+	assert.Equal(t, []string{"MAINTENANCE", "OK", "FAIL"}, webhook.statuses(), "Unexpected notifications")
+}
+
+// Given failing uptime monitor with maintenance window which is not announced
+// When it runs during maintenance window
+// Then nothing is announced
+//      and failures are not counted
+func TestHandleRequestMaintenanceNotAnnounced(t *testing.T) {
+	// Given
+	host := newTestHost(t, http.StatusInternalServerError)
+	webhook := newTestWebhook(t)
+	req := newTestRequest(t, "silent-maintenance-uptime", host, webhook)
+	req.Maintenance = []maintenance.Window{{Name: "nightly", Schedule: "* * * * *", Duration: 1}}
+	store, _ := newStorage(nil)
+
+	// When
+	for i := 0; i < 3; i++ {
+		runMonitor(t, req)
+	}
+
+	// Then
+	status, _ := store.GetUptimeStatus(req.UptimeID)
+	assert.Nil(t, status, "Uptime status was not expected to be created by runs during maintenance window")
+	assert.Empty(t, webhook.statuses(), "Nothing was expected to be announced")
+}
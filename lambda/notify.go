@@ -0,0 +1,442 @@
+package main
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/session"
+	secretsmanagerAPI "github.com/aws/aws-sdk-go/service/secretsmanager"
+	snsAPI "github.com/aws/aws-sdk-go/service/sns"
+	ssmAPI "github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/google/uuid"
+	"log"
+	"monitor-uptime/internal/notifier"
+	"monitor-uptime/internal/routing"
+	"monitor-uptime/internal/secrets"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/storage"
+	"monitor-uptime/internal/templates"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Routing configuration loaded once from ROUTING_CONFIG environment variable
+var (
+	routingOnce   sync.Once
+	routingConfig *routing.Config
+	routingErr    error
+)
+
+// Get routing configuration, nil is returned if ROUTING_CONFIG environment variable is not set
+// ROUTING_CONFIG is path to JSON file or inline JSON, it is loaded by the first call only
+func getRoutingConfig() (*routing.Config, error) {
+	routingOnce.Do(func() {
+		if value := getEnvString("ROUTING_CONFIG"); value != nil {
+			routingConfig, routingErr = routing.Load(*value)
+		}
+	})
+	return routingConfig, routingErr
+}
+
+// Returns channels to which transition to status is announced
+// Channels of request are extended by channels routed by request's tags using routing configuration (if set).
+// If there are no channels, then notification is published to SNS_TOPIC only (if set)
+func notificationChannels(statusReq *UptimeMonitorRequest, status sns.UptimeStatus) ([]notifier.Channel, error) {
+	config, err := getRoutingConfig()
+	if err != nil {
+		return nil, err
+	}
+	channels := routeChannels(statusReq.Channels, config, statusReq.Tags, status, time.Now())
+	if len(channels) == 0 && getEnvString("SNS_TOPIC") != nil {
+		channels = []notifier.Channel{{Type: notifier.CHANNEL_SNS}}
+	}
+	return channels, nil
+}
+
+// Returns channels extended by channels of routes matching tags and status at time
+// Default channels of routing configuration are used only if there are neither channels nor matching routes.
+func routeChannels(
+	channels []notifier.Channel,
+	config *routing.Config,
+	tags map[string]string,
+	status sns.UptimeStatus,
+	at time.Time) []notifier.Channel {
+	routed := append([]notifier.Channel(nil), channels...)
+	if config != nil {
+		routed = append(routed, config.Match(tags, string(status), at)...)
+		if len(routed) == 0 {
+			routed = config.DefaultChannels()
+		}
+	}
+	return uniqueChannels(routed)
+}
+
+// Returns channels without duplicates, the first of channels with the same identity is kept
+func uniqueChannels(channels []notifier.Channel) []notifier.Channel {
+	var unique []notifier.Channel
+	seen := map[string]bool{}
+	for _, channel := range channels {
+		if key := channel.Key(); !seen[key] {
+			seen[key] = true
+			unique = append(unique, channel)
+		}
+	}
+	return unique
+}
+
+// Creates notifiers of channels
+// Webhook URLs and keys may reference secrets, returns error if they cannot be resolved or channel type is unsupported
+func newNotifiers(channels []notifier.Channel, sessionOptions *session.Options) ([]notifier.Notifier, error) {
+	sess := session.Must(session.NewSessionWithOptions(*sessionOptions))
+	client := &http.Client{Timeout: time.Duration(getEnvInt("TIMEOUT", 4)) * time.Second}
+	var notifiers []notifier.Notifier
+	for _, channel := range channels {
+		if channel.Type == notifier.CHANNEL_SNS {
+			topicARN := channel.TopicARN
+			if topicARN == "" {
+				topicARN = getEnvStringWithDefault("SNS_TOPIC", "")
+			}
+			notifiers = append(notifiers, notifier.NewSNS(topicARN, snsAPI.New(sess)))
+			continue
+		}
+
+		resolved, err := resolveChannel(channel, sess)
+		if err != nil {
+			return nil, err
+		}
+		switch channel.Type {
+		case notifier.CHANNEL_SLACK:
+			notifiers = append(notifiers, notifier.NewSlack(resolved.WebhookURL, client))
+		case notifier.CHANNEL_TEAMS:
+			notifiers = append(notifiers, notifier.NewTeams(resolved.WebhookURL, client))
+		case notifier.CHANNEL_DISCORD:
+			notifiers = append(notifiers, notifier.NewDiscord(resolved.WebhookURL, client))
+		case notifier.CHANNEL_PAGERDUTY:
+			notifiers = append(notifiers, notifier.NewPagerDuty(resolved.IntegrationKey, channel.APIURL, client))
+		case notifier.CHANNEL_OPSGENIE:
+			notifiers = append(notifiers, notifier.NewOpsgenie(resolved.APIKey, channel.APIURL, client))
+		case notifier.CHANNEL_WEBHOOK:
+			notifiers = append(notifiers, notifier.NewWebhook(resolved.WebhookURL, resolved.Secret, channel.MaxAttempts, client))
+		case notifier.CHANNEL_EMAIL:
+			notifiers = append(notifiers, newEmailNotifier(resolved))
+		default:
+			return nil, errors.New("unsupported notification channel: " + channel.Type)
+		}
+	}
+	return notifiers, nil
+}
+
+// Creates email notifier of channel, SMTP server, credentials and sender default to environment variables
+func newEmailNotifier(channel *notifier.Channel) *notifier.Email {
+	config := notifier.SMTPConfig{
+		Addr:       orEnv(channel.SMTPAddr, "SMTP_ADDR"),
+		Username:   channel.Username,
+		Password:   channel.Password,
+		AuthMethod: channel.AuthMethod,
+		StartTLS:   getEnvBool("SMTP_STARTTLS", false),
+		Timeout:    time.Duration(getEnvInt("TIMEOUT", 4)) * time.Second,
+	}
+	return notifier.NewEmail(config, orEnv(channel.From, "SMTP_FROM"), channel.To, channel.Templates)
+}
+
+// Returns value, or value of environment variable if value is empty
+func orEnv(value string, key string) string {
+	if value == "" {
+		return getEnvStringWithDefault(key, "")
+	}
+	return value
+}
+
+// Creates copy of channel with resolved secret references in webhook URL, keys, secret and SMTP credentials
+// SMTP credentials default to SMTP_USERNAME and SMTP_PASSWORD environment variables, which may be references as well
+func resolveChannel(channel notifier.Channel, sess *session.Session) (*notifier.Channel, error) {
+	sm := secretsmanagerAPI.New(sess)
+	ssmClient := ssmAPI.New(sess)
+	if channel.Type == notifier.CHANNEL_EMAIL {
+		channel.Username = orEnv(channel.Username, "SMTP_USERNAME")
+		channel.Password = orEnv(channel.Password, "SMTP_PASSWORD")
+	}
+	for _, value := range []*string{&channel.WebhookURL, &channel.IntegrationKey, &channel.APIKey, &channel.Secret,
+		&channel.Username, &channel.Password} {
+		resolved, err := secrets.Resolve(*value, sm, ssmClient)
+		if err != nil {
+			return nil, err
+		}
+		*value = resolved
+	}
+	return &channel, nil
+}
+
+// Creates notification of uptime status transition from previous uptime status and last run
+// Fail counter and outage start are derived from previous uptime status, as it is read before the last run is counted
+// Notification is rendered by templates of request, default templates are used if request does not override them
+// If there is email channel, then notification contains recent runs of uptime monitor as well
+func newNotification(statusReq *UptimeMonitorRequest,
+	response *UptimeMonitorResponse,
+	previous *storage.UptimeStatusItem,
+	status sns.UptimeStatus,
+	channels []notifier.Channel,
+	store storage.Storage) (*notifier.Notification, error) {
+	notification := &notifier.Notification{
+		UptimeID:       statusReq.UptimeID,
+		Host:           statusReq.Host,
+		Tags:           statusReq.Tags,
+		PreviousStatus: sns.UptimeStatus(previous.AnnouncedStatus()),
+		Status:         status,
+		Reason:         response.Reason + response.Warning,
+		ErrorClass:     response.ErrorClass,
+		StatusCode:     response.StatusCode,
+		TTFB:           response.TTFB,
+		DNSLookup:      response.DNSLookup,
+		TCPConnect:     response.TCPConnect,
+		TLSHandshake:   response.TLSHandshake,
+		Total:          response.Total,
+		FlapScore:      response.FlapScore,
+		Time:           time.Now(),
+	}
+	if previous.CurrentState() == storage.STATE_FLAPPING {
+		notification.PreviousStatus = sns.STATUS_FLAPPING
+	}
+	if previous != nil && previous.FailingSince != 0 {
+		notification.OutageStart = time.Unix(previous.FailingSince, 0)
+	}
+	if status == sns.STATUS_FAIL {
+		notification.FailCounter = 1
+		if previous != nil {
+			notification.FailCounter += previous.FailCounter
+		}
+		if notification.OutageStart.IsZero() {
+			notification.OutageStart = notification.Time
+		}
+	}
+	var err error
+	if len(statusReq.Templates) > 0 {
+		if notification.Templates, err = templates.New(statusReq.Templates); err != nil {
+			return nil, err
+		}
+	}
+	if hasChannel(channels, notifier.CHANNEL_EMAIL) {
+		if notification.RecentRuns, err = recentRuns(statusReq.UptimeID, store); err != nil {
+			return nil, err
+		}
+	}
+	return notification, nil
+}
+
+// Announces uptime status transition to channels of request and records notification in uptime status
+// Transition is not announced (nor recorded) while uptime monitor is silenced, and it is not recorded unless it has
+// been delivered to all channels, so it stays pending and it is announced again by the next run. Pending transition
+// of previous uptime status is announced as transition from status announced before it.
+// Recovery from escalated outage is announced to secondary channels of escalation policy as well
+func announceUptimeStatus(statusReq *UptimeMonitorRequest,
+	response *UptimeMonitorResponse,
+	previous *storage.UptimeStatusItem,
+	status sns.UptimeStatus,
+	store storage.Storage,
+	sessionOptions *session.Options) error {
+	channels, err := notificationChannels(statusReq, status)
+	if err != nil {
+		return err
+	}
+	if status == sns.STATUS_OK {
+		channels = uniqueChannels(append(channels, statusReq.Escalation.RecoveryChannels(previous)...))
+	}
+	notification, err := newNotification(statusReq, response, previous, status, channels, store)
+	if err != nil {
+		return err
+	}
+	if pending := previous.PendingAnnouncement(); pending != "" && string(status) == pending {
+		notification.PreviousStatus = sns.UptimeStatus(previous.TransitionFrom)
+		if notification.FlapScore == 0 {
+			notification.FlapScore = previous.FlapScore
+		}
+	}
+	if silenced, err := isSilenced(statusReq, notification, store); silenced || err != nil {
+		return err
+	}
+	if delivered, err := notifyUptimeStatus(channels, notification, store, sessionOptions); !delivered || err != nil {
+		return err
+	}
+	if status == sns.STATUS_FAIL || status == sns.STATUS_FLAPPING {
+		err = recordIncidentEvent(statusReq.UptimeID, storage.EVENT_NOTIFIED, string(status)+" announced", notification.Time.Unix(), store)
+		if err != nil {
+			return err
+		}
+	}
+	return store.RecordNotification(statusReq.UptimeID, storage.NOTIFICATION_TRANSITION, notification.Time.Unix())
+}
+
+// Reminds persisting outage and escalates it to secondary channels according to escalation policy of request
+// Reminders are sent to channels of request, and to secondary channels as well once outage has been escalated.
+// Notifications delivered to all channels are recorded in uptime status, so they are repeated only after policy's
+// interval, while undelivered ones are sent again by the next run.
+// Nothing is sent while uptime monitor is silenced or outage is acknowledged.
+func escalateUptimeStatus(statusReq *UptimeMonitorRequest,
+	response *UptimeMonitorResponse,
+	previous *storage.UptimeStatusItem,
+	store storage.Storage,
+	sessionOptions *session.Options) error {
+	action := statusReq.Escalation.Next(previous, time.Now())
+	if !action.Remind && !action.Escalate {
+		return nil
+	}
+
+	var channels []notifier.Channel
+	if action.Remind {
+		primary, err := notificationChannels(statusReq, sns.STATUS_FAIL)
+		if err != nil {
+			return err
+		}
+		channels = append(channels, primary...)
+	}
+	if action.Escalate || previous.EscalatedAt != 0 {
+		channels = uniqueChannels(append(channels, statusReq.Escalation.Channels...))
+	}
+	notification, err := newNotification(statusReq, response, previous, sns.STATUS_FAIL, channels, store)
+	if err != nil {
+		return err
+	}
+	notification.Reminder = true
+	notification.Escalated = action.Escalate || previous.EscalatedAt != 0
+	if silenced, err := isSilenced(statusReq, notification, store); silenced || err != nil {
+		return err
+	}
+	if delivered, err := notifyUptimeStatus(channels, notification, store, sessionOptions); !delivered || err != nil {
+		return err
+	}
+	message := "reminder sent"
+	if action.Escalate {
+		message = "escalated"
+	}
+	if err = recordIncidentEvent(statusReq.UptimeID, storage.EVENT_NOTIFIED, message, notification.Time.Unix(), store); err != nil {
+		return err
+	}
+
+	if action.Remind {
+		if err = store.RecordNotification(statusReq.UptimeID, storage.NOTIFICATION_REMINDER, notification.Time.Unix()); err != nil {
+			return err
+		}
+	}
+	if action.Escalate {
+		return store.RecordNotification(statusReq.UptimeID, storage.NOTIFICATION_ESCALATION, notification.Time.Unix())
+	}
+	return nil
+}
+
+// Returns true if notifications of request's uptime monitor are silenced, suppressed notification is logged
+func isSilenced(statusReq *UptimeMonitorRequest, notification *notifier.Notification, store storage.Storage) (bool, error) {
+	at := notification.Time.Unix()
+	silences, err := store.ListSilences(statusReq.UptimeID, at)
+	if err != nil {
+		return false, err
+	}
+	for _, silence := range silences {
+		if silence.Matches(statusReq.UptimeID, statusReq.Tags, at) {
+			log.Printf("notification of uptime %s status %s suppressed by silence %s until %s",
+				statusReq.UptimeID, notification.Status, silence.ID, time.Unix(silence.Until, 0).UTC().Format(time.RFC3339))
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns true if there is channel of type
+func hasChannel(channels []notifier.Channel, channelType string) bool {
+	for _, channel := range channels {
+		if channel.Type == channelType {
+			return true
+		}
+	}
+	return false
+}
+
+// Get last EMAIL_RECENT_RUNS (default: 5) runs of uptime monitor from last 24 hours, ordered by run time
+func recentRuns(uptimeID string, store storage.Storage) ([]storage.UptimeResultItem, error) {
+	now := time.Now()
+	runs, err := store.ListUptimeResults(uptimeID, now.Add(-24*time.Hour).Unix(), now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	if count := getEnvInt("EMAIL_RECENT_RUNS", 5); len(runs) > count {
+		runs = runs[len(runs)-count:]
+	}
+	return runs, nil
+}
+
+// Notify uptime status transition to all channels
+// Notification is sent to every channel even if some of them fail. Failures are not returned, as the run has been
+// stored already and its retry would count it again, they are logged and recorded as delivery attempts instead.
+// Returns true if notification has been delivered to all channels, error is returned if attempts cannot be recorded.
+func notifyUptimeStatus(channels []notifier.Channel,
+	notification *notifier.Notification,
+	store storage.Storage,
+	sessionOptions *session.Options) (bool, error) {
+	delivered := true
+	for _, channel := range channels {
+		attempts := notifyChannel(channel, notification, sessionOptions)
+		for _, attempt := range attempts {
+			logDeliveryAttempt(attempt)
+		}
+		if attempts[len(attempts)-1].Error != "" {
+			delivered = false
+		}
+		if err := store.RecordDeliveryAttempts(notification.UptimeID, attempts); err != nil {
+			return false, err
+		}
+	}
+	return delivered, nil
+}
+
+// Logs delivery attempt, so failed deliveries are visible in logs as well
+func logDeliveryAttempt(attempt storage.DeliveryAttemptItem) {
+	duration := time.Duration(attempt.Duration) * time.Millisecond
+	switch {
+	case attempt.Error != "":
+		log.Printf("%s delivery %s attempt %d failed in %v: %s",
+			attempt.Channel, attempt.DeliveryID, attempt.Attempt, duration, attempt.Error)
+	case attempt.StatusCode != 0:
+		log.Printf("%s delivery %s attempt %d succeeded with status code %d in %v",
+			attempt.Channel, attempt.DeliveryID, attempt.Attempt, attempt.StatusCode, duration)
+	default:
+		log.Printf("%s delivery %s attempt %d succeeded in %v", attempt.Channel, attempt.DeliveryID, attempt.Attempt, duration)
+	}
+}
+
+// Notifies channel and returns attempts of delivery, the last attempt fails if notification has not been delivered
+// Notifiers which retry delivery (e.g. generic webhook) report their attempts, others are delivered by single attempt.
+func notifyChannel(channel notifier.Channel,
+	notification *notifier.Notification,
+	sessionOptions *session.Options) []storage.DeliveryAttemptItem {
+	start := time.Now()
+	notifiers, err := newNotifiers([]notifier.Channel{channel}, sessionOptions)
+	if err == nil {
+		err = notifiers[0].Notify(notification)
+		if recorder, ok := notifiers[0].(interface {
+			Attempts() []notifier.DeliveryAttempt
+		}); ok && len(recorder.Attempts()) > 0 {
+			var attempts []storage.DeliveryAttemptItem
+			for _, attempt := range recorder.Attempts() {
+				attempts = append(attempts, storage.DeliveryAttemptItem{
+					DeliveryID: attempt.DeliveryID,
+					Channel:    channel.Type,
+					Attempt:    attempt.Attempt,
+					At:         attempt.Time.Unix(),
+					StatusCode: attempt.StatusCode,
+					Duration:   attempt.Duration.Milliseconds(),
+					Error:      attempt.Error,
+				})
+			}
+			return attempts
+		}
+	}
+	attempt := storage.DeliveryAttemptItem{
+		DeliveryID: uuid.New().String(),
+		Channel:    channel.Type,
+		Attempt:    1,
+		At:         start.Unix(),
+		Duration:   time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	return []storage.DeliveryAttemptItem{attempt}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/notifier"
+	"monitor-uptime/internal/routing"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Routing configuration of payments team with default webhook
+const testRoutingConfig = `{
+	"channels": {
+		"payments-slack": {"type": "slack", "webhookUrl": "https://hooks.slack.com/payments"},
+		"ops-webhook": {"type": "webhook", "webhookUrl": "https://ops.example.com/hook"}
+	},
+	"routes": [{"name": "payments", "match": {"team": "payments"}, "channels": ["payments-slack"]}],
+	"default": ["ops-webhook"]
+}`
+
+// Returns webhook URLs of channels
+func channelURLs(channels []notifier.Channel) []string {
+	var urls []string
+	for _, channel := range channels {
+		urls = append(urls, channel.WebhookURL)
+	}
+	return urls
+}
+
+// Given routing configuration with default channel
+// When monitor with its own channel and without matching route fails
+//      and monitor without channels and matching route fails
+// Then default channel is used only by monitor without channels
+func TestRouteChannelsDefault(t *testing.T) {
+	// Given
+	config, err := routing.Parse([]byte(testRoutingConfig))
+	assert.Nil(t, err, "Error was not expected to be returned")
+	own := []notifier.Channel{{Type: notifier.CHANNEL_WEBHOOK, WebhookURL: "https://team.example.com/hook"}}
+
+	// When
+	withChannels := routeChannels(own, config, map[string]string{"team": "search"}, sns.STATUS_FAIL, time.Now())
+	withoutChannels := routeChannels(nil, config, map[string]string{"team": "search"}, sns.STATUS_FAIL, time.Now())
+
+	// Then
+	assert.Equal(t, []string{"https://team.example.com/hook"}, channelURLs(withChannels), "Default channel was not expected to be added")
+	assert.Equal(t, []string{"https://ops.example.com/hook"}, channelURLs(withoutChannels), "Default channel was expected to be used")
+}
+
+// Given routing configuration
+// When monitor whose own channel is routed as well fails
+// Then channel is notified only once
+func TestRouteChannelsDuplicate(t *testing.T) {
+	// Given
+	config, _ := routing.Parse([]byte(testRoutingConfig))
+	own := []notifier.Channel{
+		{Type: notifier.CHANNEL_SLACK, WebhookURL: "https://hooks.slack.com/payments"},
+		{Type: notifier.CHANNEL_WEBHOOK, WebhookURL: "https://ops.example.com/hook", MaxAttempts: 5},
+		{Type: notifier.CHANNEL_WEBHOOK, WebhookURL: "https://ops.example.com/hook"},
+	}
+
+	// When
+	res := routeChannels(own, config, map[string]string{"team": "payments"}, sns.STATUS_FAIL, time.Now())
+
+	// Then
+	assert.Equal(t, []string{"https://hooks.slack.com/payments", "https://ops.example.com/hook"}, channelURLs(res), "Unexpected channels")
+	assert.Equal(t, 5, res[1].MaxAttempts, "The first of duplicate channels was expected to be kept")
+}
+
+// Given webhook which fails first delivery attempt
+// When failure of uptime monitor is announced
+//      and its deliveries are listed
+// Then failed and retried attempts of the same delivery are recorded
+func TestNotifyRecordsDeliveryAttempts(t *testing.T) {
+	// Given
+	var requests int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer webhook.Close()
+	host := newTestHost(t, http.StatusInternalServerError)
+	req := newTestRequest(t, "retried-delivery-uptime", host, newTestWebhook(t))
+	req.Channels = []notifier.Channel{{Type: notifier.CHANNEL_WEBHOOK, WebhookURL: webhook.URL}}
+
+	// When
+	runMonitor(t, req)
+	runMonitor(t, req)
+	res := runMonitor(t, UptimeMonitorRequest{Action: ACTION_DELIVERIES, UptimeID: req.UptimeID})
+
+	// Then
+	assert.Len(t, res.Deliveries, 2, "Failed and retried attempts were expected to be recorded")
+	assert.Equal(t, res.Deliveries[0].DeliveryID, res.Deliveries[1].DeliveryID, "Attempts were expected to share delivery ID")
+	assert.Equal(t, notifier.CHANNEL_WEBHOOK, res.Deliveries[0].Channel, "Unexpected channel of attempt")
+	assert.Equal(t, 1, res.Deliveries[0].Attempt, "Unexpected number of failed attempt")
+	assert.Equal(t, http.StatusBadGateway, res.Deliveries[0].StatusCode, "Unexpected status code of failed attempt")
+	assert.NotEmpty(t, res.Deliveries[0].Error, "Failed attempt was expected to have error")
+	assert.Equal(t, 2, res.Deliveries[1].Attempt, "Unexpected number of retried attempt")
+	assert.Equal(t, http.StatusOK, res.Deliveries[1].StatusCode, "Unexpected status code of retried attempt")
+	assert.Empty(t, res.Deliveries[1].Error, "Retried attempt was not expected to have error")
+}
+
+// Given request without uptime ID
+// When deliveries are listed
+// Then error is returned
+func TestHandleActionDeliveriesWithoutUptimeID(t *testing.T) {
+	// Given
+	t.Setenv("STORAGE", STORAGE_MEMORY)
+
+	// When
+	_, err := HandleRequest(context.Background(), UptimeMonitorRequest{Action: ACTION_DELIVERIES})
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+}
+
+// Given uptime monitor whose webhook fails when FAIL is announced
+// When it fails twice
+//      and it fails again once webhook is up
+// Then runs are handled without error
+//      and FAIL is delivered by the next run
+//      and failed delivery is recorded
+func TestHandleRequestRetriesUndeliveredTransition(t *testing.T) {
+	// Given
+	host := newTestHost(t, http.StatusInternalServerError)
+	webhook := newTestWebhook(t)
+	req := newTestRequest(t, "undelivered-uptime", host, webhook)
+	req.Channels[0].MaxAttempts = 1
+	store, _ := newStorage(nil)
+
+	// When
+	runMonitor(t, req)
+	webhook.respond(http.StatusInternalServerError)
+	runMonitor(t, req)
+	pending, _ := store.GetUptimeStatus(req.UptimeID)
+	webhook.respond(0)
+	runMonitor(t, req)
+
+	// Then
+	status, _ := store.GetUptimeStatus(req.UptimeID)
+	attempts, _ := store.ListDeliveryAttempts(req.UptimeID)
+	assert.Equal(t, storage.ANNOUNCED_FAIL, pending.PendingAnnouncement(), "Undelivered FAIL was expected to be pending")
+	assert.Equal(t, []string{"FAIL"}, webhook.statuses(), "FAIL was expected to be delivered by the next run")
+	assert.Empty(t, status.PendingAnnouncement(), "Delivered FAIL was not expected to be pending")
+	assert.Equal(t, sns.UptimeStatus(sns.STATUS_OK), webhook.payloads[0].PreviousStatus, "FAIL was expected to be announced as transition from OK")
+	assert.Len(t, attempts, 2, "Failed and successful deliveries were expected to be recorded")
+	assert.Equal(t, http.StatusInternalServerError, attempts[0].StatusCode, "Unexpected status code of failed delivery")
+	assert.NotEmpty(t, attempts[0].Error, "Failed delivery was expected to have error")
+	assert.Empty(t, attempts[1].Error, "Retried delivery was not expected to have error")
+}
+
+// Given uptime monitor which is silenced when FAIL is announced
+// When silence is removed
+//      and uptime monitor fails again
+// Then FAIL is delivered once silence has been removed
+func TestHandleRequestAnnouncesTransitionAfterSilence(t *testing.T) {
+	// Given
+	host := newTestHost(t, http.StatusInternalServerError)
+	webhook := newTestWebhook(t)
+	req := newTestRequest(t, "silenced-transition-uptime", host, webhook)
+	silenced := runMonitor(t, UptimeMonitorRequest{Action: ACTION_SILENCE, UptimeID: req.UptimeID, Duration: 60})
+	runMonitor(t, req)
+	runMonitor(t, req)
+
+	// When
+	runMonitor(t, UptimeMonitorRequest{Action: ACTION_UNSILENCE, UptimeID: req.UptimeID, Silence: &storage.SilenceItem{ID: silenced.Silences[0].ID}})
+	suppressed := webhook.statuses()
+	runMonitor(t, req)
+
+	// Then
+	assert.Empty(t, suppressed, "FAIL was not expected to be delivered while silenced")
+	assert.Equal(t, []string{"FAIL"}, webhook.statuses(), "FAIL was expected to be delivered after silence")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws/session"
+	"log"
+	"monitor-uptime/internal/notifier"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/storage"
+	"time"
+)
+
+// Returns failing parent of request's uptime monitor, empty if no parent is down
+// Parent which is unreachable due to its own parent is represented by its failing ancestor, see OutageRoot
+func failingParent(statusReq *UptimeMonitorRequest, store storage.Storage) (string, error) {
+	for _, parentID := range statusReq.Parents {
+		parent, err := store.GetUptimeStatus(parentID)
+		if err != nil {
+			return "", err
+		}
+		if root := parent.OutageRoot(); root != "" {
+			return root, nil
+		}
+	}
+	return "", nil
+}
+
+// Folds failure of request's uptime monitor into outage of its failing parent
+// Uptime monitor is marked UNREACHABLE_DUE_TO_PARENT and its run is not counted. The first failure during parent's
+// outage is sent to incident channels (PagerDuty and Opsgenie) only, where it is deduplicated into parent's incident.
+func foldIntoParent(statusReq *UptimeMonitorRequest,
+	response *UptimeMonitorResponse,
+	previous *storage.UptimeStatusItem,
+	store storage.Storage,
+	sessionOptions *session.Options) error {
+	marked, err := store.MarkUnreachable(statusReq.UptimeID, response.Parent)
+	if err != nil || !marked {
+		return err
+	}
+	log.Printf("failure of uptime %s folded into outage of parent %s", statusReq.UptimeID, response.Parent)
+	err = recordIncidentEvent(response.Parent, storage.EVENT_UNREACHABLE, statusReq.UptimeID, time.Now().Unix(), store)
+	if err != nil {
+		return err
+	}
+
+	channels, err := notificationChannels(statusReq, sns.STATUS_UNREACHABLE)
+	if err != nil {
+		return err
+	}
+	channels = incidentChannels(channels)
+	if len(channels) == 0 {
+		return nil
+	}
+	notification, err := newNotification(statusReq, response, previous, sns.STATUS_UNREACHABLE, channels, store)
+	if err != nil {
+		return err
+	}
+	notification.Parent = response.Parent
+	if silenced, err := isSilenced(statusReq, notification, store); silenced || err != nil {
+		return err
+	}
+	_, err = notifyUptimeStatus(channels, notification, store, sessionOptions)
+	return err
+}
+
+// Returns channels which manage incidents (PagerDuty and Opsgenie)
+func incidentChannels(channels []notifier.Channel) []notifier.Channel {
+	var incidents []notifier.Channel
+	for _, channel := range channels {
+		if channel.Type == notifier.CHANNEL_PAGERDUTY || channel.Type == notifier.CHANNEL_OPSGENIE {
+			incidents = append(incidents, channel)
+		}
+	}
+	return incidents
+}
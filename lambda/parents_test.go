@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/memory"
+	"testing"
+)
+
+// Given uptime monitor with parent without status, DOWN parent and parent unreachable due to DOWN ancestor
+// When failing parent is looked up
+// Then parent without status is skipped
+//      and failure is folded into the first outage
+func TestFailingParent(t *testing.T) {
+	// Given
+	store := memory.NewStorage()
+	_, _ = store.UpdateUptimeStatus("gateway", 0)
+	_, _ = store.UpdateUptimeStatus("database", 0)
+	_, _ = store.MarkUnreachable("database", "gateway")
+
+	// When
+	missing, missingErr := failingParent(&UptimeMonitorRequest{Parents: []string{"unknown"}}, store)
+	down, downErr := failingParent(&UptimeMonitorRequest{Parents: []string{"unknown", "gateway"}}, store)
+	chained, chainedErr := failingParent(&UptimeMonitorRequest{Parents: []string{"database"}}, store)
+
+	// Then
+	assert.Nil(t, missingErr, "Error was not expected to be returned")
+	assert.Nil(t, downErr, "Error was not expected to be returned")
+	assert.Nil(t, chainedErr, "Error was not expected to be returned")
+	assert.Empty(t, missing, "Parent without status was not expected to be failing")
+	assert.Equal(t, "gateway", down, "DOWN parent was expected to be failing")
+	assert.Equal(t, "gateway", chained, "Failure was expected to be folded into root outage")
+}
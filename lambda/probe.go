@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/session"
+	secretsmanagerAPI "github.com/aws/aws-sdk-go/service/secretsmanager"
+	ssmAPI "github.com/aws/aws-sdk-go/service/ssm"
+	"monitor-uptime/internal/secrets"
+	"monitor-uptime/internal/uptime"
+	"strings"
+	"time"
+)
+
+// Get uptime monitor response for requested host using probe of requested monitor type
+// If host cannot be probed (e.g. it is unreachable), then response contains classified probe error
+// In case of failure error is returned
+func response(statusReq *UptimeMonitorRequest, sessionOptions *session.Options) (*UptimeMonitorResponse, error) {
+	switch statusReq.Type {
+	case "", MONITOR_HTTP:
+		return httpResponse(statusReq, sessionOptions)
+	case MONITOR_PING:
+		return pingResponse(statusReq)
+	case MONITOR_TCP:
+		return tcpResponse(statusReq)
+	}
+	return nil, errors.New("unsupported monitor type: " + statusReq.Type)
+}
+
+// Get uptime monitor response containing probe error and certificate details in case of TLS failure
+// If error is not a probe error, then it is returned as is
+func probeErrorResponse(host string, err error) (*UptimeMonitorResponse, error) {
+	var probeErr *uptime.ProbeError
+	if errors.As(err, &probeErr) {
+		res := &UptimeMonitorResponse{
+			Host:       host,
+			ErrorClass: string(probeErr.Class),
+			Error:      probeErr.Err.Error(),
+		}
+		setCertificate(res, probeErr.Certificate)
+		return res, nil
+	}
+	return nil, err
+}
+
+// Sets details of TLS certificate presented by host to uptime monitor response, nothing is set if certificate is nil
+func setCertificate(res *UptimeMonitorResponse, cert *uptime.Certificate) {
+	if cert == nil {
+		return
+	}
+	res.CertSubject = cert.Subject
+	res.CertIssuer = cert.Issuer
+	res.CertSANs = cert.SANs
+	res.CertNotAfter = cert.NotAfter.Unix()
+	res.CertExpiryDays = &cert.DaysUntilExpiry
+	res.CertError = cert.VerifyError
+}
+
+// Get uptime monitor response for provided host using ping probe
+func pingResponse(statusReq *UptimeMonitorRequest) (*UptimeMonitorResponse, error) {
+	count := statusReq.PingCount
+	if count <= 0 {
+		count = getEnvInt("PING_COUNT", 3)
+	}
+	response, err := uptime.GetPing(statusReq.Host, count, getEnvInt("TIMEOUT", 4), getEnvBool("PING_PRIVILEGED", false))
+	if err != nil {
+		return probeErrorResponse(statusReq.Host, err)
+	}
+
+	return &UptimeMonitorResponse{
+		Host:        statusReq.Host,
+		PacketsSent: response.PacketsSent,
+		PacketsRecv: response.PacketsRecv,
+		PacketLoss:  response.PacketLoss,
+		MinRTT:      milliseconds(response.MinRTT),
+		AvgRTT:      milliseconds(response.AvgRTT),
+		MaxRTT:      milliseconds(response.MaxRTT),
+		StdDevRTT:   milliseconds(response.StdDevRTT),
+	}, nil
+}
+
+// Get uptime monitor response for provided host:port using TCP probe
+func tcpResponse(statusReq *UptimeMonitorRequest) (*UptimeMonitorResponse, error) {
+	response, err := uptime.GetTCP(statusReq.Host, getEnvInt("TIMEOUT", 4), &uptime.TCPCheck{
+		Payload:      statusReq.Payload,
+		ExpectPrefix: statusReq.ExpectPrefix,
+		ExpectRegex:  statusReq.ExpectRegex,
+	})
+	if err != nil {
+		return probeErrorResponse(statusReq.Host, err)
+	}
+
+	return &UptimeMonitorResponse{
+		Host:            statusReq.Host,
+		DNSLookup:       response.DNSLookup.Milliseconds(),
+		TCPConnect:      response.Connect.Milliseconds(),
+		Response:        response.Response,
+		ResponseMatched: &response.Matched,
+	}, nil
+}
+
+// Converts duration into fractional milliseconds
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+// Get uptime monitor response for provided host using HTTP probe
+// Requested assertions are evaluated against HTTP response
+func httpResponse(statusReq *UptimeMonitorRequest, sessionOptions *session.Options) (*UptimeMonitorResponse, error) {
+	spec, err := resolveRequestSpec(statusReq.Request, sessionOptions)
+	if err != nil {
+		return nil, err
+	}
+	hostUrl := sanityHTTPProtocol(statusReq.Host)
+	response, err := uptime.GetUptimeWithSpec(hostUrl, getEnvInt("TIMEOUT", 4), spec)
+	if err != nil {
+		return probeErrorResponse(hostUrl, err)
+	}
+
+	res := &UptimeMonitorResponse{
+		Host:             hostUrl,
+		StatusCode:       response.StatusCode,
+		TTFB:             response.TTFB.Milliseconds(),
+		DNSLookup:        response.DNSLookup.Milliseconds(),
+		TCPConnect:       response.TCPConnect.Milliseconds(),
+		TLSHandshake:     response.TLSHandshake.Milliseconds(),
+		ServerProcessing: response.ServerProcessing.Milliseconds(),
+		ContentTransfer:  response.ContentTransfer.Milliseconds(),
+		Total:            response.Total.Milliseconds(),
+		ResponseSize:     response.BodySize,
+		ConnReused:       response.ConnReused,
+	}
+	setCertificate(res, response.Certificate)
+	for _, assertion := range uptime.EvaluateAssertions(statusReq.Assertions, response) {
+		res.Assertions = append(res.Assertions, AssertionResponse{
+			Type:    string(assertion.Type),
+			Target:  assertion.Target,
+			Value:   assertion.Value,
+			Passed:  assertion.Passed,
+			Message: assertion.Message,
+		})
+	}
+	return res, nil
+}
+
+// Creates copy of HTTP request spec with resolved secret references in headers, body and authentication
+// Copy is marked sensitive if it carries authentication or any resolved secret, so it is sent only over verified TLS.
+// Returns nil if spec is nil
+func resolveRequestSpec(spec *uptime.RequestSpec, sessionOptions *session.Options) (*uptime.RequestSpec, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	sess := session.Must(session.NewSessionWithOptions(*sessionOptions))
+	sm := secretsmanagerAPI.New(sess)
+	ssmClient := ssmAPI.New(sess)
+	resolved := *spec
+	resolved.Sensitive = spec.Auth != nil
+	resolve := func(values ...*string) error {
+		for _, value := range values {
+			resolvedValue, err := secrets.Resolve(*value, sm, ssmClient)
+			if err != nil {
+				return err
+			}
+			resolved.Sensitive = resolved.Sensitive || resolvedValue != *value
+			*value = resolvedValue
+		}
+		return nil
+	}
+
+	if err := resolve(&resolved.Body); err != nil {
+		return nil, err
+	}
+	resolved.Headers = map[string]string{}
+	for name, value := range spec.Headers {
+		if err := resolve(&value); err != nil {
+			return nil, err
+		}
+		resolved.Headers[name] = value
+	}
+	if spec.Auth != nil {
+		auth := *spec.Auth
+		if err := resolve(&auth.Username, &auth.Password, &auth.Token); err != nil {
+			return nil, err
+		}
+		resolved.Auth = &auth
+	}
+	return &resolved, nil
+}
+
+// Makes sure that host always contains protocol part
+// If not provided explicitly HTTPS is added by default
+func sanityHTTPProtocol(host string) string {
+	if !(strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://")) {
+		return "https://" + host
+	}
+	return host
+}
+
+// Get reason why host has not been probed or result does not match to requested expectations
+// Returns empty string if host is up
+func failureReason(statusReq *UptimeMonitorRequest, response *UptimeMonitorResponse) string {
+	if response.ErrorClass != "" {
+		return response.ErrorClass + ": " + response.Error
+	}
+	switch statusReq.Type {
+	case MONITOR_PING:
+		return pingFailureReason(response, statusReq.MaxPacketLoss, statusReq.MaxAvgRTT)
+	case MONITOR_TCP:
+		if response.ResponseMatched == nil || !*response.ResponseMatched {
+			return fmt.Sprintf("response %q does not match expectations", response.Response)
+		}
+		return ""
+	}
+	if !hasExpectedStatusCode(response.StatusCode, statusReq.StatusCodes) {
+		return fmt.Sprintf("unexpected status code %d", response.StatusCode)
+	}
+	if isCertExpiring(response.CertExpiryDays, statusReq.CertExpiryDays) {
+		return fmt.Sprintf("certificate expires in %d days", *response.CertExpiryDays)
+	}
+	for _, assertion := range response.Assertions {
+		if !assertion.Passed {
+			return "assertion " + assertion.Type + " failed: " + assertion.Message
+		}
+	}
+	return ""
+}
+
+// Get reason why host which is up is considered degraded, e.g. it is slower than requested warning limits
+// Returns empty string if host is not degraded
+func degradedReason(statusReq *UptimeMonitorRequest, response *UptimeMonitorResponse) string {
+	if statusReq.WarnTTFB > 0 && response.TTFB > statusReq.WarnTTFB {
+		return fmt.Sprintf("TTFB %dms exceeds %dms", response.TTFB, statusReq.WarnTTFB)
+	}
+	if statusReq.WarnTotal > 0 && response.Total > statusReq.WarnTotal {
+		return fmt.Sprintf("total time %dms exceeds %dms", response.Total, statusReq.WarnTotal)
+	}
+	if isCertExpiring(response.CertExpiryDays, statusReq.CertWarnDays) {
+		return fmt.Sprintf("certificate expires in %d days", *response.CertExpiryDays)
+	}
+	return ""
+}
+
+// Checks whether TLS certificate expires within requested number of days
+func isCertExpiring(certExpiryDays *int, expiryDays int) bool {
+	return certExpiryDays != nil && expiryDays > 0 && *certExpiryDays < expiryDays
+}
+
+// Get reason why resulted packet loss or average round-trip time are not within requested limits
+// Returns empty string if they are within limits
+func pingFailureReason(response *UptimeMonitorResponse, maxPacketLoss float64, maxAvgRTT int64) string {
+	if response.PacketsRecv == 0 || response.PacketLoss > maxPacketLoss {
+		return fmt.Sprintf("packet loss %.1f%% exceeds %.1f%%", response.PacketLoss, maxPacketLoss)
+	}
+	if maxAvgRTT > 0 && response.AvgRTT > float64(maxAvgRTT) {
+		return fmt.Sprintf("average round-trip time %.1fms exceeds %dms", response.AvgRTT, maxAvgRTT)
+	}
+	return ""
+}
+
+// Checks whether resulted status code matches to requested expectations
+func hasExpectedStatusCode(actualStatusCode int, expectedStatusCodes []int) bool {
+	for _, expectedStatusCode := range expectedStatusCodes {
+		if expectedStatusCode == actualStatusCode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/uptime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// Given host serves self-signed certificate
+//       and monitor authenticates by token referencing secret,
+// When HTTP response is retrieved,
+// Then host never receives Authorization header
+//      and response contains TLS error with certificate details
+func TestHTTPResponseInvalidCertificateWithSecret(t *testing.T) {
+	// Given
+	var authorizations []string
+	hostHTTPS := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+		}))
+	defer hostHTTPS.Close()
+	t.Setenv("UPTIME_TOKEN", "s3cr3t")
+	statusReq := &UptimeMonitorRequest{
+		Host:    hostHTTPS.URL,
+		Request: &uptime.RequestSpec{Auth: &uptime.Auth{Type: uptime.AUTH_BEARER, Token: "env:UPTIME_TOKEN"}},
+	}
+
+	// When
+	res, err := httpResponse(statusReq, &session.Options{})
+
+	// Then
+	assert.Nil(t, err, "Unexpected error happened")
+	assert.Empty(t, authorizations, "Host was not expected to receive any request")
+	assert.Equal(t, uptime.ERROR_TLS, res.ErrorClass, "Unexpected error class")
+	assert.NotEmpty(t, res.CertError, "Certificate chain verification error was expected")
+	assert.Contains(t, res.CertSubject, "Acme Co", "Unexpected certificate subject")
+}
+
+// Given errors of probes which could not reach host
+// When uptime monitor responses are created from them
+// Then responses contain class and message of probe failure
+//      and failure reason of run is prefixed by error class instead of status code
+func TestProbeErrorResponse(t *testing.T) {
+	dial := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: err}}
+	}
+	tests := []struct {
+		name  string
+		err   error
+		class string
+	}{
+		{"timeout", &url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded}, uptime.ERROR_TIMEOUT},
+		{"DNS", dial(&net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}), uptime.ERROR_DNS},
+		{"TLS", &url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}, uptime.ERROR_TLS},
+		{"connection refused", dial(&os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}), uptime.ERROR_CONNECT_REFUSED},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Given
+			probeErr := &uptime.ProbeError{Class: uptime.ClassifyError(test.err), Err: test.err}
+			statusReq := &UptimeMonitorRequest{Host: "https://example.com", StatusCodes: []int{http.StatusOK}}
+
+			// When
+			res, err := probeErrorResponse(statusReq.Host, probeErr)
+
+			// Then
+			assert.Nil(t, err, "Error was not expected to be returned")
+			assert.Equal(t, test.class, res.ErrorClass, "Unexpected error class")
+			assert.Equal(t, test.err.Error(), res.Error, "Unexpected error message")
+			assert.Equal(t, test.class+": "+test.err.Error(), failureReason(statusReq, res), "Unexpected failure reason")
+		})
+	}
+}
+
+// Given error which is not failure of probe
+// When uptime monitor response is created from it
+// Then error is returned
+func TestProbeErrorResponseNotProbeError(t *testing.T) {
+	// When
+	res, err := probeErrorResponse("https://example.com", errors.New("invalid request"))
+
+	// Then
+	assert.NotNil(t, err, "Error was expected to be returned")
+	assert.Nil(t, res, "Response was not expected to be returned")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/session"
+	dynamodbAPI "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/uuid"
+	"monitor-uptime/internal/dynamodb"
+	"monitor-uptime/internal/memory"
+	"monitor-uptime/internal/sns"
+	"monitor-uptime/internal/sqlite"
+	"monitor-uptime/internal/storage"
+	"sync"
+	"time"
+)
+
+// Storage backends
+const (
+	STORAGE_DYNAMODB = "dynamodb"
+	STORAGE_SQLITE   = "sqlite"
+	STORAGE_MEMORY   = "memory"
+)
+
+// Storages which are opened once and reused by subsequent requests
+var (
+	sharedStoragesMutex sync.Mutex
+	sharedStorages      = map[string]storage.Storage{}
+)
+
+// Creates storage backend selected by STORAGE environment variable, DynamoDB is used by default
+// SQLite and in-memory storages are opened once and shared by subsequent requests handled by the same process
+// In case of failure error is returned
+func newStorage(sessionOptions *session.Options) (storage.Storage, error) {
+	backend := getEnvStringWithDefault("STORAGE", STORAGE_DYNAMODB)
+	switch backend {
+	case STORAGE_DYNAMODB:
+		db := dynamodbAPI.New(session.Must(session.NewSessionWithOptions(*sessionOptions)))
+		return dynamodb.NewStorage(db,
+			getEnvStringWithDefault("DYNAMO_TABLE_EXECUTIONS", ""),
+			getEnvStringWithDefault("DYNAMO_INDEX_EXECUTIONS", "uptimeId-runAt-index"),
+			getEnvStringWithDefault("DYNAMO_TABLE_STATUS", "uptimeStatus"),
+			getEnvStringWithDefault("DYNAMO_TABLE_INCIDENTS", ""),
+			getEnvStringWithDefault("DYNAMO_INDEX_INCIDENTS", "uptimeId-openedAt-index")), nil
+	case STORAGE_SQLITE, STORAGE_MEMORY:
+		return sharedStorage(backend)
+	}
+	return nil, errors.New("unsupported storage: " + backend)
+}
+
+// Get shared SQLite or in-memory storage, storage is opened by the first call
+func sharedStorage(backend string) (storage.Storage, error) {
+	sharedStoragesMutex.Lock()
+	defer sharedStoragesMutex.Unlock()
+
+	if store, ok := sharedStorages[backend]; ok {
+		return store, nil
+	}
+	var store storage.Storage
+	if backend == STORAGE_SQLITE {
+		sqliteStore, err := sqlite.NewStorage(getEnvStringWithDefault("SQLITE_PATH", "uptime.db"))
+		if err != nil {
+			return nil, err
+		}
+		store = sqliteStore
+	} else {
+		store = memory.NewStorage()
+	}
+	sharedStorages[backend] = store
+	return store, nil
+}
+
+// Stores uptime into storage
+func storeUptime(statusReq *UptimeMonitorRequest, response *UptimeMonitorResponse, store storage.Storage) error {
+	return store.StoreUptimeResult(&storage.UptimeResultItem{
+		RequestID:        uuid.New().String(),
+		UptimeID:         statusReq.UptimeID,
+		RunAt:            time.Now().Unix(),
+		Type:             statusReq.Type,
+		Host:             statusReq.Host,
+		StatusCode:       response.StatusCode,
+		TTFB:             response.TTFB,
+		DNSLookup:        response.DNSLookup,
+		TLSHandshake:     response.TLSHandshake,
+		ServerProcessing: response.ServerProcessing,
+		ContentTransfer:  response.ContentTransfer,
+		Total:            response.Total,
+		ResponseSize:     response.ResponseSize,
+		ConnReused:       response.ConnReused,
+		PacketsSent:      response.PacketsSent,
+		PacketsRecv:      response.PacketsRecv,
+		PacketLoss:       response.PacketLoss,
+		MinRTT:           response.MinRTT,
+		AvgRTT:           response.AvgRTT,
+		MaxRTT:           response.MaxRTT,
+		StdDevRTT:        response.StdDevRTT,
+		TCPConnect:       response.TCPConnect,
+		Response:         response.Response,
+		ResponseMatched:  response.ResponseMatched,
+		CertSubject:      response.CertSubject,
+		CertIssuer:       response.CertIssuer,
+		CertSANs:         response.CertSANs,
+		CertNotAfter:     response.CertNotAfter,
+		CertExpiryDays:   response.CertExpiryDays,
+		CertError:        response.CertError,
+		Assertions:       assertionItems(response.Assertions),
+		Reason:           response.Reason,
+		Warning:          response.Warning,
+		ErrorClass:       response.ErrorClass,
+		Error:            response.Error,
+		Maintenance:      response.Maintenance != "",
+		Parent:           response.Parent,
+	})
+}
+
+// Converts assertion results into items stored in storage
+func assertionItems(assertions []AssertionResponse) []storage.AssertionResultItem {
+	var items []storage.AssertionResultItem
+	for _, assertion := range assertions {
+		items = append(items, storage.AssertionResultItem(assertion))
+	}
+	return items
+}
+
+// Updates uptime status in storage
+// Failed run is counted towards request's fail threshold, degraded run towards its degraded threshold
+// and successful run towards its recovery threshold
+// If status has been changed (e.g. cross threshold or uptime went from Fail to OK), then new status is returned
+func updateUptimeStatus(statusReq *UptimeMonitorRequest,
+	response *UptimeMonitorResponse,
+	store storage.Storage) (*sns.UptimeStatus, error) {
+	var err error
+	var notify bool
+	var status sns.UptimeStatus
+
+	switch {
+	case response.Reason != "":
+		status = sns.STATUS_FAIL
+		failThreshold := threshold(statusReq.FailThreshold, "FAIL_THRESHOLD", 3)
+		notify, err = store.UpdateUptimeStatus(statusReq.UptimeID, failThreshold)
+	case response.Warning != "":
+		status = sns.STATUS_DEGRADED
+		degradedThreshold := threshold(statusReq.DegradedThreshold, "DEGRADED_THRESHOLD", 3)
+		notify, err = store.DegradeUptimeStatus(statusReq.UptimeID, degradedThreshold)
+	default:
+		status = sns.STATUS_OK
+		recoveryThreshold := threshold(statusReq.RecoveryThreshold, "RECOVERY_THRESHOLD", 1)
+		notify, err = store.RecoverUptimeStatus(statusReq.UptimeID, recoveryThreshold)
+	}
+
+	if err == nil {
+		if notify {
+			return &status, nil
+		} else {
+			return nil, nil
+		}
+	} else {
+		return nil, err
+	}
+}
+
+// Detects flapping of request's uptime monitor by flap score of its recent runs
+// Flap detection is enabled by request's (or FLAP_WINDOW) window, flapping uptime monitor is evaluated even if it has
+// been disabled, so it becomes stable again. Returns FLAPPING status if uptime monitor started flapping, its flap score
+// is set in response then, or announced status if it is stable again, nil otherwise.
+func detectFlapping(statusReq *UptimeMonitorRequest,
+	response *UptimeMonitorResponse,
+	previous *storage.UptimeStatusItem,
+	store storage.Storage) (*sns.UptimeStatus, error) {
+	window := threshold(statusReq.FlapWindow, "FLAP_WINDOW", 0)
+	if window <= 0 && previous.CurrentState() != storage.STATE_FLAPPING {
+		return nil, nil
+	}
+	announce, err := store.DetectFlapping(statusReq.UptimeID, window,
+		threshold(statusReq.FlapThreshold, "FLAP_THRESHOLD", 50),
+		threshold(statusReq.FlapStable, "FLAP_STABLE", 25))
+	if err != nil || announce == "" {
+		return nil, err
+	}
+	status := sns.UptimeStatus(announce)
+	if status == sns.STATUS_FLAPPING {
+		current, err := store.GetUptimeStatus(statusReq.UptimeID)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil {
+			response.FlapScore = current.FlapScore
+		}
+	}
+	return &status, nil
+}
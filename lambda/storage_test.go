@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monitor-uptime/internal/storage"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Given uptime monitor whose flap detection window fills by run crossing fail threshold
+// When it fails, succeeds and fails twice
+// Then FAIL is replaced by single FLAPPING notification
+//      and FAIL is stored as announced status while monitor is FLAPPING
+//      and incident is opened from stored FAIL status
+func TestHandleRequestFailAndFlappingInSameRun(t *testing.T) {
+	// Given
+	host := newTestHost(t, http.StatusInternalServerError)
+	webhook := newTestWebhook(t)
+	req := newTestRequest(t, "fail-flapping-uptime", host, webhook)
+	req.FlapWindow = 4
+	req.FlapThreshold = 50
+	req.FlapStable = 25
+	store, _ := newStorage(nil)
+
+	// When
+	runMonitor(t, req)
+	host.respond(http.StatusOK)
+	runMonitor(t, req)
+	host.respond(http.StatusInternalServerError)
+	runMonitor(t, req)
+	res := runMonitor(t, req)
+
+	// Then
+	status, _ := store.GetUptimeStatus(req.UptimeID)
+	incidents, _ := store.ListIncidents(req.UptimeID, 0, time.Now().Unix())
+	assert.Equal(t, []string{"FLAPPING"}, webhook.statuses(), "FAIL was expected to be replaced by FLAPPING")
+	assert.NotZero(t, res.FlapScore, "Flap score was expected to be returned")
+	assert.Equal(t, storage.ANNOUNCED_FAIL, status.AnnouncedStatus(), "FAIL was expected to be stored as announced status")
+	assert.Equal(t, storage.STATE_FLAPPING, status.CurrentState(), "Uptime monitor was expected to be flapping")
+	assert.Len(t, incidents, 1, "Incident was expected to be opened")
+	assert.Zero(t, incidents[0].ClosedAt, "Incident was expected to be open")
+	assert.Equal(t, incidents[0].ID, status.IncidentID, "Incident was expected to be linked to uptime status")
+}